@@ -0,0 +1,75 @@
+package smooch
+
+import "encoding/json"
+
+// NewReplyAction returns a quick reply. iconURL is optional and only shown
+// by the channels that support reply icons.
+func NewReplyAction(text string, payload string, iconURL string) *Action {
+	return &Action{
+		Type:    ActionTypeReply,
+		Text:    text,
+		Payload: payload,
+		IconURL: iconURL,
+	}
+}
+
+// NewLocationRequestAction returns a quick reply asking the user to share
+// their location. It can be combined with reply actions.
+func NewLocationRequestAction(text string) *Action {
+	return &Action{
+		Type: ActionTypeLocationRequest,
+		Text: text,
+	}
+}
+
+func NewPostbackAction(text string, payload string) *Action {
+	return &Action{
+		Type:    ActionTypePostback,
+		Text:    text,
+		Payload: payload,
+	}
+}
+
+func NewLinkAction(text string, uri string) *Action {
+	return &Action{
+		Type: ActionTypeLink,
+		Text: text,
+		URI:  uri,
+	}
+}
+
+// NewPostbackActionJSON returns a postback action whose payload is v encoded
+// as JSON, to be decoded from the webhook with Postback.DecodePayload.
+func NewPostbackActionJSON(text string, v interface{}) (*Action, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return NewPostbackAction(text, string(payload)), nil
+}
+
+// DecodePayload decodes the JSON payload of the clicked action into v.
+//
+// The payload is the one the action was sent with, so it is the simplest way
+// to tell actions apart. To correlate a click with the exact action sent
+// earlier, keep the action ids Smooch assigned in the Message of the
+// ResponsePayload returned by Send and compare them with Action.ID, or use
+// Matches.
+func (p *Postback) DecodePayload(v interface{}) error {
+	if p.Action == nil {
+		return ErrPostbackActionEmpty
+	}
+	return json.Unmarshal([]byte(p.Action.Payload), v)
+}
+
+// Matches reports whether the postback is a click on action, as returned by
+// Send. Actions are matched by id, or by payload when action has no id.
+func (p *Postback) Matches(action *Action) bool {
+	if p.Action == nil || action == nil {
+		return false
+	}
+	if action.ID != "" {
+		return p.Action.ID == action.ID
+	}
+	return p.Action.Type == action.Type && p.Action.Payload == action.Payload
+}
@@ -0,0 +1,297 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// AccountClient calls the account-level endpoints used to manage apps. It
+// authenticates with an account-scoped JWT, so Options.KeyID and
+// Options.Secret have to belong to an account or service account key.
+// Options.AppID and the webhook related options are ignored and
+// Options.Scope is always JWTScopeAccount.
+type AccountClient struct {
+	client *smoochClient
+}
+
+func NewAccountClient(o Options) (*AccountClient, error) {
+	o.Scope = JWTScopeAccount
+	sc, err := newClient(o)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountClient{client: sc}, nil
+}
+
+type App struct {
+	ID       string       `json:"_id,omitempty"`
+	Name     string       `json:"name,omitempty"`
+	Settings *AppSettings `json:"settings,omitempty"`
+	Metadata Metadata     `json:"metadata,omitempty"`
+}
+
+type AppSettings struct {
+	MaskCreditCardNumbers          *bool    `json:"maskCreditCardNumbers,omitempty"`
+	UseAnimalNames                 *bool    `json:"useAnimalNames,omitempty"`
+	EchoPostback                   *bool    `json:"echoPostback,omitempty"`
+	IgnoreAutoConversationStart    *bool    `json:"ignoreAutoConversationStart,omitempty"`
+	MultiConvoEnabled              *bool    `json:"multiConvoEnabled,omitempty"`
+	ConversationRetentionSeconds   int      `json:"conversationRetentionSeconds,omitempty"`
+	AppLocalizationEnabled         *bool    `json:"appLocalizationEnabled,omitempty"`
+	WebhookBlockedOriginsAllowlist []string `json:"webhookBlockedOriginsAllowlist,omitempty"`
+}
+
+type ListAppsParams struct {
+	Limit            int
+	Offset           int
+	ServiceAccountID string
+}
+
+type AppResponse struct {
+	App *App `json:"app,omitempty"`
+}
+
+type ListAppsResponse struct {
+	Apps    []*App `json:"apps"`
+	HasMore bool   `json:"hasMore"`
+}
+
+func (ac *AccountClient) CreateApp(app *App, opts ...RequestOption) (*App, *ResponseData, error) {
+	if app == nil || app.Name == "" {
+		return nil, nil, ErrAppNameEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL("/v1.1/apps", ro.queryParams(nil))
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(app)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := ac.client.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response AppResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.App, respData, nil
+}
+
+func (ac *AccountClient) ListApps(params ListAppsParams, opts ...RequestOption) (*ListAppsResponse, *ResponseData, error) {
+	queryParams := url.Values{}
+	if params.Limit > 0 {
+		queryParams.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Offset > 0 {
+		queryParams.Set("offset", strconv.Itoa(params.Offset))
+	}
+	if params.ServiceAccountID != "" {
+		queryParams.Set("serviceAccountId", params.ServiceAccountID)
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL("/v1.1/apps", ro.queryParams(queryParams))
+
+	req, err := ac.client.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListAppsResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return &response, respData, nil
+}
+
+func (ac *AccountClient) GetApp(appID string, opts ...RequestOption) (*App, *ResponseData, error) {
+	if appID == "" {
+		return nil, nil, ErrAppIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/apps/%s", url.PathEscape(appID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := ac.client.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response AppResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.App, respData, nil
+}
+
+// UpdateApp updates the name, settings and metadata set on app.
+func (ac *AccountClient) UpdateApp(appID string, app *App, opts ...RequestOption) (*App, *ResponseData, error) {
+	if appID == "" {
+		return nil, nil, ErrAppIDEmpty
+	}
+
+	if app == nil {
+		return nil, nil, ErrAppNil
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/apps/%s", url.PathEscape(appID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(app)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := ac.client.createRequest(http.MethodPut, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response AppResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.App, respData, nil
+}
+
+func (ac *AccountClient) DeleteApp(appID string, opts ...RequestOption) (*ResponseData, error) {
+	if appID == "" {
+		return nil, ErrAppIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/apps/%s", url.PathEscape(appID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := ac.client.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	return ac.client.sendRequest(req, nil)
+}
+
+// AppKey is an app-scoped API key. Its ID and Secret can be passed as
+// Options.KeyID and Options.Secret to New.
+type AppKey struct {
+	ID     string `json:"_id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type AppKeyResponse struct {
+	Key *AppKey `json:"key,omitempty"`
+}
+
+type ListAppKeysResponse struct {
+	Keys []*AppKey `json:"keys"`
+}
+
+func (ac *AccountClient) CreateAppKey(appID string, name string, opts ...RequestOption) (*AppKey, *ResponseData, error) {
+	if appID == "" {
+		return nil, nil, ErrAppIDEmpty
+	}
+
+	if name == "" {
+		return nil, nil, ErrAppKeyNameEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/keys", url.PathEscape(appID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(&AppKey{Name: name})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := ac.client.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response AppKeyResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Key, respData, nil
+}
+
+func (ac *AccountClient) ListAppKeys(appID string, opts ...RequestOption) ([]*AppKey, *ResponseData, error) {
+	if appID == "" {
+		return nil, nil, ErrAppIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/keys", url.PathEscape(appID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := ac.client.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListAppKeysResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Keys, respData, nil
+}
+
+func (ac *AccountClient) DeleteAppKey(appID string, keyID string, opts ...RequestOption) (*ResponseData, error) {
+	if appID == "" {
+		return nil, ErrAppIDEmpty
+	}
+
+	if keyID == "" {
+		return nil, ErrAppKeyIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/keys/%s", url.PathEscape(appID), url.PathEscape(keyID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := ac.client.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	return ac.client.sendRequest(req, nil)
+}
@@ -0,0 +1,250 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sampleAppJson = `
+	{
+		"app": {
+			"_id": "55c8d9758590aa1900b9b9f6",
+			"name": "My App",
+			"settings": {
+				"maskCreditCardNumbers": true,
+				"useAnimalNames": false,
+				"conversationRetentionSeconds": 0
+			},
+			"metadata": {"customer": "acme"}
+		}
+	}`
+
+func newAccountTestClient(t *testing.T, fn RoundTripFunc) *AccountClient {
+	ac, err := NewAccountClient(Options{
+		HttpClient: NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+	return ac
+}
+
+func TestAccountClientScope(t *testing.T) {
+	accountToken, err := GenerateJWT(JWTScopeAccount, "", "")
+	assert.NoError(t, err)
+
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, "Bearer "+accountToken, req.Header.Get(authorizationHeaderKey))
+		assert.NotEqual(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleAppJson))),
+		}
+	}
+
+	ac := newAccountTestClient(t, fn)
+	_, _, err = ac.GetApp("55c8d9758590aa1900b9b9f6")
+	assert.NoError(t, err)
+}
+
+func TestAppsCRUD(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		body := sampleAppJson
+
+		switch req.Method {
+		case http.MethodPost:
+			assert.Equal(t, "/v1.1/apps", req.URL.Path)
+			data, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"name":"My App","settings":{"maskCreditCardNumbers":true}}`, string(data))
+		case http.MethodGet:
+			if req.URL.Path == "/v1.1/apps" {
+				assert.Equal(t, "10", req.URL.Query().Get("limit"))
+				assert.Equal(t, "20", req.URL.Query().Get("offset"))
+				assert.Equal(t, "5a71d2fe3fb2a5e1bcd8d2f0", req.URL.Query().Get("serviceAccountId"))
+				body = `{"apps":[{"_id":"55c8d9758590aa1900b9b9f6","name":"My App"}],"hasMore":true}`
+			} else {
+				assert.Equal(t, "/v1.1/apps/55c8d9758590aa1900b9b9f6", req.URL.Path)
+			}
+		case http.MethodPut:
+			assert.Equal(t, "/v1.1/apps/55c8d9758590aa1900b9b9f6", req.URL.Path)
+			data, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"metadata":{"customer":"acme"}}`, string(data))
+		case http.MethodDelete:
+			assert.Equal(t, "/v1.1/apps/55c8d9758590aa1900b9b9f6", req.URL.Path)
+			body = "{}"
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	ac := newAccountTestClient(t, fn)
+
+	mask := true
+	app, _, err := ac.CreateApp(&App{
+		Name:     "My App",
+		Settings: &AppSettings{MaskCreditCardNumbers: &mask},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "55c8d9758590aa1900b9b9f6", app.ID)
+	assert.True(t, *app.Settings.MaskCreditCardNumbers)
+	assert.False(t, *app.Settings.UseAnimalNames)
+
+	apps, _, err := ac.ListApps(ListAppsParams{
+		Limit:            10,
+		Offset:           20,
+		ServiceAccountID: "5a71d2fe3fb2a5e1bcd8d2f0",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, apps.Apps, 1)
+	assert.True(t, apps.HasMore)
+
+	app, _, err = ac.GetApp("55c8d9758590aa1900b9b9f6")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", app.Metadata["customer"])
+
+	_, _, err = ac.UpdateApp("55c8d9758590aa1900b9b9f6", &App{
+		Metadata: map[string]interface{}{"customer": "acme"},
+	})
+	assert.NoError(t, err)
+
+	_, err = ac.DeleteApp("55c8d9758590aa1900b9b9f6")
+	assert.NoError(t, err)
+
+	_, _, err = ac.CreateApp(&App{})
+	assert.Equal(t, ErrAppNameEmpty, err)
+
+	_, _, err = ac.GetApp("")
+	assert.Equal(t, ErrAppIDEmpty, err)
+
+	_, _, err = ac.UpdateApp("55c8d9758590aa1900b9b9f6", nil)
+	assert.Equal(t, ErrAppNil, err)
+
+	_, err = ac.DeleteApp("")
+	assert.Equal(t, ErrAppIDEmpty, err)
+}
+
+func TestAppKeys(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		body := "{}"
+
+		switch req.Method {
+		case http.MethodPost:
+			assert.Equal(t, "/v1.1/apps/55c8d9758590aa1900b9b9f6/keys", req.URL.Path)
+			data, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"name":"provisioning"}`, string(data))
+			body = `{"key":{"_id":"app_5723a347f82ba0516cb4ea34","name":"provisioning","secret":"5XJ85yjUtRcaQu_pDINblPZb"}}`
+		case http.MethodGet:
+			assert.Equal(t, "/v1.1/apps/55c8d9758590aa1900b9b9f6/keys", req.URL.Path)
+			body = `{"keys":[{"_id":"app_5723a347f82ba0516cb4ea34","name":"provisioning","secret":"5XJ85yjUtRcaQu_pDINblPZb"}]}`
+		case http.MethodDelete:
+			assert.Equal(t, "/v1.1/apps/55c8d9758590aa1900b9b9f6/keys/app_5723a347f82ba0516cb4ea34", req.URL.Path)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	ac := newAccountTestClient(t, fn)
+
+	key, _, err := ac.CreateAppKey("55c8d9758590aa1900b9b9f6", "provisioning")
+	assert.NoError(t, err)
+	assert.Equal(t, "app_5723a347f82ba0516cb4ea34", key.ID)
+	assert.Equal(t, "5XJ85yjUtRcaQu_pDINblPZb", key.Secret)
+
+	keys, _, err := ac.ListAppKeys("55c8d9758590aa1900b9b9f6")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	_, err = ac.DeleteAppKey("55c8d9758590aa1900b9b9f6", key.ID)
+	assert.NoError(t, err)
+
+	_, _, err = ac.CreateAppKey("", "provisioning")
+	assert.Equal(t, ErrAppIDEmpty, err)
+
+	_, _, err = ac.CreateAppKey("55c8d9758590aa1900b9b9f6", "")
+	assert.Equal(t, ErrAppKeyNameEmpty, err)
+
+	_, err = ac.DeleteAppKey("55c8d9758590aa1900b9b9f6", "")
+	assert.Equal(t, ErrAppKeyIDEmpty, err)
+}
+
+func TestAccountScopeForAppEndpoints(t *testing.T) {
+	accountToken, err := GenerateJWT(JWTScopeAccount, "act_5963ceb97cde542d000dbdb1", "secret")
+	assert.NoError(t, err)
+
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, "Bearer "+accountToken, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123", req.URL.Path)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"appUser":{"_id":"123"}}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		KeyID:        "act_5963ceb97cde542d000dbdb1",
+		Secret:       "secret",
+		Scope:        JWTScopeAccount,
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.GetAppUser("123")
+	assert.NoError(t, err)
+
+	_, err = New(Options{
+		Scope:        "integration",
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.Equal(t, ErrScopeInvalid, err)
+}
+
+func TestAccountEndpointsEscapeIDs(t *testing.T) {
+	var paths []string
+	fn := func(req *http.Request) *http.Response {
+		paths = append(paths, req.URL.EscapedPath())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{}`))),
+		}
+	}
+	ac := newAccountTestClient(t, fn)
+
+	ac.GetApp("a/b?c")
+	ac.UpdateApp("a/b?c", &App{Name: "Travel"})
+	ac.DeleteApp("a/b?c")
+	ac.CreateAppKey("a/b?c", "key")
+	ac.ListAppKeys("a/b?c")
+	ac.DeleteAppKey("a/b?c", "k#1")
+	ac.GetDeployment("d/e#f")
+	ac.DeleteDeployment("d/e#f")
+	ac.RegisterDeployment("d/e#f", DeploymentRegistration{PhoneNumber: "+15145555333"})
+	ac.VerifyDeployment("d/e#f", "123456")
+
+	assert.Equal(t, []string{
+		"/v1.1/apps/a%2Fb%3Fc",
+		"/v1.1/apps/a%2Fb%3Fc",
+		"/v1.1/apps/a%2Fb%3Fc",
+		"/v1.1/apps/a%2Fb%3Fc/keys",
+		"/v1.1/apps/a%2Fb%3Fc/keys",
+		"/v1.1/apps/a%2Fb%3Fc/keys/k%231",
+		"/v1.1/whatsapp/deployments/d%2Fe%23f",
+		"/v1.1/whatsapp/deployments/d%2Fe%23f",
+		"/v1.1/whatsapp/deployments/d%2Fe%23f/register",
+		"/v1.1/whatsapp/deployments/d%2Fe%23f/verify",
+	}, paths)
+}
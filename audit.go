@@ -0,0 +1,143 @@
+package smooch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const auditKeyPrefix = "smooch:audit:"
+
+// Outcomes of the webhooks recorded by an AuditLog.
+const (
+	AuditOutcomeHandled      = "handled"
+	AuditOutcomeFailed       = "failed"
+	AuditOutcomeDuplicate    = "duplicate"
+	AuditOutcomeInvalid      = "invalid"
+	AuditOutcomeUnauthorized = "unauthorized"
+)
+
+// AuditEntry is a webhook recorded by an AuditLog. Body is the raw request
+// body; it is empty for unauthorized requests, which aren't read. Error is
+// set for failed and invalid webhooks.
+type AuditEntry struct {
+	ID             string    `json:"id"`
+	ReceivedAt     time.Time `json:"receivedAt"`
+	Trigger        string    `json:"trigger,omitempty"`
+	ConversationID string    `json:"conversationId,omitempty"`
+	AppUserID      string    `json:"appUserId,omitempty"`
+	RemoteAddr     string    `json:"remoteAddr,omitempty"`
+	Body           []byte    `json:"body,omitempty"`
+	Outcome        string    `json:"outcome"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Payload decodes the body of the entry.
+func (e *AuditEntry) Payload() (*Payload, error) {
+	var p Payload
+	if err := json.Unmarshal(e.Body, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// AuditLog records every webhook an endpoint receives along with how it
+// was handled, for compliance audits and to replay them. Set it as
+// Options.WebhookAuditLog.
+type AuditLog struct {
+	storage Storage
+	ttl     time.Duration
+}
+
+// NewAuditLog returns an audit log keeping entries for ttl, or forever when
+// ttl is 0.
+func NewAuditLog(storage Storage, ttl time.Duration) *AuditLog {
+	return &AuditLog{
+		storage: storage,
+		ttl:     ttl,
+	}
+}
+
+// Record stores the entry, setting its ID when empty.
+func (l *AuditLog) Record(ctx context.Context, e *AuditEntry) error {
+	if e.ID == "" {
+		// the time comes first so keys sort in the order webhooks came in
+		sum := sha256.Sum256(append([]byte(e.RemoteAddr), e.Body...))
+		e.ID = fmt.Sprintf("%020d-%s", e.ReceivedAt.UnixNano(), hex.EncodeToString(sum[:8]))
+	}
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return l.storage.Set(ctx, auditKeyPrefix+e.ID, value, l.ttl)
+}
+
+// Entries returns the entries received since the given time, oldest first.
+func (l *AuditLog) Entries(ctx context.Context, since time.Time) ([]*AuditEntry, error) {
+	keys, err := l.storage.Keys(ctx, auditKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*AuditEntry
+	for _, key := range keys {
+		value, ok, err := l.storage.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// expired since listed
+			continue
+		}
+		var e AuditEntry
+		if err := json.Unmarshal(value, &e); err != nil {
+			return nil, err
+		}
+		if e.ReceivedAt.Before(since) {
+			continue
+		}
+		entries = append(entries, &e)
+	}
+	return entries, nil
+}
+
+// Replay dispatches the webhook of an audit entry again to the handlers of
+// the endpoint, skipping verification and deduplication. It returns the
+// errors of the handlers.
+func (ep *WebhookEndpoint) Replay(ctx context.Context, e *AuditEntry) error {
+	p, err := e.Payload()
+	if err != nil {
+		return err
+	}
+	return ep.dispatch(ctx, p, WebhookMeta{RemoteAddr: e.RemoteAddr, ReceivedAt: e.ReceivedAt})
+}
+
+func (ep *WebhookEndpoint) audit(ctx context.Context, e *AuditEntry) {
+	if ep.auditLog == nil {
+		return
+	}
+	if err := ep.auditLog.Record(ctx, e); err != nil {
+		ep.logger.Errorw("webhook audit failed", "trigger", e.Trigger, "err", err)
+	}
+}
+
+func newAuditEntry(p *Payload, body []byte, meta WebhookMeta, outcome string, err error) *AuditEntry {
+	e := &AuditEntry{
+		ReceivedAt: meta.ReceivedAt,
+		RemoteAddr: meta.RemoteAddr,
+		Body:       body,
+		Outcome:    outcome,
+	}
+	if p != nil {
+		e.Trigger = p.Trigger
+		e.ConversationID = p.Conversation.ID
+		e.AppUserID = p.AppUser.ID
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
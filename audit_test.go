@@ -0,0 +1,69 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	auditLog := NewAuditLog(NewMemoryStorage(), 0)
+	sc, err := New(Options{
+		VerifySecret:        "very-secure-test-secret",
+		WebhookAuditLog:     auditLog,
+		WebhookDeduplicator: NewDeduplicator(NewMemoryStorage(), 0),
+	})
+	assert.NoError(t, err)
+
+	fail := true
+	sc.AddWebhookEventHandlerE(func(payload *Payload) error {
+		if fail {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	post := func(body string, secret string) {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Api-Key", secret)
+		sc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	start := time.Now()
+	post(payloadExample1, "very-secure-test-secret")
+	post(payloadExample1, "very-secure-test-secret")
+	post(`{"trigger":`, "very-secure-test-secret")
+	post(payloadExample1, "wrong-secret")
+
+	entries, err := auditLog.Entries(context.Background(), start)
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 4) {
+		return
+	}
+
+	assert.Equal(t, AuditOutcomeFailed, entries[0].Outcome)
+	assert.Equal(t, "handler failed", entries[0].Error)
+	assert.Equal(t, TriggerMessageAppUser, entries[0].Trigger)
+	assert.NotEmpty(t, entries[0].ConversationID)
+	assert.JSONEq(t, payloadExample1, string(entries[0].Body))
+
+	assert.Equal(t, AuditOutcomeDuplicate, entries[1].Outcome)
+	assert.Equal(t, AuditOutcomeInvalid, entries[2].Outcome)
+	assert.Equal(t, `{"trigger":`, string(entries[2].Body))
+	assert.Equal(t, AuditOutcomeUnauthorized, entries[3].Outcome)
+	assert.Empty(t, entries[3].Body)
+
+	// replaying skips deduplication
+	fail = false
+	assert.NoError(t, sc.Replay(context.Background(), entries[0]))
+
+	entries, err = auditLog.Entries(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
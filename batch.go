@@ -0,0 +1,155 @@
+package smooch
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchConcurrency is the number of sends SendBatch keeps in
+	// flight when BatchOptions.Concurrency is not set.
+	DefaultBatchConcurrency = 10
+
+	// maxBatchRateLimitRetries is how many times a send rejected with 429
+	// Too Many Requests is tried again once the limit resets.
+	maxBatchRateLimitRetries = 3
+	// defaultBatchRateLimitPause is the pause after a 429 response without
+	// Retry-After or reset headers.
+	defaultBatchRateLimitPause = time.Second
+)
+
+// BatchOptions configures SendBatch.
+type BatchOptions struct {
+	Concurrency int
+}
+
+// BatchResult is the outcome of sending the message to one user.
+type BatchResult struct {
+	UserID       string
+	Response     *ResponsePayload
+	ResponseData *ResponseData
+	Err          error
+}
+
+// BatchResults holds a result per user, in the order of the user ids.
+type BatchResults []*BatchResult
+
+// Failed returns the results of the sends that failed, e.g. to retry them.
+func (r BatchResults) Failed() []*BatchResult {
+	var failed []*BatchResult
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// SendBatch sends the message to every user, with at most
+// BatchOptions.Concurrency sends in flight. When a response reports that
+// the rate limit is used up, every worker pauses until it resets, and
+// sends rejected with 429 are tried again. An idempotency key set with the
+// request options is made unique per user.
+func (sc *smoochClient) SendBatch(userIDs []string, message *Message, o BatchOptions, opts ...RequestOption) BatchResults {
+	concurrency := o.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	key := newRequestOptions(opts).header.Get(idempotencyKeyHeaderKey)
+
+	results := make(BatchResults, len(userIDs))
+	throttle := newBatchThrottle()
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(userIDs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				userOpts := opts
+				if key != "" {
+					userOpts = append(opts[:len(opts):len(opts)], WithIdempotencyKey(key+"-"+userIDs[i]))
+				}
+				results[i] = sc.sendBatchMessage(throttle, userIDs[i], message, userOpts)
+			}
+		}()
+	}
+
+	for i := range userIDs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func (sc *smoochClient) sendBatchMessage(throttle *batchThrottle, userID string, message *Message, opts []RequestOption) *BatchResult {
+	result := &BatchResult{UserID: userID}
+	for attempt := 0; ; attempt++ {
+		throttle.wait()
+		result.Response, result.ResponseData, result.Err = sc.Send(userID, message, opts...)
+		limited := throttle.observe(result.ResponseData)
+		if !limited || attempt == maxBatchRateLimitRetries {
+			return result
+		}
+	}
+}
+
+// batchThrottle pauses the workers of a batch while the rate limit is used
+// up.
+type batchThrottle struct {
+	mu    sync.Mutex
+	until time.Time
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(d time.Duration)
+}
+
+func newBatchThrottle() *batchThrottle {
+	return &batchThrottle{
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+}
+
+func (t *batchThrottle) wait() {
+	t.mu.Lock()
+	until := t.until
+	t.mu.Unlock()
+
+	if d := until.Sub(t.now()); d > 0 {
+		t.sleep(d)
+	}
+}
+
+// observe pauses the batch when the response used up the rate limit and
+// reports whether it was rejected for it.
+func (t *batchThrottle) observe(respData *ResponseData) bool {
+	if respData == nil {
+		return false
+	}
+	limited := respData.HTTPCode == http.StatusTooManyRequests
+	rl := respData.RateLimit
+
+	var until time.Time
+	switch {
+	case rl != nil && rl.RetryAfter > 0:
+		until = t.now().Add(rl.RetryAfter)
+	case rl != nil && (limited || rl.Remaining == 0) && !rl.Reset.IsZero():
+		until = rl.Reset
+	case limited:
+		until = t.now().Add(defaultBatchRateLimitPause)
+	default:
+		return false
+	}
+
+	t.mu.Lock()
+	if until.After(t.until) {
+		t.until = until
+	}
+	t.mu.Unlock()
+	return limited
+}
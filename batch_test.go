@@ -0,0 +1,125 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendBatch(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inflight int
+		peak     int
+		attempts = map[string]int{}
+		keys     = map[string]string{}
+	)
+	fn := func(req *http.Request) *http.Response {
+		userID := strings.Split(req.URL.Path, "/")[5]
+
+		mu.Lock()
+		inflight++
+		if inflight > peak {
+			peak = inflight
+		}
+		attempts[userID]++
+		attempt := attempts[userID]
+		keys[userID] = req.Header.Get(idempotencyKeyHeaderKey)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+
+		switch {
+		case userID == "limited" && attempt == 1:
+			return &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"X-Ratelimit-Reset": []string{"0"}},
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error": {"code": "too_many_requests", "description": "slow down"}}`))),
+			}
+		case userID == "missing":
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error": {"code": "not_found", "description": "no user"}}`))),
+			}
+		}
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	userIDs := []string{"missing", "limited"}
+	for i := 0; i < 20; i++ {
+		userIDs = append(userIDs, "user"+string(rune('a'+i)))
+	}
+	message := &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "Sale starts now"}
+
+	results := sc.SendBatch(userIDs, message, BatchOptions{Concurrency: 3}, WithIdempotencyKey("campaign"))
+	assert.Len(t, results, len(userIDs))
+	assert.LessOrEqual(t, peak, 3)
+
+	for i, result := range results {
+		assert.Equal(t, userIDs[i], result.UserID)
+	}
+	failed := results.Failed()
+	assert.Len(t, failed, 1)
+	assert.Equal(t, "missing", failed[0].UserID)
+	assert.IsType(t, &SmoochError{}, failed[0].Err)
+
+	// rate limited sends are tried again
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, 2, attempts["limited"])
+	assert.Equal(t, "campaign-usera", keys["usera"])
+}
+
+func TestBatchThrottle(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	var slept time.Duration
+	throttle := &batchThrottle{
+		now: func() time.Time { return now },
+		sleep: func(d time.Duration) {
+			slept += d
+			now = now.Add(d)
+		},
+	}
+
+	assert.False(t, throttle.observe(&ResponseData{HTTPCode: http.StatusCreated}))
+	throttle.wait()
+	assert.Zero(t, slept)
+
+	// a used up limit pauses until the reset
+	assert.False(t, throttle.observe(&ResponseData{
+		HTTPCode:  http.StatusCreated,
+		RateLimit: &RateLimit{Remaining: 0, Reset: now.Add(5 * time.Second)},
+	}))
+	throttle.wait()
+	assert.Equal(t, 5*time.Second, slept)
+
+	assert.True(t, throttle.observe(&ResponseData{
+		HTTPCode:  http.StatusTooManyRequests,
+		RateLimit: &RateLimit{RetryAfter: 10 * time.Second},
+	}))
+	throttle.wait()
+	assert.Equal(t, 15*time.Second, slept)
+
+	assert.True(t, throttle.observe(&ResponseData{HTTPCode: http.StatusTooManyRequests}))
+	throttle.wait()
+	assert.Equal(t, 15*time.Second+defaultBatchRateLimitPause, slept)
+}
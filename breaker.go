@@ -0,0 +1,88 @@
+package smooch
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker stops outbound API calls after FailureThreshold consecutive
+// failures and rejects them with ErrCircuitOpen until CoolDown has passed.
+// After the cool-down a single probe call is let through; its outcome decides
+// whether the breaker closes again or stays open for another cool-down.
+type CircuitBreaker struct {
+	FailureThreshold int
+	CoolDown         time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(failureThreshold int, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		CoolDown:         coolDown,
+	}
+}
+
+// Allow reports whether a call may be made right now.
+func (cb *CircuitBreaker) Allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if cb.clock().Sub(cb.openedAt) < cb.CoolDown {
+			return ErrCircuitOpen
+		}
+		cb.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// a probe is already in flight
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Record feeds the outcome of a call allowed by Allow back into the breaker.
+func (cb *CircuitBreaker) Record(resp *http.Response, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if err == nil && !isRetryableStatus(resp.StatusCode) {
+		cb.state = circuitClosed
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.state == circuitHalfOpen || cb.failures >= cb.FailureThreshold {
+		cb.state = circuitOpen
+		cb.openedAt = cb.clock()
+	}
+}
+
+// Open reports whether the breaker is currently rejecting calls.
+func (cb *CircuitBreaker) Open() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state != circuitClosed
+}
+
+func (cb *CircuitBreaker) clock() time.Time {
+	if cb.now != nil {
+		return cb.now()
+	}
+	return time.Now()
+}
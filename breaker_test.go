@@ -0,0 +1,72 @@
+package smooch
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1444348338, 0)
+	cb := NewCircuitBreaker(2, time.Minute)
+	cb.now = func() time.Time { return now }
+
+	assert.NoError(t, cb.Allow())
+	cb.Record(nil, errors.New("connection reset"))
+	assert.False(t, cb.Open())
+
+	assert.NoError(t, cb.Allow())
+	cb.Record(&http.Response{StatusCode: http.StatusBadGateway}, nil)
+	assert.True(t, cb.Open())
+	assert.Equal(t, ErrCircuitOpen, cb.Allow())
+
+	// the probe after the cool-down fails, so the breaker opens again
+	now = now.Add(time.Minute)
+	assert.NoError(t, cb.Allow())
+	assert.Equal(t, ErrCircuitOpen, cb.Allow())
+	cb.Record(nil, errors.New("connection reset"))
+	assert.Equal(t, ErrCircuitOpen, cb.Allow())
+
+	// the next probe succeeds and closes the breaker
+	now = now.Add(time.Minute)
+	assert.NoError(t, cb.Allow())
+	cb.Record(&http.Response{StatusCode: http.StatusBadRequest}, nil)
+	assert.False(t, cb.Open())
+	assert.NoError(t, cb.Allow())
+}
+
+func TestSendWithCircuitBreaker(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error":{"code":"unavailable"}}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret:   "very-secure-test-secret",
+		HttpClient:     NewTestClient(fn),
+		CircuitBreaker: NewCircuitBreaker(1, time.Hour),
+	})
+	assert.NoError(t, err)
+
+	message := &Message{
+		Role: RoleAppMaker,
+		Type: MessageTypeText,
+		Text: "Hello",
+	}
+	_, _, err = sc.Send("TestUser", message)
+	assert.Error(t, err)
+	assert.IsType(t, &SmoochError{}, err)
+
+	_, _, err = sc.Send("TestUser", message)
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Equal(t, 1, calls)
+}
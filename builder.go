@@ -0,0 +1,307 @@
+package smooch
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxActionText is the longest action text the API accepts.
+const maxActionText = 128
+
+// MessageValidationError is returned when a message is missing required
+// fields or combines fields its type does not allow.
+type MessageValidationError struct {
+	Reason string
+}
+
+func (e *MessageValidationError) Error() string {
+	return "invalid message: " + e.Reason
+}
+
+func invalidMessage(format string, args ...interface{}) error {
+	return &MessageValidationError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// MessageBuilder builds a Message and checks it before it is sent:
+//
+//	message, err := smooch.NewMessage().
+//		Text("Pick a size").
+//		Action(&smooch.Action{Type: smooch.ActionTypeReply, Text: "S", Payload: "S"}).
+//		Build()
+//
+// The role defaults to RoleAppMaker. When no type is set it is inferred from
+// the fields that are set.
+type MessageBuilder struct {
+	message *Message
+	err     error
+}
+
+func NewMessage() *MessageBuilder {
+	return &MessageBuilder{message: &Message{Role: RoleAppMaker}}
+}
+
+func (b *MessageBuilder) Type(messageType MessageType) *MessageBuilder {
+	b.message.Type = messageType
+	return b
+}
+
+func (b *MessageBuilder) Role(role Role) *MessageBuilder {
+	b.message.Role = role
+	return b
+}
+
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	b.message.Text = text
+	return b
+}
+
+func (b *MessageBuilder) Media(mediaURL string, mediaType string) *MessageBuilder {
+	b.message.MediaURL = mediaURL
+	b.message.MediaType = mediaType
+	return b
+}
+
+func (b *MessageBuilder) Coordinates(lat float64, long float64) *MessageBuilder {
+	b.message.Coordinates = &Coordinates{Lat: lat, Long: long}
+	return b
+}
+
+// Location sets the name and address shown with the coordinates of a
+// location message.
+func (b *MessageBuilder) Location(name string, address string) *MessageBuilder {
+	b.message.Location = &Location{Name: name, Address: address}
+	return b
+}
+
+func (b *MessageBuilder) Action(actions ...*Action) *MessageBuilder {
+	b.message.Actions = append(b.message.Actions, actions...)
+	return b
+}
+
+func (b *MessageBuilder) Item(items ...*Item) *MessageBuilder {
+	b.message.Items = append(b.message.Items, items...)
+	return b
+}
+
+func (b *MessageBuilder) Metadata(key string, value interface{}) *MessageBuilder {
+	if b.message.Metadata == nil {
+		b.message.Metadata = Metadata{}
+	}
+	b.message.Metadata[key] = value
+	return b
+}
+
+// Override sets the raw payload sent to channel instead of the one Smooch
+// would derive from the message.
+func (b *MessageBuilder) Override(channel string, override *Override) *MessageBuilder {
+	if b.message.Override == nil {
+		b.message.Override = map[string]*Override{}
+	}
+	b.message.Override[channel] = override
+	return b
+}
+
+// ChannelOverride sets a typed channel payload, such as MessengerOverride,
+// as the override of its channel. Payloads with a Validate method are
+// checked first.
+func (b *MessageBuilder) ChannelOverride(payload ChannelPayload) *MessageBuilder {
+	if v, ok := payload.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			b.err = err
+			return b
+		}
+	}
+
+	override, err := NewOverride(payload)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	return b.Override(payload.Channel(), override)
+}
+
+// ReplyTo quotes the message with messageID, threading the answer on the
+// channels supporting replies.
+func (b *MessageBuilder) ReplyTo(messageID string) *MessageBuilder {
+	b.message.QuotedMessage = &QuotedMessage{
+		Type:      QuotedMessageTypeMessageID,
+		MessageID: messageID,
+	}
+	return b
+}
+
+func (b *MessageBuilder) ImageAspectRatio(ratio ImageRatio) *MessageBuilder {
+	b.message.DisplaySettings = &DisplaySettings{ImageAspectRatio: ratio}
+	return b
+}
+
+// Build returns the message, or a *MessageValidationError when it would be
+// rejected by the API.
+func (b *MessageBuilder) Build() (*Message, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	message := *b.message
+	if message.Type == "" {
+		message.Type = inferMessageType(&message)
+	}
+
+	err := message.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func inferMessageType(m *Message) MessageType {
+	switch {
+	case len(m.Items) > 0:
+		return MessageTypeCarousel
+	case m.Coordinates != nil:
+		return MessageTypeLocation
+	case m.MediaURL != "":
+		return MessageTypeImage
+	}
+	return MessageTypeText
+}
+
+// Validate checks the fields required by the message type and the
+// combinations the API does not accept.
+func (m *Message) Validate() error {
+	switch m.Role {
+	case "":
+		return ErrMessageRoleEmpty
+	case RoleAppUser, RoleAppMaker:
+	default:
+		return invalidMessage("unknown role %q", m.Role)
+	}
+
+	switch m.Type {
+	case "":
+		return ErrMessageTypeEmpty
+	case MessageTypeText:
+		if m.Text == "" {
+			return invalidMessage("text messages need text")
+		}
+		if m.MediaURL != "" {
+			return invalidMessage("text messages can't have a media url, use an image or file message")
+		}
+	case MessageTypeImage, MessageTypeFile:
+		if m.MediaURL == "" {
+			return invalidMessage("%s messages need a media url", m.Type)
+		}
+	case MessageTypeLocation:
+		if m.Coordinates == nil {
+			return invalidMessage("location messages need coordinates")
+		}
+		if len(m.Actions) > 0 {
+			return invalidMessage("location messages can't have actions")
+		}
+	case MessageTypeCarousel, MessageTypeList:
+		if len(m.Items) == 0 {
+			return invalidMessage("%s messages need items", m.Type)
+		}
+		if m.Type == MessageTypeCarousel && len(m.Actions) > 0 {
+			return invalidMessage("carousel messages can't have actions, set them on the items")
+		}
+	default:
+		return invalidMessage("unknown message type %q", m.Type)
+	}
+
+	if len(m.Items) > 0 && m.Type != MessageTypeCarousel && m.Type != MessageTypeList {
+		return invalidMessage("items are only allowed on carousel and list messages")
+	}
+	if (m.Coordinates != nil || m.Location != nil) && m.Type != MessageTypeLocation {
+		return invalidMessage("coordinates and location are only allowed on location messages")
+	}
+	if q := m.QuotedMessage; q != nil && q.Message == nil && q.MessageID == "" && q.ExternalMessageID == "" {
+		return invalidMessage("quoted messages need a message id")
+	}
+
+	err := validateActions(m.Actions)
+	if err != nil {
+		return err
+	}
+	for i, item := range m.Items {
+		if err := item.Validate(); err != nil {
+			return invalidMessage("item %d: %s", i, validationReason(err))
+		}
+	}
+	return nil
+}
+
+// Validate checks the title and the actions of a carousel or list item.
+func (i *Item) Validate() error {
+	if i.Title == "" {
+		return invalidMessage("items need a title")
+	}
+	if utf8.RuneCountInString(i.Title) > defaultCarouselConstraints.MaxTitleLength {
+		return invalidMessage("item title is longer than %d characters", defaultCarouselConstraints.MaxTitleLength)
+	}
+	if utf8.RuneCountInString(i.Description) > defaultCarouselConstraints.MaxDescriptionLength {
+		return invalidMessage("item description is longer than %d characters", defaultCarouselConstraints.MaxDescriptionLength)
+	}
+	return validateActions(i.Actions)
+}
+
+// Validate checks the type of the action and the fields it requires.
+func (a *Action) Validate() error {
+	switch a.Type {
+	case "":
+		return invalidMessage("actions need a type")
+	case ActionTypeReply, ActionTypePostback:
+		if a.Text == "" || a.Payload == "" {
+			return invalidMessage("%s actions need text and a payload", a.Type)
+		}
+	case ActionTypeLink, ActionTypeWebview:
+		if a.Text == "" || a.URI == "" {
+			return invalidMessage("%s actions need text and a uri", a.Type)
+		}
+	case ActionTypeBuy:
+		if a.Text == "" || a.Amount <= 0 {
+			return invalidMessage("buy actions need text and an amount")
+		}
+	case ActionTypeLocationRequest, ActionTypeShare:
+	default:
+		return invalidMessage("unknown action type %q", a.Type)
+	}
+
+	if utf8.RuneCountInString(a.Text) > maxActionText {
+		return invalidMessage("%s action text is longer than %d characters", a.Type, maxActionText)
+	}
+	return nil
+}
+
+// validationReason returns the reason of a *MessageValidationError, or the
+// error message of other errors.
+func validationReason(err error) string {
+	var validationErr *MessageValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Reason
+	}
+	return err.Error()
+}
+
+func validateActions(actions []*Action) error {
+	replies := 0
+	for i, action := range actions {
+		if err := action.Validate(); err != nil {
+			return invalidMessage("action %d: %s", i, validationReason(err))
+		}
+		if action.Type == ActionTypeReply {
+			replies++
+		}
+	}
+
+	// replies can only be combined with location requests
+	if replies > 0 {
+		for _, action := range actions {
+			if action.Type != ActionTypeReply && action.Type != ActionTypeLocationRequest {
+				return invalidMessage("reply actions can't be mixed with %s actions", action.Type)
+			}
+		}
+	}
+	return nil
+}
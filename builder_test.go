@@ -0,0 +1,141 @@
+package smooch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBuilder(t *testing.T) {
+	message, err := NewMessage().
+		Text("Pick a size").
+		Action(
+			&Action{Type: ActionTypeReply, Text: "S", Payload: "S"},
+			&Action{Type: ActionTypeLocationRequest, Text: "Send location"},
+		).
+		Metadata("order", 42).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeText, message.Type)
+	assert.Equal(t, RoleAppMaker, message.Role)
+	assert.Len(t, message.Actions, 2)
+	assert.Equal(t, 42, message.Metadata["order"])
+
+	message, err = NewMessage().Media("https://example.org/a.png", "image/png").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeImage, message.Type)
+
+	message, err = NewMessage().Coordinates(45.5, -73.6).Location("Zendesk", "5333 Avenue Casgrain").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeLocation, message.Type)
+	assert.Equal(t, "Zendesk", message.Location.Name)
+
+	message, err = NewMessage().Item(&Item{Title: "Hat"}).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeCarousel, message.Type)
+}
+
+func TestMessageBuilderValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *MessageBuilder
+	}{
+		{"empty text", NewMessage()},
+		{"file without media", NewMessage().Type(MessageTypeFile).Text("report")},
+		{"items on text", NewMessage().Type(MessageTypeText).Text("hi").Item(&Item{Title: "Hat"})},
+		{"carousel actions", NewMessage().Item(&Item{Title: "Hat"}).Action(&Action{Type: ActionTypeLink, Text: "Go", URI: "https://example.org"})},
+		{"coordinates on text", NewMessage().Type(MessageTypeText).Text("hi").Coordinates(1, 2)},
+		{"location on text", NewMessage().Type(MessageTypeText).Text("hi").Location("Zendesk", "")},
+		{"item without title", NewMessage().Type(MessageTypeList).Item(&Item{})},
+		{"link without uri", NewMessage().Text("hi").Action(&Action{Type: ActionTypeLink, Text: "Go"})},
+		{"unknown type", NewMessage().Type("sticker").Text("hi")},
+		{"unknown role", NewMessage().Role("bot").Text("hi")},
+		{"unknown action type", NewMessage().Text("hi").Action(&Action{Type: "call", Text: "Call"})},
+		{"long action text", NewMessage().Text("hi").Action(&Action{Type: ActionTypeReply, Text: strings.Repeat("a", 129), Payload: "A"})},
+		{"long item title", NewMessage().Item(&Item{Title: strings.Repeat("a", 129)})},
+		{"mixed replies", NewMessage().Text("hi").Action(
+			&Action{Type: ActionTypeReply, Text: "S", Payload: "S"},
+			&Action{Type: ActionTypeLink, Text: "Go", URI: "https://example.org"},
+		)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.builder.Build()
+			assert.IsType(t, &MessageValidationError{}, err)
+		})
+	}
+
+	_, err := NewMessage().Role("").Text("hi").Build()
+	assert.Equal(t, ErrMessageRoleEmpty, err)
+}
+
+func TestActionAndItemValidate(t *testing.T) {
+	assert.NoError(t, (&Action{Type: ActionTypeShare}).Validate())
+	assert.NoError(t, (&Action{Type: ActionTypeBuy, Text: "Buy", Amount: 1000}).Validate())
+
+	err := (&Action{Type: ActionTypeBuy, Text: "Buy"}).Validate()
+	assert.EqualError(t, err, "invalid message: buy actions need text and an amount")
+
+	err = (&Item{Title: "Hat", Actions: []*Action{{Type: ActionTypePostback, Text: "Buy"}}}).Validate()
+	assert.EqualError(t, err, "invalid message: action 0: postback actions need text and a payload")
+
+	message := &Message{Role: RoleAppMaker, Type: MessageTypeCarousel, Items: []*Item{{Title: "Hat"}, {}}}
+	assert.EqualError(t, message.Validate(), "invalid message: item 1: items need a title")
+}
+
+func TestMessageOverride(t *testing.T) {
+	override, err := NewOverride(map[string]interface{}{
+		"type": "sticker",
+		"sticker": map[string]string{
+			"id": "1234",
+		},
+	})
+	assert.NoError(t, err)
+
+	message, err := NewMessage().
+		Text("A sticker for you").
+		Override(SourceTypeWhatsApp, override).
+		Build()
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(message)
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, map[string]interface{}{
+		"whatsapp": map[string]interface{}{
+			"payload": map[string]interface{}{
+				"type":    "sticker",
+				"sticker": map[string]interface{}{"id": "1234"},
+			},
+		},
+	}, decoded["override"])
+
+	roundTrip := &Message{}
+	assert.NoError(t, json.Unmarshal(data, roundTrip))
+	assert.JSONEq(t, string(override.Payload), string(roundTrip.Override[SourceTypeWhatsApp].Payload))
+}
+
+func TestMessageBuilderReplyTo(t *testing.T) {
+	message, err := NewMessage().
+		Text("Booked!").
+		ReplyTo("5c8a8f6b4ef0c7002f3c8e6a").
+		Build()
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(message)
+	assert.NoError(t, err)
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, map[string]interface{}{
+		"type":      "messageId",
+		"messageId": "5c8a8f6b4ef0c7002f3c8e6a",
+	}, decoded["quotedMessage"])
+
+	_, err = NewMessage().Text("Booked!").ReplyTo("").Build()
+	assert.IsType(t, &MessageValidationError{}, err)
+}
@@ -0,0 +1,232 @@
+package smooch
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	appUserCacheKeyPrefix = "smooch:appusers:"
+
+	// DefaultAppUserCacheTTL is how long app users are cached when the
+	// cache is given no ttl.
+	DefaultAppUserCacheTTL = 5 * time.Minute
+)
+
+// AppUserCache keeps the app users fetched with GetAppUser and its
+// variants, so handlers can look the user up on every webhook without an
+// API call each time. Set it as Options.AppUserCache.
+//
+// Users are cached under both their Smooch id and their external user id.
+// They are dropped when changed through the client, and when the webhooks
+// report them deleted, merged, or linked to or unlinked from a client; any
+// other change shows once ttl runs out.
+type AppUserCache struct {
+	storage Storage
+	ttl     time.Duration
+}
+
+// NewAppUserCache returns a cache keeping app users in storage, e.g. to
+// share them between instances.
+func NewAppUserCache(storage Storage, ttl time.Duration) *AppUserCache {
+	if ttl <= 0 {
+		ttl = DefaultAppUserCacheTTL
+	}
+	return &AppUserCache{
+		storage: storage,
+		ttl:     ttl,
+	}
+}
+
+// NewMemoryAppUserCache returns an in-process cache holding up to
+// maxEntries ids, evicting the least recently used ones.
+func NewMemoryAppUserCache(maxEntries int, ttl time.Duration) *AppUserCache {
+	return NewAppUserCache(newLRUStorage(maxEntries), ttl)
+}
+
+// Get returns the cached app user with the given Smooch or external id.
+func (c *AppUserCache) Get(ctx context.Context, userID string) (*AppUser, bool, error) {
+	value, ok, err := c.storage.Get(ctx, appUserCacheKeyPrefix+userID)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var appUser AppUser
+	if err := json.Unmarshal(value, &appUser); err != nil {
+		return nil, false, err
+	}
+	return &appUser, true, nil
+}
+
+// Put caches the app user under its ids.
+func (c *AppUserCache) Put(ctx context.Context, appUser *AppUser) error {
+	value, err := json.Marshal(appUser)
+	if err != nil {
+		return err
+	}
+	for _, id := range []string{appUser.ID, appUser.UserID} {
+		if id == "" {
+			continue
+		}
+		if err := c.storage.Set(ctx, appUserCacheKeyPrefix+id, value, c.ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invalidate drops the app users with the given Smooch or external ids,
+// under all of their ids.
+func (c *AppUserCache) Invalidate(ctx context.Context, userIDs ...string) error {
+	for _, userID := range userIDs {
+		if userID == "" {
+			continue
+		}
+		ids := []string{userID}
+		if appUser, ok, err := c.Get(ctx, userID); err != nil {
+			return err
+		} else if ok {
+			ids = append(ids, appUser.ID, appUser.UserID)
+		}
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			if err := c.storage.Delete(ctx, appUserCacheKeyPrefix+id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// invalidateAppUser drops the app users from the cache of the client, if
+// any. Errors are logged, as the change they follow went through.
+func (sc *smoochClient) invalidateAppUser(userIDs ...string) {
+	if sc.appUserCache == nil {
+		return
+	}
+	if err := sc.appUserCache.Invalidate(context.Background(), userIDs...); err != nil {
+		sc.logger.Errorw("app user cache invalidation failed", "userIds", userIDs, "err", err)
+	}
+}
+
+// invalidateAppUserEvent drops the app user of a webhook changing it.
+func (sc *smoochClient) invalidateAppUserEvent(e WebhookEvent) {
+	base := e.Base()
+	userIDs := []string{base.AppUser.ID, base.AppUser.UserID}
+	if unknown, ok := e.(*UnknownEvent); ok && unknown.Trigger == TriggerAppUserMerge {
+		if surviving := unknown.Payload.Surviving; surviving != nil {
+			userIDs = append(userIDs, surviving.ID, surviving.UserID)
+		}
+		for _, discarded := range unknown.Payload.Discarded {
+			userIDs = append(userIDs, discarded.ID, discarded.UserID)
+		}
+	}
+	sc.invalidateAppUser(userIDs...)
+}
+
+// lruStorage is an in-process Storage holding up to maxEntries keys.
+type lruStorage struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func newLRUStorage(maxEntries int) *lruStorage {
+	return &lruStorage{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+		now:        time.Now,
+	}
+}
+
+func (s *lruStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if s.expired(entry) {
+		s.remove(element)
+		return nil, false, nil
+	}
+	s.order.MoveToFront(element)
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+func (s *lruStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expiresAt = s.now().Add(ttl)
+	}
+	if element, ok := s.entries[key]; ok {
+		element.Value = entry
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(entry)
+	if s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+func (s *lruStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+	return nil
+}
+
+func (s *lruStorage) Keys(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key, element := range s.entries {
+		if s.expired(element.Value.(*lruEntry)) {
+			s.remove(element)
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *lruStorage) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*lruEntry).key)
+}
+
+func (s *lruStorage) expired(entry *lruEntry) bool {
+	return !entry.expiresAt.IsZero() && !s.now().Before(entry.expiresAt)
+}
@@ -0,0 +1,168 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppUserCache(t *testing.T) {
+	gets := 0
+	fn := func(req *http.Request) *http.Response {
+		if req.Method == http.MethodGet {
+			gets++
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"appUser": {"_id": "123", "userId": "steve", "givenName": "Steve"}}`))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		AppUserCache: NewMemoryAppUserCache(10, time.Minute),
+	})
+	assert.NoError(t, err)
+
+	appUser, respData, err := sc.GetAppUserByExternalID("steve")
+	assert.NoError(t, err)
+	assert.NotNil(t, respData)
+	assert.Equal(t, "Steve", appUser.GivenName)
+
+	// cached under both ids
+	appUser, respData, err = sc.GetAppUserByID("123")
+	assert.NoError(t, err)
+	assert.Nil(t, respData)
+	assert.Equal(t, "Steve", appUser.GivenName)
+	assert.Equal(t, 1, gets)
+
+	// calls failing validation don't
+	_, err = sc.UnlinkAppUserChannel("steve", "")
+	assert.Equal(t, ErrChannelTypeEmpty, err)
+	_, respData, err = sc.GetAppUserByID("123")
+	assert.NoError(t, err)
+	assert.Nil(t, respData)
+
+	// changes through the client drop the user under both ids
+	_, _, err = sc.SetAppUserProperties("steve", map[string]interface{}{"vip": true})
+	assert.NoError(t, err)
+	_, _, err = sc.GetAppUserByID("123")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, gets)
+
+	_, _, err = sc.AddAppUserClient("steve", ClientCreate{ID: "device", Platform: "ios"})
+	assert.NoError(t, err)
+	_, _, err = sc.GetAppUserByID("123")
+	assert.NoError(t, err)
+	_, err = sc.RemoveAppUserClient("123", "device")
+	assert.NoError(t, err)
+	_, _, err = sc.GetAppUserByID("123")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, gets)
+
+	// so do the webhooks changing the user
+	err = sc.dispatch(context.Background(), &Payload{
+		Trigger:   TriggerAppUserMerge,
+		Surviving: &AppUser{ID: "456"},
+		Discarded: []*AppUser{{ID: "123"}},
+	}, WebhookMeta{})
+	assert.NoError(t, err)
+	_, _, err = sc.GetAppUser("steve")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, gets)
+
+	err = sc.dispatch(context.Background(), &Payload{
+		Trigger: TriggerClientAdd,
+		AppUser: AppUser{ID: "123"},
+	}, WebhookMeta{})
+	assert.NoError(t, err)
+	_, _, err = sc.GetAppUser("123")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, gets)
+}
+
+func TestAppUserCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1444348338, 0)
+	storage := NewMemoryStorage()
+	storage.now = func() time.Time { return now }
+	cache := NewAppUserCache(storage, time.Minute)
+
+	assert.NoError(t, cache.Put(ctx, &AppUser{ID: "123"}))
+	_, ok, err := cache.Get(ctx, "123")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok, err = cache.Get(ctx, "123")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLRUStorage(t *testing.T) {
+	ctx := context.Background()
+	s := newLRUStorage(2)
+
+	assert.NoError(t, s.Set(ctx, "a", []byte("1"), 0))
+	assert.NoError(t, s.Set(ctx, "b", []byte("2"), 0))
+	_, ok, _ := s.Get(ctx, "a")
+	assert.True(t, ok)
+
+	// b is the least recently used
+	assert.NoError(t, s.Set(ctx, "c", []byte("3"), 0))
+	keys, err := s.Keys(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, keys)
+
+	assert.NoError(t, s.Delete(ctx, "a"))
+	value, ok, err := s.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("3"), value)
+	_, ok, _ = s.Get(ctx, "a")
+	assert.False(t, ok)
+}
+
+func TestAppUserCacheKeptOnFailedChange(t *testing.T) {
+	gets := 0
+	fn := func(req *http.Request) *http.Response {
+		if req.Method == http.MethodGet {
+			gets++
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"appUser": {"_id": "123", "userId": "steve"}}`))),
+			}
+		}
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error":{"code":"unavailable"}}`))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		AppUserCache: NewMemoryAppUserCache(10, time.Minute),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.GetAppUser("steve")
+	assert.NoError(t, err)
+
+	_, _, err = sc.UpdateAppUser("steve", AppUserUpdate{GivenName: "Steve"})
+	assert.Error(t, err)
+	_, err = sc.DeleteAppUser("steve")
+	assert.Error(t, err)
+	_, _, err = sc.UpdateUser("steve", UserUpdate{})
+	assert.Error(t, err)
+	_, _, err = sc.MergeUsers("steve", "456")
+	assert.Error(t, err)
+
+	_, _, err = sc.GetAppUser("123")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, gets)
+}
@@ -0,0 +1,132 @@
+package smooch
+
+import "unicode/utf8"
+
+// CarouselConstraints are the limits put on carousel messages.
+type CarouselConstraints struct {
+	MaxItems             int
+	MaxActions           int
+	MaxTitleLength       int
+	MaxDescriptionLength int
+	// TextFallback is set for channels without native carousels, where
+	// Smooch sends the items as text and only link actions survive.
+	TextFallback bool
+}
+
+var defaultCarouselConstraints = CarouselConstraints{
+	MaxItems:             10,
+	MaxActions:           3,
+	MaxTitleLength:       128,
+	MaxDescriptionLength: 128,
+}
+
+var carouselConstraints = map[string]CarouselConstraints{
+	SourceTypeMessenger: {MaxItems: 10, MaxActions: 3, MaxTitleLength: 80, MaxDescriptionLength: 80},
+	SourceTypeLine:      {MaxItems: 10, MaxActions: 3, MaxTitleLength: 40, MaxDescriptionLength: 60},
+	SourceTypeTelegram:  {MaxItems: 10, MaxActions: 3, MaxTitleLength: 128, MaxDescriptionLength: 128},
+	SourceTypeViber:     {MaxItems: 6, MaxActions: 3, MaxTitleLength: 85, MaxDescriptionLength: 85},
+	SourceTypeWeChat:    {MaxItems: 8, MaxActions: 1, MaxTitleLength: 64, MaxDescriptionLength: 120},
+	SourceTypeWhatsApp:  {MaxItems: 10, MaxActions: 3, MaxTitleLength: 128, MaxDescriptionLength: 128, TextFallback: true},
+	SourceTypeTwilio:    {MaxItems: 10, MaxActions: 3, MaxTitleLength: 128, MaxDescriptionLength: 128, TextFallback: true},
+}
+
+// CarouselBuilder builds carousel messages and checks them against the
+// limits of the channels they are sent to, which the API otherwise reports
+// as opaque rejections or silently truncates.
+type CarouselBuilder struct {
+	items    []*Item
+	channels []string
+	ratio    ImageRatio
+}
+
+func NewCarousel() *CarouselBuilder {
+	return &CarouselBuilder{}
+}
+
+func (b *CarouselBuilder) Item(item *Item) *CarouselBuilder {
+	b.items = append(b.items, item)
+	return b
+}
+
+// ForChannels adds the channels the carousel will be delivered to, their
+// limits are checked on top of the general ones.
+func (b *CarouselBuilder) ForChannels(channels ...string) *CarouselBuilder {
+	b.channels = append(b.channels, channels...)
+	return b
+}
+
+func (b *CarouselBuilder) ImageAspectRatio(ratio ImageRatio) *CarouselBuilder {
+	b.ratio = ratio
+	return b
+}
+
+// Build returns the carousel message, or a *MessageValidationError naming
+// the first limit it breaks.
+func (b *CarouselBuilder) Build() (*Message, error) {
+	err := checkCarousel("", defaultCarouselConstraints, b.items)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range b.channels {
+		constraints, ok := carouselConstraints[channel]
+		if !ok {
+			continue
+		}
+		err := checkCarousel(channel, constraints, b.items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	message := NewMessage().Type(MessageTypeCarousel).Item(b.items...)
+	if b.ratio != "" {
+		message.ImageAspectRatio(b.ratio)
+	}
+	return message.Build()
+}
+
+func checkCarousel(channel string, c CarouselConstraints, items []*Item) error {
+	prefix := "carousel"
+	if channel != "" {
+		prefix = channel + " carousel"
+	}
+
+	if len(items) > c.MaxItems {
+		return invalidMessage("%s has %d items, at most %d are allowed", prefix, len(items), c.MaxItems)
+	}
+
+	for i, item := range items {
+		if len(item.Actions) == 0 {
+			return invalidMessage("%s item %d needs at least one action", prefix, i)
+		}
+		if len(item.Actions) > c.MaxActions {
+			return invalidMessage("%s item %d has %d actions, at most %d are allowed", prefix, i, len(item.Actions), c.MaxActions)
+		}
+		if utf8.RuneCountInString(item.Title) > c.MaxTitleLength {
+			return invalidMessage("%s item %d title is longer than %d characters", prefix, i, c.MaxTitleLength)
+		}
+		if utf8.RuneCountInString(item.Description) > c.MaxDescriptionLength {
+			return invalidMessage("%s item %d description is longer than %d characters", prefix, i, c.MaxDescriptionLength)
+		}
+		if c.TextFallback {
+			for _, action := range item.Actions {
+				if action.Type != ActionTypeLink && action.Type != ActionTypeWebview {
+					return invalidMessage("%s is sent as text, so the %s action of item %d would be dropped", prefix, action.Type, i)
+				}
+			}
+		}
+	}
+
+	// LINE renders the items side by side and requires them to be uniform
+	if channel == SourceTypeLine && len(items) > 0 {
+		for i, item := range items {
+			if len(item.Actions) != len(items[0].Actions) {
+				return invalidMessage("%s items must all have the same number of actions, item %d differs", prefix, i)
+			}
+			if (item.MediaURL == "") != (items[0].MediaURL == "") {
+				return invalidMessage("%s items must all have an image or none, item %d differs", prefix, i)
+			}
+		}
+	}
+	return nil
+}
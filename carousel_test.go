@@ -0,0 +1,62 @@
+package smooch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func carouselItem(title string, actions ...*Action) *Item {
+	if len(actions) == 0 {
+		actions = []*Action{{Type: ActionTypePostback, Text: "Buy", Payload: title}}
+	}
+	return &Item{Title: title, Actions: actions}
+}
+
+func TestCarouselBuilder(t *testing.T) {
+	message, err := NewCarousel().
+		Item(carouselItem("Hat")).
+		Item(carouselItem("Scarf")).
+		ForChannels(SourceTypeMessenger, SourceTypeLine).
+		ImageAspectRatio(ImageRatioSquare).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeCarousel, message.Type)
+	assert.Len(t, message.Items, 2)
+	assert.Equal(t, ImageRatioSquare, message.DisplaySettings.ImageAspectRatio)
+}
+
+func TestCarouselBuilderLimits(t *testing.T) {
+	link := &Action{Type: ActionTypeLink, Text: "Open", URI: "https://example.org"}
+
+	tooMany := NewCarousel()
+	for i := 0; i < 11; i++ {
+		tooMany.Item(carouselItem("Hat"))
+	}
+
+	tests := []struct {
+		name    string
+		builder *CarouselBuilder
+		reason  string
+	}{
+		{"no items", NewCarousel(), "carousel messages need items"},
+		{"too many items", tooMany, "carousel has 11 items"},
+		{"no actions", NewCarousel().Item(&Item{Title: "Hat"}), "needs at least one action"},
+		{"too many actions", NewCarousel().Item(carouselItem("Hat", link, link, link, link)), "has 4 actions"},
+		{"messenger title", NewCarousel().Item(carouselItem(strings.Repeat("a", 81))).ForChannels(SourceTypeMessenger), "messenger carousel item 0 title"},
+		{"whatsapp fallback", NewCarousel().Item(carouselItem("Hat")).ForChannels(SourceTypeWhatsApp), "postback action of item 0 would be dropped"},
+		{"line uniform", NewCarousel().Item(carouselItem("Hat", link)).Item(carouselItem("Scarf", link, link)).ForChannels(SourceTypeLine), "same number of actions"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.builder.Build()
+			assert.IsType(t, &MessageValidationError{}, err)
+			assert.Contains(t, err.Error(), test.reason)
+		})
+	}
+
+	_, err := NewCarousel().Item(carouselItem("Hat", link)).ForChannels(SourceTypeWhatsApp).Build()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,129 @@
+package smooch
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The defaults follow the text limits documented by each channel at the
+// time of writing, use RegisterTextLimit when they change.
+var (
+	textLimitsMu sync.RWMutex
+	textLimits   = map[string]int{
+		SourceTypeWhatsApp:  4096,
+		SourceTypeTwilio:    1600,
+		SourceTypeMessenger: 2000,
+		SourceTypeTelegram:  4096,
+		SourceTypeViber:     7000,
+		SourceTypeLine:      5000,
+	}
+)
+
+// RegisterTextLimit adds or replaces the maximum length, in characters, of
+// the text messages of a channel.
+func RegisterTextLimit(channel string, limit int) {
+	textLimitsMu.Lock()
+	defer textLimitsMu.Unlock()
+	textLimits[channel] = limit
+}
+
+// ChannelTextLimit returns the text limit registered for a channel.
+func ChannelTextLimit(channel string) (int, bool) {
+	textLimitsMu.RLock()
+	defer textLimitsMu.RUnlock()
+	limit, ok := textLimits[channel]
+	return limit, ok
+}
+
+// WithTextChunking splits text messages longer than the text limit of
+// channel into several messages, sent one after the other. Send then
+// returns the response of the last one. Actions go with the last message
+// and a quoted message with the first. Channels without a registered limit
+// aren't split.
+func WithTextChunking(channel string) RequestOption {
+	return func(o *requestOptions) {
+		o.chunkChannel = channel
+	}
+}
+
+// SplitText splits text into chunks of at most limit characters, at word
+// boundaries when there are some.
+func SplitText(text string, limit int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	if limit <= 0 || len(runes) <= limit {
+		return []string{string(runes)}
+	}
+
+	var chunks []string
+	for len(runes) > limit {
+		cut := limit
+		// break after the last space that fits, words longer than the
+		// limit are cut
+		for i := limit; i > 0; i-- {
+			if unicode.IsSpace(runes[i]) {
+				cut = i
+				break
+			}
+		}
+		chunks = append(chunks, strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace))
+		runes = []rune(strings.TrimLeftFunc(string(runes[cut:]), unicode.IsSpace))
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// splitMessage splits a text message following WithTextChunking, or
+// returns nil when it doesn't need to be split.
+func (o *requestOptions) splitMessage(m *Message) []*Message {
+	if o.chunkChannel == "" || m.Type != MessageTypeText {
+		return nil
+	}
+	limit, ok := ChannelTextLimit(o.chunkChannel)
+	if !ok {
+		return nil
+	}
+	chunks := SplitText(m.Text, limit)
+	if len(chunks) < 2 {
+		return nil
+	}
+
+	messages := make([]*Message, len(chunks))
+	for i, chunk := range chunks {
+		part := *m
+		part.Text = chunk
+		if i > 0 {
+			part.QuotedMessage = nil
+		}
+		if i < len(chunks)-1 {
+			part.Actions = nil
+		}
+		messages[i] = &part
+	}
+	return messages
+}
+
+// sendChunks sends the messages in order, stopping at the first error.
+func (sc *smoochClient) sendChunks(userID string, messages []*Message, ro *requestOptions, opts []RequestOption) (*ResponsePayload, *ResponseData, error) {
+	var (
+		response *ResponsePayload
+		respData *ResponseData
+		err      error
+	)
+	key := ro.header.Get(idempotencyKeyHeaderKey)
+	for i, message := range messages {
+		chunkOpts := append(opts[:len(opts):len(opts)], WithTextChunking(""))
+		if key != "" {
+			// a shared key would have the API drop all but the first
+			chunkOpts = append(chunkOpts, WithIdempotencyKey(key+"-"+strconv.Itoa(i)))
+		}
+		response, respData, err = sc.Send(userID, message, chunkOpts...)
+		if err != nil {
+			return nil, respData, err
+		}
+	}
+	return response, respData, nil
+}
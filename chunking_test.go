@@ -0,0 +1,71 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitText(t *testing.T) {
+	assert.Equal(t, []string{"short"}, SplitText("short", 10))
+	assert.Equal(t, []string{"the quick", "brown fox", "jumps"}, SplitText("the quick brown fox jumps", 10))
+	assert.Equal(t, []string{"abcdefghij", "klm"}, SplitText("abcdefghijklm", 10))
+	assert.Equal(t, []string{"héllo", "wörld"}, SplitText("héllo  wörld", 6))
+
+	for _, chunk := range SplitText(strings.Repeat("lorem ipsum ", 500), 1600) {
+		assert.LessOrEqual(t, len([]rune(chunk)), 1600)
+	}
+}
+
+func TestSendWithTextChunking(t *testing.T) {
+	var (
+		texts   []string
+		actions []int
+		keys    []string
+	)
+	fn := func(req *http.Request) *http.Response {
+		var message Message
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&message))
+		texts = append(texts, message.Text)
+		actions = append(actions, len(message.Actions))
+		keys = append(keys, req.Header.Get(idempotencyKeyHeaderKey))
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	RegisterTextLimit("test", 10)
+	message := &Message{
+		Role:    RoleAppMaker,
+		Type:    MessageTypeText,
+		Text:    "the quick brown fox jumps",
+		Actions: []*Action{{Type: ActionTypeReply, Text: "More", Payload: "more"}},
+	}
+
+	response, _, err := sc.Send("TestUser", message, WithTextChunking("test"), WithIdempotencyKey("key"))
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, []string{"the quick", "brown fox", "jumps"}, texts)
+	assert.Equal(t, []int{0, 0, 1}, actions)
+	assert.Equal(t, []string{"key-0", "key-1", "key-2"}, keys)
+	assert.Equal(t, "the quick brown fox jumps", message.Text)
+
+	// channels without a limit aren't split
+	texts = nil
+	_, _, err = sc.Send("TestUser", message, WithTextChunking("unknown"))
+	assert.NoError(t, err)
+	assert.Len(t, texts, 1)
+}
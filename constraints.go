@@ -0,0 +1,106 @@
+package smooch
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// AttachmentConstraints are the limits a channel puts on attachments.
+type AttachmentConstraints struct {
+	// MaxSize is the maximum size in bytes, 0 for no limit.
+	MaxSize int64
+	// MIMETypes lists the accepted MIME types, a type ending in "/*"
+	// accepts the whole family. Every type is accepted when empty.
+	MIMETypes []string
+}
+
+// AttachmentConstraintError is returned when an attachment doesn't meet the
+// constraints of its destination channel.
+type AttachmentConstraintError struct {
+	Channel  string
+	MIMEType string
+	Size     int64
+	MaxSize  int64
+}
+
+func (e *AttachmentConstraintError) Error() string {
+	if e.MaxSize > 0 && e.Size > e.MaxSize {
+		return fmt.Sprintf("%s attachments are limited to %d bytes, got %d", e.Channel, e.MaxSize, e.Size)
+	}
+	return fmt.Sprintf("%s does not accept %s attachments", e.Channel, e.MIMEType)
+}
+
+// The defaults follow the limits documented by each channel at the time of
+// writing, use RegisterAttachmentConstraints when they change.
+var (
+	attachmentConstraintsMu sync.RWMutex
+	attachmentConstraints   = map[string]AttachmentConstraints{
+		SourceTypeWhatsApp:  {MaxSize: 16 << 20},
+		SourceTypeMessenger: {MaxSize: 25 << 20},
+		SourceTypeTelegram:  {MaxSize: 50 << 20},
+		SourceTypeViber:     {MaxSize: 50 << 20},
+		SourceTypeTwilio:    {MaxSize: 5 << 20},
+		SourceTypeLine: {
+			MaxSize:   10 << 20,
+			MIMETypes: []string{"image/jpeg", "image/png", "video/mp4", "audio/mp4", "audio/x-m4a"},
+		},
+		SourceTypeWeChat: {
+			MaxSize:   10 << 20,
+			MIMETypes: []string{"image/jpeg", "image/png", "image/gif", "audio/*", "video/mp4"},
+		},
+	}
+)
+
+// RegisterAttachmentConstraints adds or replaces the attachment constraints
+// of a channel.
+func RegisterAttachmentConstraints(channel string, constraints AttachmentConstraints) {
+	attachmentConstraintsMu.Lock()
+	defer attachmentConstraintsMu.Unlock()
+	attachmentConstraints[channel] = constraints
+}
+
+// ChannelAttachmentConstraints returns the attachment constraints registered
+// for a channel.
+func ChannelAttachmentConstraints(channel string) (AttachmentConstraints, bool) {
+	attachmentConstraintsMu.RLock()
+	defer attachmentConstraintsMu.RUnlock()
+	constraints, ok := attachmentConstraints[channel]
+	return constraints, ok
+}
+
+// ValidateAttachment checks an attachment against the constraints of
+// channel and returns an *AttachmentConstraintError when it doesn't meet
+// them. A negative size skips the size check. Channels without registered
+// constraints accept everything.
+func ValidateAttachment(channel string, mimeType string, size int64) error {
+	constraints, ok := ChannelAttachmentConstraints(channel)
+	if !ok {
+		return nil
+	}
+
+	err := &AttachmentConstraintError{
+		Channel:  channel,
+		MIMEType: mimeType,
+		Size:     size,
+		MaxSize:  constraints.MaxSize,
+	}
+
+	if constraints.MaxSize > 0 && size > constraints.MaxSize {
+		return err
+	}
+
+	if len(constraints.MIMETypes) == 0 {
+		return nil
+	}
+	mimeType = stripMIMEParams(mimeType)
+	for _, accepted := range constraints.MIMETypes {
+		if accepted == mimeType {
+			return nil
+		}
+		if strings.HasSuffix(accepted, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(accepted, "*")) {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package smooch
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAttachment(t *testing.T) {
+	assert.NoError(t, ValidateAttachment(SourceTypeWhatsApp, "video/mp4", 10<<20))
+	assert.NoError(t, ValidateAttachment(SourceTypeWhatsApp, "video/mp4", -1))
+	assert.NoError(t, ValidateAttachment(SourceTypeWeChat, "audio/amr", 1024))
+	assert.NoError(t, ValidateAttachment("unknown", "video/mp4", 1<<30))
+
+	err := ValidateAttachment(SourceTypeWhatsApp, "video/mp4", 20<<20)
+	assert.IsType(t, &AttachmentConstraintError{}, err)
+	assert.Equal(t, int64(16<<20), err.(*AttachmentConstraintError).MaxSize)
+	assert.Contains(t, err.Error(), "limited to")
+
+	err = ValidateAttachment(SourceTypeLine, "application/pdf", 1024)
+	assert.IsType(t, &AttachmentConstraintError{}, err)
+	assert.Equal(t, "line does not accept application/pdf attachments", err.Error())
+
+	RegisterAttachmentConstraints("custom", AttachmentConstraints{MIMETypes: []string{"image/*"}})
+	assert.NoError(t, ValidateAttachment("custom", "image/webp", 1<<30))
+	assert.Error(t, ValidateAttachment("custom", "video/mp4", 1024))
+}
+
+func TestUploadAttachmentValidatesChannel(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		t.Error("request should not be sent")
+		return nil
+	}
+
+	sc, err := New(Options{
+		VerifySecret:  "very-secure-test-secret",
+		HttpClient:    NewTestClient(fn),
+		MaxUploadSize: 100 << 20,
+	})
+	assert.NoError(t, err)
+
+	upload := NewAttachmentUpload("video/mp4")
+	upload.Channel = SourceTypeTwilio
+	r := NewBytesFileReader("video.mp4", bytes.Repeat([]byte{0}, 6<<20))
+	_, _, err = sc.UploadAttachment(r, upload)
+	assert.IsType(t, &AttachmentConstraintError{}, err)
+}
@@ -0,0 +1,295 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	ConversationTypePersonal = "personal"
+	ConversationTypeSDKGroup = "sdkGroup"
+
+	AuthorTypeBusiness = "business"
+	AuthorTypeUser     = "user"
+)
+
+// ConversationEntity is a conversation of the v2 API, where a user can have
+// several conversations, e.g. one per topic.
+type ConversationEntity struct {
+	ID               string    `json:"id"`
+	Type             string    `json:"type,omitempty"`
+	DisplayName      string    `json:"displayName,omitempty"`
+	Description      string    `json:"description,omitempty"`
+	IconURL          string    `json:"iconUrl,omitempty"`
+	Metadata         Metadata  `json:"metadata,omitempty"`
+	BusinessLastRead time.Time `json:"businessLastRead,omitempty"`
+	LastUpdatedAt    time.Time `json:"lastUpdatedAt,omitempty"`
+}
+
+// ConversationCreate describes a conversation to create for the
+// participants, given by user id.
+type ConversationCreate struct {
+	Type         string
+	Participants []string
+	DisplayName  string
+	Description  string
+	IconURL      string
+	Metadata     Metadata
+}
+
+func (c ConversationCreate) MarshalJSON() ([]byte, error) {
+	type participant struct {
+		UserID string `json:"userId"`
+	}
+	participants := make([]participant, len(c.Participants))
+	for i, userID := range c.Participants {
+		participants[i] = participant{UserID: userID}
+	}
+
+	conversationType := c.Type
+	if conversationType == "" {
+		conversationType = ConversationTypePersonal
+	}
+	return json.Marshal(struct {
+		Type         string        `json:"type"`
+		Participants []participant `json:"participants,omitempty"`
+		DisplayName  string        `json:"displayName,omitempty"`
+		Description  string        `json:"description,omitempty"`
+		IconURL      string        `json:"iconUrl,omitempty"`
+		Metadata     Metadata      `json:"metadata,omitempty"`
+	}{conversationType, participants, c.DisplayName, c.Description, c.IconURL, c.Metadata})
+}
+
+// MessageAuthor is the author of a message sent to a conversation.
+type MessageAuthor struct {
+	Type        string `json:"type"`
+	UserID      string `json:"userId,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
+}
+
+// MessageContent is the content of a message of the v2 API.
+type MessageContent struct {
+	Type           MessageType  `json:"type"`
+	Text           string       `json:"text,omitempty"`
+	MediaURL       string       `json:"mediaUrl,omitempty"`
+	MediaType      string       `json:"mediaType,omitempty"`
+	AltText        string       `json:"altText,omitempty"`
+	Actions        []*Action    `json:"actions,omitempty"`
+	Items          []*Item      `json:"items,omitempty"`
+	BlockChatInput bool         `json:"blockChatInput,omitempty"`
+	Coordinates    *Coordinates `json:"coordinates,omitempty"`
+	Location       *Location    `json:"location,omitempty"`
+}
+
+// ConversationMessage is a message of a conversation of the v2 API.
+type ConversationMessage struct {
+	ID       string          `json:"id,omitempty"`
+	Received time.Time       `json:"received,omitempty"`
+	Author   MessageAuthor   `json:"author"`
+	Content  *MessageContent `json:"content"`
+	Metadata Metadata        `json:"metadata,omitempty"`
+}
+
+type ConversationResponse struct {
+	Conversation *ConversationEntity `json:"conversation,omitempty"`
+}
+
+type ListConversationsResponse struct {
+	Conversations []*ConversationEntity `json:"conversations"`
+}
+
+type ConversationMessagesResponse struct {
+	Messages []*ConversationMessage `json:"messages"`
+}
+
+func (sc *smoochClient) CreateConversation(conversation ConversationCreate, opts ...RequestOption) (*ConversationEntity, *ResponseData, error) {
+	if len(conversation.Participants) == 0 {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/conversations", sc.appID),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(conversation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ConversationResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Conversation, respData, nil
+}
+
+// ListConversations lists the conversations of the user. Only the first
+// page is returned; pass page[after] with WithQueryParam for the next ones.
+func (sc *smoochClient) ListConversations(userID string, opts ...RequestOption) ([]*ConversationEntity, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/conversations", sc.appID),
+		ro.queryParams(url.Values{"filter[userId]": []string{userID}}),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListConversationsResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Conversations, respData, nil
+}
+
+func (sc *smoochClient) GetConversation(conversationID string, opts ...RequestOption) (*ConversationEntity, *ResponseData, error) {
+	if conversationID == "" {
+		return nil, nil, ErrConversationIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/conversations/%s", sc.appID, url.PathEscape(conversationID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ConversationResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Conversation, respData, nil
+}
+
+// UpdateConversationMetadata replaces the metadata of the conversation,
+// e.g. with routing tags, which webhooks then carry in
+// Payload.Conversation.Metadata.
+func (sc *smoochClient) UpdateConversationMetadata(conversationID string, metadata Metadata, opts ...RequestOption) (*ConversationEntity, *ResponseData, error) {
+	if conversationID == "" {
+		return nil, nil, ErrConversationIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/conversations/%s", sc.appID, url.PathEscape(conversationID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(struct {
+		Metadata Metadata `json:"metadata"`
+	}{metadata})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPatch, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ConversationResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Conversation, respData, nil
+}
+
+// SendToConversation sends the message to one conversation of a user, as
+// the business. Only the content fields of the message are sent.
+func (sc *smoochClient) SendToConversation(conversationID string, message *Message, opts ...RequestOption) ([]*ConversationMessage, *ResponseData, error) {
+	if conversationID == "" {
+		return nil, nil, ErrConversationIDEmpty
+	}
+
+	if message == nil {
+		return nil, nil, ErrMessageNil
+	}
+
+	if message.Type == "" {
+		return nil, nil, ErrMessageTypeEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/conversations/%s/messages", sc.appID, url.PathEscape(conversationID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(struct {
+		Author   MessageAuthor   `json:"author"`
+		Content  *MessageContent `json:"content"`
+		Metadata Metadata        `json:"metadata,omitempty"`
+	}{
+		Author: MessageAuthor{
+			Type:        AuthorTypeBusiness,
+			DisplayName: message.Name,
+			AvatarURL:   message.AvatarURL,
+		},
+		Content:  newMessageContent(message),
+		Metadata: message.Metadata,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ConversationMessagesResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Messages, respData, nil
+}
+
+func newMessageContent(m *Message) *MessageContent {
+	return &MessageContent{
+		Type:           m.Type,
+		Text:           m.Text,
+		MediaURL:       m.MediaURL,
+		MediaType:      m.MediaType,
+		AltText:        m.AltText,
+		Actions:        m.Actions,
+		Items:          m.Items,
+		BlockChatInput: m.BlockChatInput,
+		Coordinates:    m.Coordinates,
+		Location:       m.Location,
+	}
+}
@@ -0,0 +1,213 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	sampleConversationJson = `
+	{
+		"conversation": {
+			"id": "c7f6e6d6c3a637261bd9656f",
+			"type": "personal",
+			"displayName": "Booking #42",
+			"metadata": {"booking": 42},
+			"businessLastRead": "2020-10-21T14:47:47.532Z",
+			"lastUpdatedAt": "2020-10-21T15:02:11.160Z"
+		}
+	}`
+
+	sampleListConversationsJson = `
+	{
+		"conversations": [
+			{"id": "c7f6e6d6c3a637261bd9656f", "type": "personal", "displayName": "Booking #42"},
+			{"id": "d8a5f4d5c2a536160bc8545e", "type": "personal", "displayName": "Support"}
+		],
+		"meta": {"hasMore": false}
+	}`
+
+	sampleConversationMessagesJson = `
+	{
+		"messages": [
+			{
+				"id": "5f7b2c0e8f6f0e0000c4a1b2",
+				"received": "2020-10-21T15:02:11.160Z",
+				"author": {"type": "business", "displayName": "Concierge"},
+				"content": {"type": "text", "text": "Your room is ready"}
+			}
+		]
+	}`
+)
+
+func TestCreateConversation(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/v2/apps/app/conversations", req.URL.Path)
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"type": "personal",
+			"participants": [{"userId": "user"}],
+			"displayName": "Booking #42",
+			"metadata": {"booking": 42}
+		}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleConversationJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.CreateConversation(ConversationCreate{})
+	assert.Equal(t, ErrUserIDEmpty, err)
+
+	conversation, _, err := sc.CreateConversation(ConversationCreate{
+		Participants: []string{"user"},
+		DisplayName:  "Booking #42",
+		Metadata:     Metadata{"booking": 42},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "c7f6e6d6c3a637261bd9656f", conversation.ID)
+	assert.Equal(t, ConversationTypePersonal, conversation.Type)
+	booking, ok := conversation.Metadata.GetInt("booking")
+	assert.True(t, ok)
+	assert.Equal(t, 42, booking)
+	assert.Equal(t, 2020, conversation.BusinessLastRead.Year())
+}
+
+func TestListAndGetConversations(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodGet, req.Method)
+
+		body := sampleConversationJson
+		if req.URL.Path == "/v2/apps/app/conversations" {
+			assert.Equal(t, "user", req.URL.Query().Get("filter[userId]"))
+			body = sampleListConversationsJson
+		} else {
+			assert.Equal(t, "/v2/apps/app/conversations/c7f6e6d6c3a637261bd9656f", req.URL.Path)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	conversations, _, err := sc.ListConversations("user")
+	assert.NoError(t, err)
+	assert.Len(t, conversations, 2)
+	assert.Equal(t, "Support", conversations[1].DisplayName)
+
+	conversation, _, err := sc.GetConversation(conversations[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Booking #42", conversation.DisplayName)
+
+	_, _, err = sc.GetConversation("")
+	assert.Equal(t, ErrConversationIDEmpty, err)
+}
+
+func TestSendToConversation(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/v2/apps/app/conversations/c7f6e6d6c3a637261bd9656f/messages", req.URL.Path)
+
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{"type": "business", "displayName": "Concierge"}, body["author"])
+		assert.Equal(t, map[string]interface{}{"type": "text", "text": "Your room is ready"}, body["content"])
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleConversationMessagesJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	messages, _, err := sc.SendToConversation("c7f6e6d6c3a637261bd9656f", &Message{
+		Type: MessageTypeText,
+		Text: "Your room is ready",
+		Role: RoleAppMaker,
+		Name: "Concierge",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "5f7b2c0e8f6f0e0000c4a1b2", messages[0].ID)
+	assert.Equal(t, AuthorTypeBusiness, messages[0].Author.Type)
+	assert.Equal(t, "Your room is ready", messages[0].Content.Text)
+	assert.Equal(t, 2020, messages[0].Received.Year())
+
+	_, _, err = sc.SendToConversation("", &Message{Type: MessageTypeText})
+	assert.Equal(t, ErrConversationIDEmpty, err)
+}
+
+func TestUpdateConversationMetadata(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPatch, req.Method)
+		assert.Equal(t, "/v2/apps/app/conversations/c7f6e6d6c3a637261bd9656f", req.URL.Path)
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"metadata": {"department": "sales", "locale": "fr"}}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleConversationJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	conversation, _, err := sc.UpdateConversationMetadata("c7f6e6d6c3a637261bd9656f", Metadata{
+		"department": "sales",
+		"locale":     "fr",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "c7f6e6d6c3a637261bd9656f", conversation.ID)
+
+	_, _, err = sc.UpdateConversationMetadata("", nil)
+	assert.Equal(t, ErrConversationIDEmpty, err)
+}
+
+func TestConversationMetadataInWebhook(t *testing.T) {
+	var p Payload
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"trigger": "message:appUser",
+		"conversation": {"_id": "c7f6e6d6c3a637261bd9656f", "metadata": {"department": "sales"}}
+	}`), &p))
+
+	department, ok := p.Conversation.Metadata.GetString("department")
+	assert.True(t, ok)
+	assert.Equal(t, "sales", department)
+}
@@ -0,0 +1,210 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	redactedHeaders = []string{
+		authorizationHeaderKey,
+		"X-Api-Key",
+		"Cookie",
+		"Set-Cookie",
+	}
+
+	// redactedWords mark the JSON keys holding credentials, in any case and
+	// as any word of a camelCase key, e.g. pageAccessToken or callbackSecret.
+	redactedWords = map[string]bool{
+		"secret":   true,
+		"token":    true,
+		"password": true,
+		"key":      true,
+		"cert":     true,
+		"pin":      true,
+	}
+
+	// redactedFields are JSON keys holding personal data.
+	redactedFields = map[string]bool{
+		"email":       true,
+		"givenName":   true,
+		"surname":     true,
+		"phoneNumber": true,
+		"displayName": true,
+		"avatarUrl":   true,
+		"deviceId":    true,
+	}
+
+	// userPathSegments precede the user id in API paths.
+	userPathSegments = map[string]bool{
+		"appusers": true,
+		"users":    true,
+	}
+
+	// redactedQueryParams hold user ids.
+	redactedQueryParams = []string{"userId", "appUserId"}
+)
+
+// debugMiddleware logs every request and response through the logger with
+// credentials and personal data redacted. Multipart bodies are not logged.
+func debugMiddleware(logger Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var body []byte
+			if req.Body != nil && !isMultipart(req.Header) {
+				var err error
+				body, err = ioutil.ReadAll(req.Body)
+				req.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = ioutil.NopCloser(bytes.NewReader(body))
+			}
+			logger.Debugw("smooch request",
+				"method", req.Method,
+				"url", redactURL(req.URL),
+				"header", redactHeader(req.Header),
+				"body", string(redactBody(body)),
+			)
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				logger.Debugw("smooch response", "err", err)
+				return resp, err
+			}
+
+			body, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+			logger.Debugw("smooch response",
+				"status", resp.StatusCode,
+				"header", redactHeader(resp.Header),
+				"body", string(redactBody(body)),
+			)
+			return resp, nil
+		})
+	}
+}
+
+func isMultipart(header http.Header) bool {
+	return strings.HasPrefix(header.Get(contentTypeHeaderKey), "multipart/")
+}
+
+func redactHeader(header http.Header) http.Header {
+	clone := http.Header{}
+	for key, values := range header {
+		clone[key] = values
+	}
+	for _, key := range redactedHeaders {
+		if clone.Get(key) != "" {
+			clone.Set(key, redacted)
+		}
+	}
+	return clone
+}
+
+// redactBody masks redacted fields in JSON bodies. Bodies that are not JSON
+// are returned as they are.
+func redactBody(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+
+	redactedBody, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return body
+	}
+	return redactedBody
+}
+
+func redactValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for key, value := range x {
+			if redactedKey(key) {
+				x[key] = redacted
+				continue
+			}
+			x[key] = redactValue(value)
+		}
+	case []interface{}:
+		for i, value := range x {
+			x[i] = redactValue(value)
+		}
+	}
+	return v
+}
+
+func redactedKey(key string) bool {
+	if redactedFields[key] {
+		return true
+	}
+	for _, word := range splitKeyWords(key) {
+		if redactedWords[strings.ToLower(word)] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitKeyWords splits a camelCase, snake_case or kebab-case key into words.
+func splitKeyWords(key string) []string {
+	var words []string
+	start := 0
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-':
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+		case unicode.IsUpper(r) && i > start &&
+			(!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// redactURL masks the user ids in the path and query of u, as they are
+// often e-mail addresses.
+func redactURL(u *url.URL) string {
+	clone := *u
+	segments := strings.Split(clone.EscapedPath(), "/")
+	for i := 1; i < len(segments); i++ {
+		if userPathSegments[segments[i-1]] && segments[i] != "merge" {
+			segments[i] = "REDACTED"
+		}
+	}
+	clone.RawPath = strings.Join(segments, "/")
+	clone.Path, _ = url.PathUnescape(clone.RawPath)
+
+	query := clone.Query()
+	for _, key := range redactedQueryParams {
+		if query.Get(key) != "" {
+			query.Set(key, redacted)
+		}
+	}
+	clone.RawQuery = query.Encode()
+	return clone.String()
+}
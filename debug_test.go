@@ -0,0 +1,147 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+type testLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (tl *testLogger) log(level string, msg string, keysAndValues []interface{}) {
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.entries = append(tl.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (tl *testLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	tl.log("debug", msg, keysAndValues)
+}
+
+func (tl *testLogger) Infow(msg string, keysAndValues ...interface{}) {
+	tl.log("info", msg, keysAndValues)
+}
+
+func (tl *testLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	tl.log("error", msg, keysAndValues)
+}
+
+func (tl *testLogger) find(level string, msg string) []logEntry {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	var found []logEntry
+	for _, e := range tl.entries {
+		if e.level == level && e.msg == msg {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func TestDebugDump(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "Just put some vinegar on it")
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Set-Cookie": []string{"session=abc"}},
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetUserJson))),
+		}
+	}
+
+	logger := &testLogger{}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		Logger:       logger,
+		Debug:        true,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.Send("TestUser", &Message{
+		Role: RoleAppMaker,
+		Type: MessageTypeText,
+		Text: "Just put some vinegar on it",
+	})
+	assert.NoError(t, err)
+
+	requests := logger.find("debug", "smooch request")
+	assert.Len(t, requests, 1)
+	header := requests[0].fields["header"].(http.Header)
+	assert.Equal(t, redacted, header.Get(authorizationHeaderKey))
+	assert.Contains(t, requests[0].fields["body"], "Just put some vinegar on it")
+
+	responses := logger.find("debug", "smooch response")
+	assert.Len(t, responses, 1)
+	assert.Equal(t, http.StatusOK, responses[0].fields["status"])
+	header = responses[0].fields["header"].(http.Header)
+	assert.Equal(t, redacted, header.Get("Set-Cookie"))
+
+	body := responses[0].fields["body"].(string)
+	assert.Contains(t, body, "7494535bff5cef41a15be74d")
+	assert.False(t, strings.Contains(body, `"givenName":"Steve"`))
+	assert.Contains(t, body, `"givenName":"[REDACTED]"`)
+	assert.Contains(t, body, `"deviceId":"[REDACTED]"`)
+}
+
+func TestRedactBody(t *testing.T) {
+	assert.Equal(t, "not json", string(redactBody([]byte("not json"))))
+	assert.Equal(t,
+		`{"items":[{"email":"[REDACTED]","id":1}],"secret":"[REDACTED]"}`,
+		string(redactBody([]byte(`{"secret":"s3cr3t","items":[{"email":"bob@example.com","id":1}]}`))),
+	)
+}
+
+func TestRedactCredentials(t *testing.T) {
+	body := redactBody([]byte(`{
+		"pageAccessToken": "a", "appSecret": "b", "authToken": "c", "channelSecret": "d",
+		"channelAccessToken": "e", "switcherSecret": "f", "callbackSecret": "g", "cert": "h",
+		"pin": "i", "APIKey": "j", "client_secret": "k", "shipping": "l", "keyword": "m"
+	}`))
+
+	var fields map[string]string
+	assert.NoError(t, json.Unmarshal(body, &fields))
+	for key, value := range fields {
+		if key == "shipping" || key == "keyword" {
+			assert.NotEqual(t, redacted, value, key)
+			continue
+		}
+		assert.Equal(t, redacted, value, key)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	u, err := url.Parse("https://api.smooch.io/v1.1/apps/app/appusers/steve%2Fb@channel5.com/messages?before=1&userId=steve")
+	assert.NoError(t, err)
+	redactedURL := redactURL(u)
+	assert.NotContains(t, redactedURL, "steve")
+	assert.Contains(t, redactedURL, "/v1.1/apps/app/appusers/REDACTED/messages?")
+	assert.Contains(t, redactedURL, "before=1")
+
+	u, err = url.Parse("https://api.smooch.io/v2/apps/app/users/merge")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.smooch.io/v2/apps/app/users/merge", redactURL(u))
+}
@@ -0,0 +1,94 @@
+package smooch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	dedupeKeyPrefix = "smooch:webhooks:"
+
+	// DefaultDedupeTTL is how long webhooks are remembered when NewDeduplicator
+	// is given no ttl. Smooch stops retrying a webhook well before that.
+	DefaultDedupeTTL = 24 * time.Hour
+)
+
+// Deduplicator drops the webhooks Smooch delivers again after a timeout or
+// an error response. Set it as Options.WebhookDeduplicator.
+//
+// Messages are recognized by id, so a retried message:appUser webhook only
+// dispatches the messages that weren't seen yet; other triggers are
+// recognized by the hash of their body. Checking and recording aren't
+// atomic, so a webhook retried while the first delivery is still being
+// handled may go through twice.
+type Deduplicator struct {
+	storage Storage
+	ttl     time.Duration
+}
+
+func NewDeduplicator(storage Storage, ttl time.Duration) *Deduplicator {
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+	return &Deduplicator{
+		storage: storage,
+		ttl:     ttl,
+	}
+}
+
+// Filter removes what was already seen from the payload and records the
+// rest. It reports whether anything is left to dispatch.
+func (d *Deduplicator) Filter(ctx context.Context, p *Payload, body []byte) (bool, error) {
+	if len(p.Messages) == 0 {
+		sum := sha256.Sum256(body)
+		return d.record(ctx, "body:"+hex.EncodeToString(sum[:]))
+	}
+
+	messages := p.Messages[:0:0]
+	for _, message := range p.Messages {
+		if message.ID == "" {
+			messages = append(messages, message)
+			continue
+		}
+		isNew, err := d.record(ctx, "message:"+message.ID)
+		if err != nil {
+			return true, err
+		}
+		if isNew {
+			messages = append(messages, message)
+		}
+	}
+	p.Messages = messages
+	return len(messages) > 0, nil
+}
+
+// Forget removes what Filter recorded of the payload, so a retry of the
+// webhook is dispatched again. p is the payload as Filter left it.
+func (d *Deduplicator) Forget(ctx context.Context, p *Payload, body []byte) error {
+	if len(p.Messages) == 0 {
+		sum := sha256.Sum256(body)
+		return d.storage.Delete(ctx, dedupeKeyPrefix+"body:"+hex.EncodeToString(sum[:]))
+	}
+
+	for _, message := range p.Messages {
+		if message.ID == "" {
+			continue
+		}
+		if err := d.storage.Delete(ctx, dedupeKeyPrefix+"message:"+message.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record stores id and reports whether it wasn't stored already.
+func (d *Deduplicator) record(ctx context.Context, id string) (bool, error) {
+	key := dedupeKeyPrefix + id
+	_, seen, err := d.storage.Get(ctx, key)
+	if err != nil || seen {
+		return !seen, err
+	}
+	return true, d.storage.Set(ctx, key, []byte{1}, d.ttl)
+}
@@ -0,0 +1,89 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookDeduplication(t *testing.T) {
+	storage := NewMemoryStorage()
+	sc, err := New(Options{
+		VerifySecret:        "very-secure-test-secret",
+		WebhookDeduplicator: NewDeduplicator(storage, time.Hour),
+	})
+	assert.NoError(t, err)
+
+	var dispatched []*Payload
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		dispatched = append(dispatched, payload)
+	})
+
+	post := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Api-Key", "very-secure-test-secret")
+		w := httptest.NewRecorder()
+		sc.Handler().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	}
+
+	post(payloadExample1)
+	post(payloadExample1)
+	assert.Len(t, dispatched, 1)
+
+	// a retry batching a new message only dispatches the new one
+	post(`{
+		"trigger": "message:appUser",
+		"messages": [
+			{"_id": "55c8c1498590aa1900b9b9b1", "type": "text", "role": "appUser", "text": "Hi"},
+			{"_id": "55c8c1498590aa1900b9b9b2", "type": "text", "role": "appUser", "text": "Anyone?"}
+		]
+	}`)
+	assert.Len(t, dispatched, 2)
+	assert.Len(t, dispatched[1].Messages, 1)
+	assert.Equal(t, "55c8c1498590aa1900b9b9b2", dispatched[1].Messages[0].ID)
+
+	// triggers without messages are recognized by their body
+	post(errorPayloadExample)
+	post(errorPayloadExample)
+	assert.Len(t, dispatched, 3)
+
+	keys, err := storage.Keys(context.Background(), dedupeKeyPrefix)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
+type failingStorage struct {
+	Storage
+}
+
+func (failingStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("storage down")
+}
+
+func TestWebhookDeduplicationStorageFailure(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret:        "very-secure-test-secret",
+		WebhookDeduplicator: NewDeduplicator(failingStorage{}, 0),
+	})
+	assert.NoError(t, err)
+
+	calls := 0
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		calls++
+		assert.Len(t, payload.Messages, 1)
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(payloadExample1)))
+		req.Header.Set("X-Api-Key", "very-secure-test-secret")
+		sc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,212 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	DeploymentHostingSmooch = "smooch"
+	DeploymentHostingSelf   = "self"
+
+	DeploymentStatusStarting            = "starting"
+	DeploymentStatusUnregistered        = "unregistered"
+	DeploymentStatusRegistered          = "registered"
+	DeploymentStatusPendingVerification = "pendingVerification"
+	DeploymentStatusVerified            = "verified"
+	DeploymentStatusFailed              = "failed"
+
+	VerificationMethodSMS   = "sms"
+	VerificationMethodVoice = "voice"
+)
+
+// Deployment is a WhatsApp API client deployment. Once verified, its ID can
+// be used as WhatsAppIntegration.DeploymentID.
+type Deployment struct {
+	ID             string `json:"_id,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Hosting        string `json:"hosting,omitempty"`
+	BaseURL        string `json:"baseUrl,omitempty"`
+	Username       string `json:"username,omitempty"`
+	Password       string `json:"password,omitempty"`
+	PhoneNumber    string `json:"phoneNumber,omitempty"`
+	CallbackURL    string `json:"callbackUrl,omitempty"`
+	CallbackSecret string `json:"callbackSecret,omitempty"`
+}
+
+type DeploymentRegistration struct {
+	PhoneNumber string `json:"phoneNumber"`
+	// Method is VerificationMethodSMS or VerificationMethodVoice.
+	Method      string `json:"method,omitempty"`
+	Certificate string `json:"cert,omitempty"`
+	Pin         string `json:"pin,omitempty"`
+}
+
+type DeploymentResponse struct {
+	Deployment *Deployment `json:"deployment,omitempty"`
+}
+
+type ListDeploymentsResponse struct {
+	Deployments []*Deployment `json:"deployments"`
+}
+
+// CreateDeployment starts a new WhatsApp deployment. Deployments hosted by
+// Smooch take a while to start, poll GetDeployment until the status is
+// DeploymentStatusUnregistered before registering a phone number.
+func (ac *AccountClient) CreateDeployment(deployment *Deployment, opts ...RequestOption) (*Deployment, *ResponseData, error) {
+	if deployment == nil {
+		deployment = &Deployment{Hosting: DeploymentHostingSmooch}
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL("/v1.1/whatsapp/deployments", ro.queryParams(nil))
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(deployment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := ac.client.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response DeploymentResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Deployment, respData, nil
+}
+
+func (ac *AccountClient) ListDeployments(opts ...RequestOption) ([]*Deployment, *ResponseData, error) {
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL("/v1.1/whatsapp/deployments", ro.queryParams(nil))
+
+	req, err := ac.client.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListDeploymentsResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Deployments, respData, nil
+}
+
+func (ac *AccountClient) GetDeployment(deploymentID string, opts ...RequestOption) (*Deployment, *ResponseData, error) {
+	if deploymentID == "" {
+		return nil, nil, ErrDeploymentIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/whatsapp/deployments/%s", url.PathEscape(deploymentID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := ac.client.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response DeploymentResponse
+	respData, err := ac.client.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Deployment, respData, nil
+}
+
+func (ac *AccountClient) DeleteDeployment(deploymentID string, opts ...RequestOption) (*ResponseData, error) {
+	if deploymentID == "" {
+		return nil, ErrDeploymentIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/whatsapp/deployments/%s", url.PathEscape(deploymentID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := ac.client.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	return ac.client.sendRequest(req, nil)
+}
+
+// RegisterDeployment registers a phone number with the deployment. WhatsApp
+// then sends a verification code to the number, which has to be passed to
+// VerifyDeployment.
+func (ac *AccountClient) RegisterDeployment(deploymentID string, registration DeploymentRegistration, opts ...RequestOption) (*ResponseData, error) {
+	if deploymentID == "" {
+		return nil, ErrDeploymentIDEmpty
+	}
+
+	if registration.PhoneNumber == "" {
+		return nil, ErrPhoneNumberEmpty
+	}
+
+	if registration.Method == "" {
+		registration.Method = VerificationMethodSMS
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/whatsapp/deployments/%s/register", url.PathEscape(deploymentID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(&registration)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := ac.client.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	return ac.client.sendRequest(req, nil)
+}
+
+func (ac *AccountClient) VerifyDeployment(deploymentID string, code string, opts ...RequestOption) (*ResponseData, error) {
+	if deploymentID == "" {
+		return nil, ErrDeploymentIDEmpty
+	}
+
+	if code == "" {
+		return nil, ErrVerificationCodeEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := ac.client.getURL(
+		fmt.Sprintf("/v1.1/whatsapp/deployments/%s/verify", url.PathEscape(deploymentID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(map[string]string{"code": code})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := ac.client.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	return ac.client.sendRequest(req, nil)
+}
@@ -0,0 +1,94 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sampleDeploymentJson = `
+	{
+		"deployment": {
+			"_id": "5e9f1cbd2e3ee4000cfa6e04",
+			"status": "unregistered",
+			"hosting": "smooch"
+		}
+	}`
+
+func TestDeployments(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		body := sampleDeploymentJson
+
+		switch req.URL.Path {
+		case "/v1.1/whatsapp/deployments":
+			if req.Method == http.MethodPost {
+				data, err := ioutil.ReadAll(req.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{"hosting":"smooch"}`, string(data))
+			} else {
+				assert.Equal(t, http.MethodGet, req.Method)
+				body = `{"deployments":[{"_id":"5e9f1cbd2e3ee4000cfa6e04","status":"verified"}]}`
+			}
+		case "/v1.1/whatsapp/deployments/5e9f1cbd2e3ee4000cfa6e04":
+			if req.Method == http.MethodDelete {
+				body = "{}"
+			}
+		case "/v1.1/whatsapp/deployments/5e9f1cbd2e3ee4000cfa6e04/register":
+			assert.Equal(t, http.MethodPost, req.Method)
+			data, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"phoneNumber":"+15145555333","method":"sms"}`, string(data))
+			body = "{}"
+		case "/v1.1/whatsapp/deployments/5e9f1cbd2e3ee4000cfa6e04/verify":
+			assert.Equal(t, http.MethodPost, req.Method)
+			data, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"code":"123456"}`, string(data))
+			body = "{}"
+		default:
+			t.Errorf("unexpected path %s", req.URL.Path)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	ac := newAccountTestClient(t, fn)
+
+	deployment, _, err := ac.CreateDeployment(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "5e9f1cbd2e3ee4000cfa6e04", deployment.ID)
+	assert.Equal(t, DeploymentStatusUnregistered, deployment.Status)
+
+	deployment, _, err = ac.GetDeployment(deployment.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, DeploymentHostingSmooch, deployment.Hosting)
+
+	_, err = ac.RegisterDeployment(deployment.ID, DeploymentRegistration{PhoneNumber: "+15145555333"})
+	assert.NoError(t, err)
+
+	_, err = ac.VerifyDeployment(deployment.ID, "123456")
+	assert.NoError(t, err)
+
+	deployments, _, err := ac.ListDeployments()
+	assert.NoError(t, err)
+	assert.Len(t, deployments, 1)
+	assert.Equal(t, DeploymentStatusVerified, deployments[0].Status)
+
+	_, err = ac.DeleteDeployment(deployment.ID)
+	assert.NoError(t, err)
+
+	_, _, err = ac.GetDeployment("")
+	assert.Equal(t, ErrDeploymentIDEmpty, err)
+
+	_, err = ac.RegisterDeployment(deployment.ID, DeploymentRegistration{})
+	assert.Equal(t, ErrPhoneNumberEmpty, err)
+
+	_, err = ac.VerifyDeployment(deployment.ID, "")
+	assert.Equal(t, ErrVerificationCodeEmpty, err)
+}
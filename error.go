@@ -7,14 +7,26 @@ import (
 )
 
 type SmoochError struct {
-	message string
-	code    int
+	message      string
+	code         int
+	errorCode    string
+	responseData *ResponseData
 }
 
 func (e *SmoochError) Code() int {
 	return e.code
 }
 
+// ErrorCode returns the error code reported by Smooch, e.g. "unauthorized".
+func (e *SmoochError) ErrorCode() string {
+	return e.errorCode
+}
+
+// ResponseData returns the raw response that caused the error.
+func (e *SmoochError) ResponseData() *ResponseData {
+	return e.responseData
+}
+
 func (e *SmoochError) Error() string {
 	return e.message
 }
@@ -32,7 +44,8 @@ func checkSmoochError(r *http.Response) error {
 			errorPayload.Details.Code,
 			errorPayload.Details.Description,
 		),
-		code: r.StatusCode,
+		code:      r.StatusCode,
+		errorCode: errorPayload.Details.Code,
 	}
 
 	return err
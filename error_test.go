@@ -30,4 +30,5 @@ func TestCheckSmoochError(t *testing.T) {
 	err := checkSmoochError(response)
 	assert.Error(t, err)
 	assert.EqualError(t, err, "StatusCode: 401 Code: unauthorized Message: Authorization is required")
+	assert.Equal(t, "unauthorized", err.(*SmoochError).ErrorCode())
 }
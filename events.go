@@ -0,0 +1,308 @@
+package smooch
+
+import "time"
+
+// Event holds the fields every webhook carries. It is embedded in the typed
+// events returned by DecodeEvent.
+type Event struct {
+	Trigger      string
+	App          Application
+	AppUser      AppUser
+	Conversation Conversation
+	Client       *AppUserClient
+	Version      string
+	Timestamp    time.Time
+}
+
+func (e *Event) event() *Event { return e }
+
+// Base returns the fields shared by every event.
+func (e *Event) Base() *Event { return e }
+
+// WebhookEvent is one of the typed events returned by DecodeEvent. Use a
+// type switch to tell them apart:
+//
+//	switch e := smooch.DecodeEvent(payload).(type) {
+//	case *smooch.MessageAppUserEvent:
+//		...
+//	case *smooch.PostbackEvent:
+//		...
+//	}
+type WebhookEvent interface {
+	Base() *Event
+	event() *Event
+}
+
+// MessageAppUserEvent is sent when an app user sends messages.
+type MessageAppUserEvent struct {
+	Event
+	Messages []*Message
+}
+
+// MessageAppMakerEvent is sent when messages are sent to an app user, by the
+// API or by a business system.
+type MessageAppMakerEvent struct {
+	Event
+	Messages []*Message
+}
+
+// DeliveryEvent is sent when a message reached the channel or the user's
+// device, for TriggerMessageDeliveryChannel and TriggerMessageDeliveryUser.
+type DeliveryEvent struct {
+	Event
+	Destination  *SourceDestination
+	Message      *TruncatedMessage
+	IsFinalEvent bool
+}
+
+// DeliveryFailureEvent is sent when a message could not be delivered to a
+// channel.
+type DeliveryFailureEvent struct {
+	Event
+	Destination  *SourceDestination
+	Message      *TruncatedMessage
+	Error        *Error
+	IsFinalEvent bool
+}
+
+// PostbackEvent is sent when an app user taps postback actions.
+type PostbackEvent struct {
+	Event
+	Postbacks []*Postback
+}
+
+// ConversationStartEvent is sent when an app user starts a conversation.
+// Referral is set when it was started from a link or an ad.
+type ConversationStartEvent struct {
+	Event
+	Referral *Referral
+}
+
+// ConversationReferralEvent is sent when an app user already in a
+// conversation follows a link or an ad again.
+type ConversationReferralEvent struct {
+	Event
+	Referral *Referral
+}
+
+// ConversationReadEvent is sent when an app user reads the conversation.
+// LastRead is when it was read, Source the channel it was read on.
+type ConversationReadEvent struct {
+	Event
+	Source   *SourceDestination
+	LastRead time.Time
+}
+
+// TypingEvent is sent when an app user starts or stops typing.
+type TypingEvent struct {
+	Event
+	Source *SourceDestination
+	Typing bool
+}
+
+// LinkEvent is sent while linking an app user to a channel, with
+// TriggerLinkMatch once the channel user is found and TriggerLinkSuccess once
+// it is linked. Client is the linked client.
+type LinkEvent struct {
+	Event
+	Destination *SourceDestination
+}
+
+// LinkFailureEvent is sent when linking an app user to a channel failed,
+// e.g. because the phone number could not be reached.
+type LinkFailureEvent struct {
+	Event
+	Destination *SourceDestination
+	Error       *Error
+}
+
+// PaymentEvent is sent when app users pay for buy actions.
+type PaymentEvent struct {
+	Event
+	Payments []*Payment
+}
+
+// ClientEvent is sent when a client, such as a device or a channel, is
+// added to or removed from an app user. Client is the added or removed one.
+type ClientEvent struct {
+	Event
+}
+
+// AppUserDeleteEvent is sent when an app user is deleted, from the
+// dashboard or with DeleteAppUser. AppUser only has the ids of the deleted
+// user.
+type AppUserDeleteEvent struct {
+	Event
+}
+
+// UnknownEvent is returned for the triggers without a typed event. Payload
+// has every field that was decoded.
+type UnknownEvent struct {
+	Event
+	Payload *Payload
+}
+
+var eventDecoders = map[string]func(e Event, p *Payload) WebhookEvent{
+	TriggerMessageAppUser: func(e Event, p *Payload) WebhookEvent {
+		return &MessageAppUserEvent{Event: e, Messages: p.Messages}
+	},
+	TriggerMessageAppMaker: func(e Event, p *Payload) WebhookEvent {
+		return &MessageAppMakerEvent{Event: e, Messages: p.Messages}
+	},
+	TriggerMessageDeliveryChannel: decodeDeliveryEvent,
+	TriggerMessageDeliveryUser:    decodeDeliveryEvent,
+	TriggerMessageDeliveryFailure: func(e Event, p *Payload) WebhookEvent {
+		return &DeliveryFailureEvent{
+			Event:        e,
+			Destination:  p.Destination,
+			Message:      p.Message,
+			Error:        p.Error,
+			IsFinalEvent: p.IsFinalEvent,
+		}
+	},
+	TriggerPostback: func(e Event, p *Payload) WebhookEvent {
+		return &PostbackEvent{Event: e, Postbacks: p.Postbacks}
+	},
+	TriggerConversationStart: func(e Event, p *Payload) WebhookEvent {
+		return &ConversationStartEvent{Event: e, Referral: p.Referral}
+	},
+	TriggerConversationReferral: func(e Event, p *Payload) WebhookEvent {
+		return &ConversationReferralEvent{Event: e, Referral: p.Referral}
+	},
+	TriggerConversationRead: func(e Event, p *Payload) WebhookEvent {
+		return &ConversationReadEvent{Event: e, Source: p.Source, LastRead: p.Timestamp}
+	},
+	TriggerTypingAppUser: func(e Event, p *Payload) WebhookEvent {
+		typing := p.Activity != nil && p.Activity.Type == ActivityTypingStart
+		return &TypingEvent{Event: e, Source: p.Source, Typing: typing}
+	},
+	TriggerLinkMatch:   decodeLinkEvent,
+	TriggerLinkSuccess: decodeLinkEvent,
+	TriggerLinkFailure: func(e Event, p *Payload) WebhookEvent {
+		return &LinkFailureEvent{Event: e, Destination: p.Destination, Error: p.Error}
+	},
+	TriggerPaymentSuccess: func(e Event, p *Payload) WebhookEvent {
+		return &PaymentEvent{Event: e, Payments: p.Payments}
+	},
+	TriggerClientAdd:    decodeClientEvent,
+	TriggerClientRemove: decodeClientEvent,
+	TriggerAppUserDelete: func(e Event, p *Payload) WebhookEvent {
+		return &AppUserDeleteEvent{Event: e}
+	},
+}
+
+func decodeDeliveryEvent(e Event, p *Payload) WebhookEvent {
+	return &DeliveryEvent{
+		Event:        e,
+		Destination:  p.Destination,
+		Message:      p.Message,
+		IsFinalEvent: p.IsFinalEvent,
+	}
+}
+
+func decodeLinkEvent(e Event, p *Payload) WebhookEvent {
+	return &LinkEvent{Event: e, Destination: p.Destination}
+}
+
+func decodeClientEvent(e Event, p *Payload) WebhookEvent {
+	return &ClientEvent{Event: e}
+}
+
+// DecodeEvent returns the typed event of the payload's trigger, or an
+// *UnknownEvent for the triggers without one.
+func DecodeEvent(p *Payload) WebhookEvent {
+	e := Event{
+		Trigger:      p.Trigger,
+		App:          p.App,
+		AppUser:      p.AppUser,
+		Conversation: p.Conversation,
+		Client:       p.Client,
+		Version:      p.Version,
+		Timestamp:    p.Timestamp,
+	}
+
+	decode, ok := eventDecoders[p.Trigger]
+	if !ok {
+		return &UnknownEvent{Event: e, Payload: p}
+	}
+	return decode(e, p)
+}
+
+// OnMessageAppUser registers fn for TriggerMessageAppUser webhooks. Typed
+// handlers run after the ones added with AddWebhookEventHandler.
+func (ep *WebhookEndpoint) OnMessageAppUser(fn func(e *MessageAppUserEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*MessageAppUserEvent)) }, TriggerMessageAppUser)
+}
+
+func (ep *WebhookEndpoint) OnMessageAppMaker(fn func(e *MessageAppMakerEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*MessageAppMakerEvent)) }, TriggerMessageAppMaker)
+}
+
+// OnDelivery registers fn for both channel and user delivery webhooks; the
+// trigger of the event tells them apart.
+func (ep *WebhookEndpoint) OnDelivery(fn func(e *DeliveryEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*DeliveryEvent)) }, TriggerMessageDeliveryChannel, TriggerMessageDeliveryUser)
+}
+
+func (ep *WebhookEndpoint) OnDeliveryFailure(fn func(e *DeliveryFailureEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*DeliveryFailureEvent)) }, TriggerMessageDeliveryFailure)
+}
+
+func (ep *WebhookEndpoint) OnPostback(fn func(e *PostbackEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*PostbackEvent)) }, TriggerPostback)
+}
+
+func (ep *WebhookEndpoint) OnConversationStart(fn func(e *ConversationStartEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*ConversationStartEvent)) }, TriggerConversationStart)
+}
+
+func (ep *WebhookEndpoint) OnConversationReferral(fn func(e *ConversationReferralEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*ConversationReferralEvent)) }, TriggerConversationReferral)
+}
+
+func (ep *WebhookEndpoint) OnConversationRead(fn func(e *ConversationReadEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*ConversationReadEvent)) }, TriggerConversationRead)
+}
+
+// OnTyping registers fn for typing:appUser webhooks, sent when app users
+// start and stop typing.
+func (ep *WebhookEndpoint) OnTyping(fn func(e *TypingEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*TypingEvent)) }, TriggerTypingAppUser)
+}
+
+// OnLink registers fn for both link:match and link:success webhooks; the
+// trigger of the event tells them apart.
+func (ep *WebhookEndpoint) OnLink(fn func(e *LinkEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*LinkEvent)) }, TriggerLinkMatch, TriggerLinkSuccess)
+}
+
+func (ep *WebhookEndpoint) OnLinkFailure(fn func(e *LinkFailureEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*LinkFailureEvent)) }, TriggerLinkFailure)
+}
+
+func (ep *WebhookEndpoint) OnPaymentSuccess(fn func(e *PaymentEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*PaymentEvent)) }, TriggerPaymentSuccess)
+}
+
+func (ep *WebhookEndpoint) OnClientAdd(fn func(e *ClientEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*ClientEvent)) }, TriggerClientAdd)
+}
+
+func (ep *WebhookEndpoint) OnClientRemove(fn func(e *ClientEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*ClientEvent)) }, TriggerClientRemove)
+}
+
+// OnAppUserDelete registers fn for appUser:delete webhooks, so copies of
+// the user's data can be purged.
+func (ep *WebhookEndpoint) OnAppUserDelete(fn func(e *AppUserDeleteEvent)) {
+	ep.on(func(e WebhookEvent) { fn(e.(*AppUserDeleteEvent)) }, TriggerAppUserDelete)
+}
+
+func (ep *WebhookEndpoint) on(handler func(e WebhookEvent), triggers ...string) {
+	if ep.eventHandlers == nil {
+		ep.eventHandlers = map[string][]func(e WebhookEvent){}
+	}
+	for _, trigger := range triggers {
+		ep.eventHandlers[trigger] = append(ep.eventHandlers[trigger], handler)
+	}
+}
@@ -0,0 +1,312 @@
+package smooch
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(payloadExample1), payload))
+
+	event, ok := DecodeEvent(payload).(*MessageAppUserEvent)
+	assert.True(t, ok)
+	assert.Equal(t, TriggerMessageAppUser, event.Trigger)
+	assert.Equal(t, "5698edbf2a43bd081be982f1", event.App.ID)
+	assert.Len(t, event.Messages, 1)
+
+	payload = &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(errorPayloadExample), payload))
+
+	failure, ok := DecodeEvent(payload).(*DeliveryFailureEvent)
+	assert.True(t, ok)
+	assert.Equal(t, "unauthorized", failure.Error.Code)
+	assert.Equal(t, "5baa610db5bebb000ce855d6", failure.Message.ID)
+	assert.Equal(t, "line", failure.Destination.Type)
+	assert.True(t, failure.IsFinalEvent)
+	assert.Equal(t, payload.Timestamp, failure.Timestamp)
+}
+
+func TestDecodeEventByTrigger(t *testing.T) {
+	tests := map[string]WebhookEvent{
+		TriggerMessageAppMaker:        &MessageAppMakerEvent{},
+		TriggerMessageDeliveryChannel: &DeliveryEvent{},
+		TriggerMessageDeliveryUser:    &DeliveryEvent{},
+		TriggerPostback:               &PostbackEvent{},
+		TriggerConversationStart:      &ConversationStartEvent{},
+		TriggerConversationReferral:   &ConversationReferralEvent{},
+		TriggerConversationRead:       &ConversationReadEvent{},
+		TriggerTypingAppUser:          &TypingEvent{},
+		TriggerLinkMatch:              &LinkEvent{},
+		TriggerLinkSuccess:            &LinkEvent{},
+		TriggerLinkFailure:            &LinkFailureEvent{},
+		TriggerPaymentSuccess:         &PaymentEvent{},
+		TriggerClientAdd:              &ClientEvent{},
+		TriggerClientRemove:           &ClientEvent{},
+		TriggerAppUserDelete:          &AppUserDeleteEvent{},
+		"merge:appUser":               &UnknownEvent{},
+	}
+	for trigger, expected := range tests {
+		event := DecodeEvent(&Payload{Trigger: trigger})
+		assert.IsType(t, expected, event, trigger)
+		assert.Equal(t, trigger, event.event().Trigger)
+	}
+}
+
+func TestTypedEventHandlers(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var calls []string
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		calls = append(calls, "any")
+	})
+	sc.OnMessageAppUser(func(e *MessageAppUserEvent) {
+		calls = append(calls, "message")
+		assert.Len(t, e.Messages, 1)
+	})
+	sc.OnDelivery(func(e *DeliveryEvent) {
+		calls = append(calls, "delivery:"+e.Trigger)
+	})
+	sc.OnPostback(func(e *PostbackEvent) {
+		t.Error("postback handler should not be called")
+	})
+
+	sc.dispatch(context.Background(), &Payload{Trigger: TriggerMessageAppUser, Messages: []*Message{{}}}, WebhookMeta{})
+	sc.dispatch(context.Background(), &Payload{Trigger: TriggerMessageDeliveryUser}, WebhookMeta{})
+	sc.dispatch(context.Background(), &Payload{Trigger: TriggerMessageDeliveryChannel}, WebhookMeta{})
+	sc.dispatch(context.Background(), &Payload{Trigger: TriggerMessageDeliveryFailure}, WebhookMeta{})
+
+	assert.Equal(t, []string{
+		"any", "message",
+		"any", "delivery:" + TriggerMessageDeliveryUser,
+		"any", "delivery:" + TriggerMessageDeliveryChannel,
+		"any",
+	}, calls)
+}
+
+func TestConversationReadEvent(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var read *ConversationReadEvent
+	sc.OnConversationRead(func(e *ConversationReadEvent) {
+		read = e
+	})
+
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"trigger": "conversation:read",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+		"conversation": {"_id": "105e47578be874292d365ee8"},
+		"source": {"type": "messenger"},
+		"timestamp": 1480349392.103,
+		"version": "v1.1"
+	}`), payload))
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	assert.NotNil(t, read)
+	assert.Equal(t, "105e47578be874292d365ee8", read.Conversation.ID)
+	assert.Equal(t, SourceTypeMessenger, read.Source.Type)
+	assert.Equal(t, time.Unix(1480349392, 103*int64(time.Millisecond)), read.LastRead)
+}
+
+func TestTypingEvent(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var typing []bool
+	sc.OnTyping(func(e *TypingEvent) {
+		assert.Equal(t, SourceTypeWhatsApp, e.Source.Type)
+		typing = append(typing, e.Typing)
+	})
+
+	for _, activity := range []string{ActivityTypingStart, ActivityTypingStop} {
+		payload := &Payload{}
+		assert.NoError(t, json.Unmarshal([]byte(`{
+			"trigger": "typing:appUser",
+			"app": {"_id": "5698edbf2a43bd081be982f1"},
+			"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+			"conversation": {"_id": "105e47578be874292d365ee8"},
+			"source": {"type": "whatsapp"},
+			"activity": {"type": "`+activity+`"},
+			"version": "v1.1"
+		}`), payload))
+		sc.dispatch(context.Background(), payload, WebhookMeta{})
+	}
+
+	assert.Equal(t, []bool{true, false}, typing)
+}
+
+func TestLinkEvents(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var linked *LinkEvent
+	var failure *LinkFailureEvent
+	sc.OnLink(func(e *LinkEvent) {
+		linked = e
+	})
+	sc.OnLinkFailure(func(e *LinkFailureEvent) {
+		failure = e
+	})
+
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"trigger": "link:success",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+		"destination": {"type": "twilio"},
+		"client": {
+			"_id": "5c9d2f34a1d3a2504bc89512",
+			"platform": "twilio",
+			"displayName": "+15145555333",
+			"integrationId": "599ad41e49db6e8e9efa4b2b",
+			"active": true,
+			"primary": false
+		},
+		"version": "v1.1"
+	}`), payload))
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	assert.NotNil(t, linked)
+	assert.Equal(t, TriggerLinkSuccess, linked.Trigger)
+	assert.Equal(t, "twilio", linked.Destination.Type)
+	assert.Equal(t, "+15145555333", linked.Client.DisplayName)
+
+	payload = &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"trigger": "link:failure",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+		"destination": {"type": "twilio"},
+		"error": {"code": "invalid_phone_number", "message": "The phone number is invalid"},
+		"version": "v1.1"
+	}`), payload))
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	assert.NotNil(t, failure)
+	assert.Equal(t, "invalid_phone_number", failure.Error.Code)
+}
+
+func TestPaymentEvent(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var payment *PaymentEvent
+	sc.OnPaymentSuccess(func(e *PaymentEvent) {
+		payment = e
+	})
+
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"trigger": "payment:success",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+		"payments": [{
+			"source": {"type": "messenger"},
+			"message": {"_id": "5825f4c5c4ed9c350d2d81b1", "type": "text", "role": "appMaker", "text": "Room 42, 2 nights"},
+			"action": {
+				"_id": "5825f4c5c4ed9c350d2d81b2",
+				"type": "buy",
+				"text": "Pay now",
+				"amount": 19800,
+				"currency": "eur",
+				"state": "paid"
+			},
+			"charge": {"id": "ch_18x1lTAvBaZgK0KRD6m7HQmb"}
+		}],
+		"timestamp": 1480001711.941,
+		"version": "v1.1"
+	}`), payload))
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	assert.NotNil(t, payment)
+	assert.Len(t, payment.Payments, 1)
+	paid := payment.Payments[0]
+	assert.Equal(t, ActionTypeBuy, paid.Action.Type)
+	assert.Equal(t, ActionStatePaid, paid.Action.State)
+	assert.Equal(t, 19800, paid.Action.Amount)
+	assert.Equal(t, "ch_18x1lTAvBaZgK0KRD6m7HQmb", paid.Charge.ID)
+	assert.Equal(t, "5825f4c5c4ed9c350d2d81b1", paid.Message.ID)
+}
+
+func TestClientEvents(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	devices := map[string]string{}
+	sc.OnClientAdd(func(e *ClientEvent) {
+		devices[e.Client.ID] = e.Client.DeviceID
+	})
+	sc.OnClientRemove(func(e *ClientEvent) {
+		delete(devices, e.Client.ID)
+	})
+
+	for _, trigger := range []string{TriggerClientAdd, TriggerClientRemove} {
+		payload := &Payload{}
+		assert.NoError(t, json.Unmarshal([]byte(`{
+			"trigger": "`+trigger+`",
+			"app": {"_id": "5698edbf2a43bd081be982f1"},
+			"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+			"client": {
+				"_id": "5c9d2f34a1d3a2504bc89513",
+				"platform": "ios",
+				"deviceId": "8A8E8C8C-4B2C-4E0B-A5D3-5E1E1C6E5B2D",
+				"active": true,
+				"primary": true
+			},
+			"version": "v1.1"
+		}`), payload))
+		sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+		if trigger == TriggerClientAdd {
+			assert.Equal(t, map[string]string{
+				"5c9d2f34a1d3a2504bc89513": "8A8E8C8C-4B2C-4E0B-A5D3-5E1E1C6E5B2D",
+			}, devices)
+		}
+	}
+	assert.Empty(t, devices)
+}
+
+func TestAppUserDeleteEvent(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var deleted *AppUserDeleteEvent
+	sc.OnAppUserDelete(func(e *AppUserDeleteEvent) {
+		deleted = e
+	})
+
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"trigger": "appUser:delete",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f", "userId": "guest-42"},
+		"version": "v1.1"
+	}`), payload))
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	assert.NotNil(t, deleted)
+	assert.Equal(t, "c7f6e6d6c3a637261bd9656f", deleted.AppUser.ID)
+	assert.Equal(t, "guest-42", deleted.AppUser.UserID)
+}
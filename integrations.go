@@ -0,0 +1,293 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	IntegrationTypeCustom = "custom"
+
+	IntegrationStatusActive   = "active"
+	IntegrationStatusInactive = "inactive"
+	IntegrationStatusError    = "error"
+)
+
+// IntegrationConfig is the type-specific part of an integration, e.g.
+// MessengerIntegration or WhatsAppIntegration.
+type IntegrationConfig interface {
+	IntegrationType() string
+}
+
+type MessengerIntegration struct {
+	PageAccessToken string `json:"pageAccessToken,omitempty"`
+	AppID           string `json:"appId,omitempty"`
+	AppSecret       string `json:"appSecret,omitempty"`
+	PageID          string `json:"pageId,omitempty"`
+}
+
+func (MessengerIntegration) IntegrationType() string { return SourceTypeMessenger }
+
+type WhatsAppIntegration struct {
+	DeploymentID        string `json:"deploymentId,omitempty"`
+	BaseURL             string `json:"baseUrl,omitempty"`
+	Username            string `json:"username,omitempty"`
+	Password            string `json:"password,omitempty"`
+	PhoneNumber         string `json:"phoneNumber,omitempty"`
+	HsmFallbackLanguage string `json:"hsmFallbackLanguage,omitempty"`
+	AccountID           string `json:"accountId,omitempty"`
+}
+
+func (WhatsAppIntegration) IntegrationType() string { return SourceTypeWhatsApp }
+
+type TwilioIntegration struct {
+	AccountSID     string `json:"accountSid,omitempty"`
+	AuthToken      string `json:"authToken,omitempty"`
+	PhoneNumberSID string `json:"phoneNumberSid,omitempty"`
+	PhoneNumber    string `json:"phoneNumber,omitempty"`
+}
+
+func (TwilioIntegration) IntegrationType() string { return SourceTypeTwilio }
+
+type TelegramIntegration struct {
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+func (TelegramIntegration) IntegrationType() string { return SourceTypeTelegram }
+
+type ViberIntegration struct {
+	Token string `json:"token,omitempty"`
+	URI   string `json:"uri,omitempty"`
+}
+
+func (ViberIntegration) IntegrationType() string { return SourceTypeViber }
+
+type LineIntegration struct {
+	ChannelID          string `json:"channelId,omitempty"`
+	ChannelSecret      string `json:"channelSecret,omitempty"`
+	ChannelAccessToken string `json:"channelAccessToken,omitempty"`
+	ServiceCode        string `json:"serviceCode,omitempty"`
+	SwitcherSecret     string `json:"switcherSecret,omitempty"`
+}
+
+func (LineIntegration) IntegrationType() string { return SourceTypeLine }
+
+type CustomIntegration struct {
+	Webhooks []*IntegrationWebhook `json:"webhooks,omitempty"`
+}
+
+func (CustomIntegration) IntegrationType() string { return IntegrationTypeCustom }
+
+type IntegrationWebhook struct {
+	ID       string   `json:"_id,omitempty"`
+	Target   string   `json:"target"`
+	Triggers []string `json:"triggers,omitempty"`
+	Secret   string   `json:"secret,omitempty"`
+}
+
+// Integration is a channel connected to the app. The type-specific fields
+// are available through DecodeConfig.
+type Integration struct {
+	ID          string `json:"_id"`
+	Type        string `json:"type"`
+	Status      string `json:"status,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Error       string `json:"error,omitempty"`
+
+	raw json.RawMessage
+}
+
+func (i *Integration) UnmarshalJSON(data []byte) error {
+	type Alias Integration
+	aux := (*Alias)(i)
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	i.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// DecodeConfig decodes the type-specific fields of the integration into
+// config, which has to match the integration type.
+func (i *Integration) DecodeConfig(config IntegrationConfig) error {
+	if config.IntegrationType() != i.Type {
+		return fmt.Errorf("integration is of type %s, not %s", i.Type, config.IntegrationType())
+	}
+	return json.Unmarshal(i.raw, config)
+}
+
+type IntegrationResponse struct {
+	Integration *Integration `json:"integration,omitempty"`
+}
+
+type ListIntegrationsResponse struct {
+	Integrations []*Integration `json:"integrations"`
+}
+
+// ListIntegrations lists the integrations of the app, optionally filtered
+// by type.
+func (sc *smoochClient) ListIntegrations(types []string, opts ...RequestOption) ([]*Integration, *ResponseData, error) {
+	queryParams := url.Values{}
+	if len(types) > 0 {
+		queryParams.Set("types", strings.Join(types, ","))
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/integrations", sc.appID),
+		ro.queryParams(queryParams),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListIntegrationsResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Integrations, respData, nil
+}
+
+func (sc *smoochClient) GetIntegration(integrationID string, opts ...RequestOption) (*Integration, *ResponseData, error) {
+	if integrationID == "" {
+		return nil, nil, ErrIntegrationIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/integrations/%s", sc.appID, url.PathEscape(integrationID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response IntegrationResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Integration, respData, nil
+}
+
+func (sc *smoochClient) CreateIntegration(displayName string, config IntegrationConfig, opts ...RequestOption) (*Integration, *ResponseData, error) {
+	if config == nil {
+		return nil, nil, ErrIntegrationConfigNil
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/integrations", sc.appID),
+		ro.queryParams(nil),
+	)
+
+	buf, err := encodeIntegration(displayName, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response IntegrationResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Integration, respData, nil
+}
+
+// UpdateIntegration updates the display name and the fields set on config.
+func (sc *smoochClient) UpdateIntegration(integrationID string, displayName string, config IntegrationConfig, opts ...RequestOption) (*Integration, *ResponseData, error) {
+	if integrationID == "" {
+		return nil, nil, ErrIntegrationIDEmpty
+	}
+
+	if config == nil {
+		return nil, nil, ErrIntegrationConfigNil
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/integrations/%s", sc.appID, url.PathEscape(integrationID)),
+		ro.queryParams(nil),
+	)
+
+	buf, err := encodeIntegration(displayName, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPut, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response IntegrationResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Integration, respData, nil
+}
+
+func (sc *smoochClient) DeleteIntegration(integrationID string, opts ...RequestOption) (*ResponseData, error) {
+	if integrationID == "" {
+		return nil, ErrIntegrationIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/integrations/%s", sc.appID, url.PathEscape(integrationID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	return sc.sendRequest(req, nil)
+}
+
+// encodeIntegration flattens config into a single JSON object together with
+// the integration type and display name, as the API expects.
+func encodeIntegration(displayName string, config IntegrationConfig) (*bytes.Buffer, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	fields["type"] = config.IntegrationType()
+	if displayName != "" {
+		fields["displayName"] = displayName
+	}
+
+	buf := new(bytes.Buffer)
+	err = json.NewEncoder(buf).Encode(fields)
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,177 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	sampleIntegrationJson = `
+	{
+		"integration": {
+			"_id": "582dedf230e788746891281a",
+			"type": "messenger",
+			"status": "active",
+			"displayName": "Support page",
+			"pageId": "841529259325",
+			"appId": "1674554616147204",
+			"pageAccessToken": "EAAYdDDcvwB8BAHxdnhS"
+		}
+	}`
+
+	sampleListIntegrationsJson = `
+	{
+		"integrations": [
+			{
+				"_id": "582dedf230e788746891281a",
+				"type": "messenger",
+				"status": "active",
+				"pageId": "841529259325"
+			},
+			{
+				"_id": "5735dded48011972d621dc0a",
+				"type": "whatsapp",
+				"status": "error",
+				"error": "Invalid credentials",
+				"deploymentId": "5e9f1cbd2e3ee4000cfa6e04"
+			}
+		]
+	}`
+)
+
+func TestListIntegrations(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/integrations", req.URL.Path)
+		assert.Equal(t, "messenger,whatsapp", req.URL.Query().Get("types"))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleListIntegrationsJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	integrations, _, err := sc.ListIntegrations([]string{SourceTypeMessenger, SourceTypeWhatsApp})
+	assert.NoError(t, err)
+	assert.Len(t, integrations, 2)
+	assert.Equal(t, IntegrationStatusError, integrations[1].Status)
+	assert.Equal(t, "Invalid credentials", integrations[1].Error)
+
+	var whatsApp WhatsAppIntegration
+	assert.NoError(t, integrations[1].DecodeConfig(&whatsApp))
+	assert.Equal(t, "5e9f1cbd2e3ee4000cfa6e04", whatsApp.DeploymentID)
+
+	var messenger MessengerIntegration
+	assert.Error(t, integrations[1].DecodeConfig(&messenger))
+}
+
+func TestIntegrationCRUD(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+
+		switch req.Method {
+		case http.MethodPost:
+			assert.Equal(t, "/v1.1/apps/app/integrations", req.URL.Path)
+			body, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{
+				"type": "messenger",
+				"displayName": "Support page",
+				"appId": "1674554616147204",
+				"appSecret": "8e3d8f7a",
+				"pageAccessToken": "EAAYdDDcvwB8BAHxdnhS"
+			}`, string(body))
+		case http.MethodPut:
+			assert.Equal(t, "/v1.1/apps/app/integrations/582dedf230e788746891281a", req.URL.Path)
+			body, err := ioutil.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"type": "messenger", "pageAccessToken": "new-token"}`, string(body))
+		case http.MethodGet, http.MethodDelete:
+			assert.Equal(t, "/v1.1/apps/app/integrations/582dedf230e788746891281a", req.URL.Path)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleIntegrationJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	integration, _, err := sc.CreateIntegration("Support page", &MessengerIntegration{
+		AppID:           "1674554616147204",
+		AppSecret:       "8e3d8f7a",
+		PageAccessToken: "EAAYdDDcvwB8BAHxdnhS",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "582dedf230e788746891281a", integration.ID)
+	assert.Equal(t, SourceTypeMessenger, integration.Type)
+	assert.Equal(t, "Support page", integration.DisplayName)
+
+	integration, _, err = sc.GetIntegration("582dedf230e788746891281a")
+	assert.NoError(t, err)
+	var messenger MessengerIntegration
+	assert.NoError(t, integration.DecodeConfig(&messenger))
+	assert.Equal(t, "841529259325", messenger.PageID)
+
+	_, _, err = sc.UpdateIntegration("582dedf230e788746891281a", "", &MessengerIntegration{
+		PageAccessToken: "new-token",
+	})
+	assert.NoError(t, err)
+
+	_, err = sc.DeleteIntegration("582dedf230e788746891281a")
+	assert.NoError(t, err)
+
+	_, _, err = sc.CreateIntegration("", nil)
+	assert.Equal(t, ErrIntegrationConfigNil, err)
+
+	_, _, err = sc.GetIntegration("")
+	assert.Equal(t, ErrIntegrationIDEmpty, err)
+
+	_, err = sc.DeleteIntegration("")
+	assert.Equal(t, ErrIntegrationIDEmpty, err)
+}
+
+func TestIntegrationEndpointsEscapeIDs(t *testing.T) {
+	var paths []string
+	fn := func(req *http.Request) *http.Response {
+		paths = append(paths, req.URL.EscapedPath())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{}`))),
+		}
+	}
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	sc.GetIntegration("i/j?k")
+	sc.DeleteIntegration("i/j?k")
+	sc.ListMessageTemplates("i/j?k", ListMessageTemplatesParams{})
+
+	assert.Equal(t, []string{
+		"/v1.1/apps/app/integrations/i%2Fj%3Fk",
+		"/v1.1/apps/app/integrations/i%2Fj%3Fk",
+		"/v1.1/apps/app/integrations/i%2Fj%3Fk/messageTemplates",
+	}, paths)
+}
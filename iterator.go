@@ -0,0 +1,105 @@
+package smooch
+
+// MessageIterator walks conversation history page by page, following the
+// paging cursors returned by GetMessages.
+//
+// When GetMessagesParams.After is set, messages are returned from oldest to
+// newest starting after that cursor. Otherwise they are returned from newest
+// to oldest, starting at Before or at the most recent message.
+type MessageIterator struct {
+	client  *smoochClient
+	userID  string
+	params  GetMessagesParams
+	opts    []RequestOption
+	forward bool
+
+	page []*Message
+	cur  *Message
+	done bool
+	err  error
+}
+
+func (sc *smoochClient) MessageIterator(userID string, params GetMessagesParams, opts ...RequestOption) *MessageIterator {
+	return &MessageIterator{
+		client:  sc,
+		userID:  userID,
+		params:  params,
+		opts:    opts,
+		forward: params.After != "",
+	}
+}
+
+// Next advances to the next message. It returns false when the history is
+// exhausted or an error occurred, see Err.
+func (it *MessageIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.cur = nil
+			return false
+		}
+		it.fetch()
+	}
+
+	it.cur = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// Message returns the current message.
+func (it *MessageIterator) Message() *Message {
+	return it.cur
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *MessageIterator) Err() error {
+	return it.err
+}
+
+func (it *MessageIterator) fetch() {
+	response, _, err := it.client.GetMessages(it.userID, it.params, it.opts...)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	messages := response.Messages
+	var cursor string
+	if it.forward {
+		cursor = response.AfterCursor()
+		if cursor == it.params.After {
+			cursor = ""
+		}
+		it.params.After = cursor
+	} else {
+		cursor = response.BeforeCursor()
+		if cursor == it.params.Before {
+			cursor = ""
+		}
+		it.params.Before = cursor
+
+		// pages are sorted oldest first
+		reversed := make([]*Message, len(messages))
+		for i, m := range messages {
+			reversed[len(messages)-1-i] = m
+		}
+		messages = reversed
+	}
+
+	it.page = messages
+	if len(messages) == 0 || cursor == "" {
+		it.done = true
+	}
+}
+
+// ForEachMessage calls fn for every message of the conversation history, in
+// the order described on MessageIterator. Returning an error from fn stops
+// the iteration and returns that error.
+func (sc *smoochClient) ForEachMessage(userID string, params GetMessagesParams, fn func(m *Message) error, opts ...RequestOption) error {
+	it := sc.MessageIterator(userID, params, opts...)
+	for it.Next() {
+		if err := fn(it.Message()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,94 @@
+package smooch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func messagesPage(ids []string, previous string, next string) string {
+	var messages []string
+	for _, id := range ids {
+		messages = append(messages, fmt.Sprintf(`{"_id":"%s","type":"text","role":"appUser"}`, id))
+	}
+	return fmt.Sprintf(`{"messages":[%s],"previous":"%s","next":"%s"}`,
+		strings.Join(messages, ","), previous, next)
+}
+
+func newPagingTestClient(t *testing.T, pages map[string]string) *smoochClient {
+	fn := func(req *http.Request) *http.Response {
+		key := "before=" + req.URL.Query().Get("before")
+		if after := req.URL.Query().Get("after"); after != "" {
+			key = "after=" + after
+		}
+		body, ok := pages[key]
+		assert.True(t, ok, key)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+	return sc
+}
+
+func TestMessageIteratorBackward(t *testing.T) {
+	messagesURL := "https://api.smooch.io/v1.1/apps/app/appusers/123/messages"
+	sc := newPagingTestClient(t, map[string]string{
+		"before=":  messagesPage([]string{"m5", "m6"}, messagesURL+"?before=5", ""),
+		"before=5": messagesPage([]string{"m3", "m4"}, messagesURL+"?before=3", messagesURL+"?after=4"),
+		"before=3": messagesPage([]string{"m1", "m2"}, "", messagesURL+"?after=2"),
+	})
+
+	var ids []string
+	it := sc.MessageIterator("123", GetMessagesParams{})
+	for it.Next() {
+		ids = append(ids, it.Message().ID)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"m6", "m5", "m4", "m3", "m2", "m1"}, ids)
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Message())
+}
+
+func TestForEachMessageForward(t *testing.T) {
+	messagesURL := "https://api.smooch.io/v1.1/apps/app/appusers/123/messages"
+	sc := newPagingTestClient(t, map[string]string{
+		"after=0": messagesPage([]string{"m1", "m2"}, "", messagesURL+"?after=2"),
+		"after=2": messagesPage([]string{"m3"}, messagesURL+"?before=3", messagesURL+"?after=2"),
+	})
+
+	var ids []string
+	err := sc.ForEachMessage("123", GetMessagesParams{After: "0"}, func(m *Message) error {
+		ids = append(ids, m.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m1", "m2", "m3"}, ids)
+
+	stop := errors.New("stop")
+	ids = nil
+	err = sc.ForEachMessage("123", GetMessagesParams{After: "0"}, func(m *Message) error {
+		ids = append(ids, m.ID)
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []string{"m1"}, ids)
+
+	err = sc.ForEachMessage("", GetMessagesParams{}, func(m *Message) error {
+		return nil
+	})
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
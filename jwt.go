@@ -1,13 +1,35 @@
 package smooch
 
 import (
+	"time"
+
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
+const (
+	// JWTScopeApp tokens are signed with an app key and can only call the
+	// endpoints of that app.
+	JWTScopeApp = "app"
+	// JWTScopeAccount tokens are signed with an account or service account
+	// key and can call the account endpoints as well as the app endpoints of
+	// every app the account has access to.
+	JWTScopeAccount = "account"
+)
+
 func GenerateJWT(scope string, keyID string, secret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	return generateJWT(jwt.MapClaims{"scope": scope}, keyID, secret)
+}
+
+// generateExpiringJWT generates a token that is only valid for ttl.
+func generateExpiringJWT(scope string, keyID string, secret string, ttl time.Duration) (string, error) {
+	return generateJWT(jwt.MapClaims{
 		"scope": scope,
-	})
+		"exp":   time.Now().Add(ttl).Unix(),
+	}, keyID, secret)
+}
+
+func generateJWT(claims jwt.MapClaims, keyID string, secret string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	token.Header = map[string]interface{}{
 		"alg": "HS256",
 		"typ": "JWT",
@@ -16,3 +38,19 @@ func GenerateJWT(scope string, keyID string, secret string) (string, error) {
 
 	return token.SignedString([]byte(secret))
 }
+
+// verifyJWT checks that token is signed with secret using HMAC and, when it
+// has an exp claim, that it hasn't expired.
+func verifyJWT(token string, secret string) bool {
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrJWTSigningMethod
+		}
+		return []byte(secret), nil
+	})
+	return err == nil && parsed.Valid
+}
+
+func validScope(scope string) bool {
+	return scope == JWTScopeApp || scope == JWTScopeAccount
+}
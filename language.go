@@ -0,0 +1,62 @@
+package smooch
+
+import "strings"
+
+// TemplateLanguagePolicyFallback lets WhatsApp pick the language of the
+// user's device when the template is approved in it, and use the given code
+// otherwise. WhatsApp has deprecated it for new templates.
+const TemplateLanguagePolicyFallback = "fallback"
+
+// SelectTemplateLanguage picks the language to send a template in, given
+// the locale preferred by the user, e.g. "pt-BR", and the languages the
+// template is approved in, e.g. "pt_BR" or "en". It tries, in order:
+//
+//   - the exact locale, e.g. pt_BR
+//   - the bare language, e.g. pt
+//   - another region of the same language, e.g. pt_PT
+//   - defaultCode with the fallback policy
+//
+// It returns false when none of them is available.
+func SelectTemplateLanguage(preferred string, available []string, defaultCode string) (*TemplateLanguage, bool) {
+	deterministic := func(code string) *TemplateLanguage {
+		return &TemplateLanguage{Policy: TemplateLanguagePolicyDeterministic, Code: code}
+	}
+
+	locale := normalizeLocale(preferred)
+	language := strings.SplitN(locale, "_", 2)[0]
+
+	if locale != "" {
+		for _, code := range available {
+			if normalizeLocale(code) == locale {
+				return deterministic(code), true
+			}
+		}
+		for _, code := range available {
+			if normalizeLocale(code) == language {
+				return deterministic(code), true
+			}
+		}
+		for _, code := range available {
+			if strings.HasPrefix(normalizeLocale(code), language+"_") {
+				return deterministic(code), true
+			}
+		}
+	}
+
+	for _, code := range available {
+		if defaultCode != "" && normalizeLocale(code) == normalizeLocale(defaultCode) {
+			return &TemplateLanguage{Policy: TemplateLanguagePolicyFallback, Code: code}, true
+		}
+	}
+	return nil, false
+}
+
+// normalizeLocale turns "pt-br" and "PT_BR" into "pt_BR".
+func normalizeLocale(locale string) string {
+	parts := strings.SplitN(strings.Replace(strings.TrimSpace(locale), "-", "_", -1), "_", 2)
+	parts[0] = strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		parts[1] = strings.ToUpper(parts[1])
+	}
+	return strings.Join(parts, "_")
+}
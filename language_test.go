@@ -0,0 +1,36 @@
+package smooch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectTemplateLanguage(t *testing.T) {
+	available := []string{"en", "en_GB", "pt_PT", "es"}
+
+	tests := []struct {
+		preferred string
+		code      string
+		policy    string
+	}{
+		{"en-GB", "en_GB", TemplateLanguagePolicyDeterministic},
+		{"en_US", "en", TemplateLanguagePolicyDeterministic},
+		{"pt-br", "pt_PT", TemplateLanguagePolicyDeterministic},
+		{"es", "es", TemplateLanguagePolicyDeterministic},
+		{"lt", "en", TemplateLanguagePolicyFallback},
+		{"", "en", TemplateLanguagePolicyFallback},
+	}
+
+	for _, test := range tests {
+		t.Run(test.preferred, func(t *testing.T) {
+			language, ok := SelectTemplateLanguage(test.preferred, available, "en")
+			assert.True(t, ok)
+			assert.Equal(t, test.code, language.Code)
+			assert.Equal(t, test.policy, language.Policy)
+		})
+	}
+
+	_, ok := SelectTemplateLanguage("lt", available, "de")
+	assert.False(t, ok)
+}
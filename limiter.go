@@ -0,0 +1,154 @@
+package smooch
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimitChannelKey carries the channel set with WithRateLimitChannel in
+// the request context, from createRequest to sendRequest.
+type rateLimitChannelKey struct{}
+
+// Rate is a number of requests per second, with bursts of up to Burst
+// requests. Burst defaults to 1.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// WithRateLimitChannel tells the client which channel the call is for, so
+// it is held to Options.ChannelRateLimits on top of Options.RateLimit.
+func WithRateLimitChannel(channel string) RequestOption {
+	return func(o *requestOptions) {
+		o.rateLimitChannel = channel
+	}
+}
+
+// rateLimiter holds API calls back so they stay under the configured
+// rates, instead of being rejected by Smooch.
+type rateLimiter struct {
+	global   *tokenBucket
+	channels map[string]*tokenBucket
+}
+
+func newRateLimiter(global *Rate, channels map[string]Rate) *rateLimiter {
+	if global == nil && len(channels) == 0 {
+		return nil
+	}
+
+	rl := &rateLimiter{channels: map[string]*tokenBucket{}}
+	if global != nil {
+		rl.global = newTokenBucket(*global)
+	}
+	for channel, rate := range channels {
+		rl.channels[channel] = newTokenBucket(rate)
+	}
+	return rl
+}
+
+// wait blocks until the request may be sent, or ctx is done. The tokens
+// are given back when ctx is done first, so canceled calls don't hold back
+// the ones behind them.
+func (rl *rateLimiter) wait(ctx context.Context, channel string) error {
+	var reserved []*tokenBucket
+	var delay time.Duration
+	for _, bucket := range []*tokenBucket{rl.global, rl.channels[channel]} {
+		if bucket == nil {
+			continue
+		}
+		reserved = append(reserved, bucket)
+		if d := bucket.reserve(); d > delay {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		for _, bucket := range reserved {
+			bucket.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// waitRateLimit blocks until the request may be sent, when the client has
+// a rate limit.
+func (sc *smoochClient) waitRateLimit(req *http.Request, channel string) error {
+	if sc.rateLimiter == nil {
+		return nil
+	}
+	return sc.rateLimiter.wait(req.Context(), channel)
+}
+
+// rateLimitChannel returns the channel set with WithRateLimitChannel.
+func rateLimitChannel(ctx context.Context) string {
+	channel, _ := ctx.Value(rateLimitChannelKey{}).(string)
+	return channel
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func newTokenBucket(rate Rate) *tokenBucket {
+	burst := float64(rate.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate.PerSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it.
+// Tokens go negative when reserved ahead, so waiting callers are served in
+// order.
+func (b *tokenBucket) reserve() time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// release gives back a token taken by reserve.
+func (b *tokenBucket) release() {
+	if b.rate <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
@@ -0,0 +1,141 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1444348338, 0)
+	bucket := newTokenBucket(Rate{PerSecond: 2, Burst: 2})
+	bucket.now = func() time.Time { return now }
+	bucket.last = now
+
+	assert.Equal(t, time.Duration(0), bucket.reserve())
+	assert.Equal(t, time.Duration(0), bucket.reserve())
+	assert.Equal(t, 500*time.Millisecond, bucket.reserve())
+	assert.Equal(t, time.Second, bucket.reserve())
+
+	// refilling never goes over the burst
+	now = now.Add(time.Minute)
+	assert.Equal(t, time.Duration(0), bucket.reserve())
+	assert.Equal(t, time.Duration(0), bucket.reserve())
+	assert.Equal(t, 500*time.Millisecond, bucket.reserve())
+
+	unlimited := newTokenBucket(Rate{})
+	assert.Equal(t, time.Duration(0), unlimited.reserve())
+	assert.Equal(t, time.Duration(0), unlimited.reserve())
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	assert.Nil(t, newRateLimiter(nil, nil))
+
+	rl := newRateLimiter(nil, map[string]Rate{"whatsapp": {PerSecond: 0.001}})
+	assert.NoError(t, rl.wait(context.Background(), "whatsapp"))
+	assert.NoError(t, rl.wait(context.Background(), "messenger"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, rl.wait(ctx, "whatsapp"))
+
+	// the canceled wait gave its token back
+	assert.InDelta(t, 0, rl.channels["whatsapp"].tokens, 0.01)
+}
+
+func TestSendWithRateLimit(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		calls++
+		assert.Equal(t, "whatsapp", rateLimitChannel(req.Context()))
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret:      "very-secure-test-secret",
+		HttpClient:        NewTestClient(fn),
+		RateLimit:         &Rate{PerSecond: 1000, Burst: 10},
+		ChannelRateLimits: map[string]Rate{"whatsapp": {PerSecond: 1000}},
+	})
+	assert.NoError(t, err)
+
+	message := &Message{
+		Role: RoleAppMaker,
+		Type: MessageTypeText,
+		Text: "Hello",
+	}
+	for i := 0; i < 3; i++ {
+		_, _, err = sc.Send("TestUser", message, WithRateLimitChannel("whatsapp"))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, calls)
+}
+
+func TestRateLimitWaitKeepsBreakerProbe(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error":{"code":"unavailable"}}`))),
+		}
+	}
+
+	now := time.Unix(1444348338, 0)
+	cb := NewCircuitBreaker(1, time.Minute)
+	cb.now = func() time.Time { return now }
+	sc, err := New(Options{
+		VerifySecret:   "very-secure-test-secret",
+		HttpClient:     NewTestClient(fn),
+		CircuitBreaker: cb,
+		RateLimit:      &Rate{PerSecond: 0.001},
+	})
+	assert.NoError(t, err)
+
+	_, err = sc.Do(context.Background(), http.MethodGet, "/v1.1/apps", nil, nil, nil)
+	assert.IsType(t, &SmoochError{}, err)
+	assert.True(t, cb.Open())
+
+	// the cool-down is over, but the call is canceled while it waits for
+	// the rate limit, before it could be the probe
+	now = now.Add(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = sc.Do(ctx, http.MethodGet, "/v1.1/apps", nil, nil, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, cb.Allow())
+}
+
+func TestRetriesAreRateLimited(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error":{"code":"unavailable"}}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		RetryPolicy:  &ExponentialBackoffPolicy{MaxAttempts: 3},
+		RateLimit:    &Rate{PerSecond: 0.001},
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = sc.Do(ctx, http.MethodGet, "/v1.1/apps", nil, nil, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, calls)
+}
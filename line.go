@@ -0,0 +1,172 @@
+package smooch
+
+import "encoding/json"
+
+// LineSticker is a LINE override payload sending a sticker, see the LINE
+// sticker list for package and sticker ids.
+type LineSticker struct {
+	PackageID string `json:"packageId"`
+	StickerID string `json:"stickerId"`
+}
+
+func (LineSticker) Channel() string { return SourceTypeLine }
+
+func (s LineSticker) MarshalJSON() ([]byte, error) {
+	type alias LineSticker
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"sticker", alias(s)})
+}
+
+// LineFlex is a LINE override payload sending a flex message. AltText is
+// shown in notifications and chat lists.
+type LineFlex struct {
+	AltText  string            `json:"altText"`
+	Contents LineFlexContainer `json:"contents"`
+}
+
+func (LineFlex) Channel() string { return SourceTypeLine }
+
+func (f LineFlex) MarshalJSON() ([]byte, error) {
+	type alias LineFlex
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"flex", alias(f)})
+}
+
+// LineFlexContainer is a LineFlexBubble or a LineFlexCarousel.
+type LineFlexContainer interface {
+	lineFlexContainer()
+}
+
+// LineFlexComponent is one of the LineFlex* components laid out in a box.
+type LineFlexComponent interface {
+	lineFlexComponent()
+}
+
+type LineFlexBubble struct {
+	Size   string         `json:"size,omitempty"`
+	Header *LineFlexBox   `json:"header,omitempty"`
+	Hero   *LineFlexImage `json:"hero,omitempty"`
+	Body   *LineFlexBox   `json:"body,omitempty"`
+	Footer *LineFlexBox   `json:"footer,omitempty"`
+}
+
+func (LineFlexBubble) lineFlexContainer() {}
+
+func (b LineFlexBubble) MarshalJSON() ([]byte, error) {
+	type alias LineFlexBubble
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"bubble", alias(b)})
+}
+
+type LineFlexCarousel struct {
+	Contents []*LineFlexBubble `json:"contents"`
+}
+
+func (LineFlexCarousel) lineFlexContainer() {}
+
+func (c LineFlexCarousel) MarshalJSON() ([]byte, error) {
+	type alias LineFlexCarousel
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"carousel", alias(c)})
+}
+
+type LineFlexBox struct {
+	// Layout is horizontal, vertical or baseline.
+	Layout   string              `json:"layout"`
+	Contents []LineFlexComponent `json:"contents"`
+	Spacing  string              `json:"spacing,omitempty"`
+	Margin   string              `json:"margin,omitempty"`
+}
+
+func (LineFlexBox) lineFlexComponent() {}
+
+func (b LineFlexBox) MarshalJSON() ([]byte, error) {
+	type alias LineFlexBox
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"box", alias(b)})
+}
+
+type LineFlexText struct {
+	Text   string `json:"text"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Color  string `json:"color,omitempty"`
+	Wrap   bool   `json:"wrap,omitempty"`
+}
+
+func (LineFlexText) lineFlexComponent() {}
+
+func (t LineFlexText) MarshalJSON() ([]byte, error) {
+	type alias LineFlexText
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"text", alias(t)})
+}
+
+type LineFlexImage struct {
+	URL         string `json:"url"`
+	Size        string `json:"size,omitempty"`
+	AspectRatio string `json:"aspectRatio,omitempty"`
+	AspectMode  string `json:"aspectMode,omitempty"`
+}
+
+func (LineFlexImage) lineFlexComponent() {}
+
+func (i LineFlexImage) MarshalJSON() ([]byte, error) {
+	type alias LineFlexImage
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"image", alias(i)})
+}
+
+type LineFlexButton struct {
+	Action *LineAction `json:"action"`
+	// Style is link, primary or secondary.
+	Style string `json:"style,omitempty"`
+}
+
+func (LineFlexButton) lineFlexComponent() {}
+
+func (b LineFlexButton) MarshalJSON() ([]byte, error) {
+	type alias LineFlexButton
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"button", alias(b)})
+}
+
+type LineFlexSeparator struct {
+	Margin string `json:"margin,omitempty"`
+}
+
+func (LineFlexSeparator) lineFlexComponent() {}
+
+func (s LineFlexSeparator) MarshalJSON() ([]byte, error) {
+	type alias LineFlexSeparator
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		alias
+	}{"separator", alias(s)})
+}
+
+// LineAction is what happens when a flex button is tapped. Type is uri,
+// postback or message.
+type LineAction struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	URI   string `json:"uri,omitempty"`
+	Data  string `json:"data,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
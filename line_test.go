@@ -0,0 +1,64 @@
+package smooch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineSticker(t *testing.T) {
+	message, err := NewMessage().
+		Text("(thumbs up)").
+		ChannelOverride(&LineSticker{PackageID: "446", StickerID: "1988"}).
+		Build()
+	assert.NoError(t, err)
+	assert.JSONEq(t,
+		`{"type": "sticker", "packageId": "446", "stickerId": "1988"}`,
+		string(message.Override[SourceTypeLine].Payload),
+	)
+}
+
+func TestLineFlex(t *testing.T) {
+	flex := &LineFlex{
+		AltText: "Your booking",
+		Contents: &LineFlexCarousel{Contents: []*LineFlexBubble{{
+			Hero: &LineFlexImage{URL: "https://example.org/hotel.jpg", AspectMode: "cover"},
+			Body: &LineFlexBox{
+				Layout: "vertical",
+				Contents: []LineFlexComponent{
+					&LineFlexText{Text: "Hotel Vilnius", Weight: "bold"},
+					&LineFlexSeparator{},
+					&LineFlexButton{
+						Style:  "primary",
+						Action: &LineAction{Type: "uri", Label: "Open", URI: "https://example.org/booking"},
+					},
+				},
+			},
+		}}},
+	}
+
+	data, err := json.Marshal(flex)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "flex",
+		"altText": "Your booking",
+		"contents": {
+			"type": "carousel",
+			"contents": [{
+				"type": "bubble",
+				"hero": {"type": "image", "url": "https://example.org/hotel.jpg", "aspectMode": "cover"},
+				"body": {
+					"type": "box",
+					"layout": "vertical",
+					"contents": [
+						{"type": "text", "text": "Hotel Vilnius", "weight": "bold"},
+						{"type": "separator"},
+						{"type": "button", "style": "primary", "action": {"type": "uri", "label": "Open", "uri": "https://example.org/booking"}}
+					]
+				}
+			}]
+		}
+	}`, string(data))
+	assert.Equal(t, SourceTypeLine, flex.Channel())
+}
@@ -0,0 +1,40 @@
+package smooch
+
+const (
+	MessengerMessagingTypeResponse   = "RESPONSE"
+	MessengerMessagingTypeUpdate     = "UPDATE"
+	MessengerMessagingTypeMessageTag = "MESSAGE_TAG"
+
+	// Message tags allow sending outside of the 24 hour standard messaging
+	// window, for the use case each of them names.
+	MessengerTagConfirmedEventUpdate = "CONFIRMED_EVENT_UPDATE"
+	MessengerTagPostPurchaseUpdate   = "POST_PURCHASE_UPDATE"
+	MessengerTagAccountUpdate        = "ACCOUNT_UPDATE"
+	MessengerTagHumanAgent           = "HUMAN_AGENT"
+
+	MessengerNotificationRegular    = "REGULAR"
+	MessengerNotificationSilentPush = "SILENT_PUSH"
+	MessengerNotificationNoPush     = "NO_PUSH"
+)
+
+// MessengerOverride is the override payload of Facebook Messenger. Message,
+// when set, is a Send API message object replacing the one Smooch derives
+// from the message.
+type MessengerOverride struct {
+	MessagingType    string      `json:"messaging_type,omitempty"`
+	Tag              string      `json:"tag,omitempty"`
+	NotificationType string      `json:"notification_type,omitempty"`
+	PersonaID        string      `json:"persona_id,omitempty"`
+	Message          interface{} `json:"message,omitempty"`
+}
+
+func (MessengerOverride) Channel() string { return SourceTypeMessenger }
+
+// NewMessengerTagOverride returns an override sending the message with tag,
+// e.g. MessengerTagPostPurchaseUpdate.
+func NewMessengerTagOverride(tag string) *MessengerOverride {
+	return &MessengerOverride{
+		MessagingType: MessengerMessagingTypeMessageTag,
+		Tag:           tag,
+	}
+}
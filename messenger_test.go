@@ -0,0 +1,31 @@
+package smooch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessengerOverride(t *testing.T) {
+	override := NewMessengerTagOverride(MessengerTagPostPurchaseUpdate)
+	override.NotificationType = MessengerNotificationSilentPush
+	override.PersonaID = "1234567890"
+
+	message, err := NewMessage().
+		Text("Your order has shipped").
+		ChannelOverride(override).
+		Build()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"messaging_type": "MESSAGE_TAG",
+		"tag": "POST_PURCHASE_UPDATE",
+		"notification_type": "SILENT_PUSH",
+		"persona_id": "1234567890"
+	}`, string(message.Override[SourceTypeMessenger].Payload))
+
+	_, err = NewMessage().
+		Text("Your order has shipped").
+		ChannelOverride(&MessengerOverride{Message: make(chan int)}).
+		Build()
+	assert.Error(t, err)
+}
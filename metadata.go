@@ -0,0 +1,80 @@
+package smooch
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Metadata is the free-form metadata of apps, messages and actions. Values
+// decoded from the API are JSON values, so numbers are float64; the getters
+// convert them.
+type Metadata map[string]interface{}
+
+// NewMetadata encodes v, usually a struct with json tags, as metadata.
+func NewMetadata(v interface{}) (Metadata, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m Metadata
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GetString returns the string at key and whether there is one.
+func (m Metadata) GetString(key string) (string, bool) {
+	s, ok := m[key].(string)
+	return s, ok
+}
+
+// GetInt returns the integer at key and whether there is one. Floats with
+// a fractional part are not integers.
+func (m Metadata) GetInt(key string) (int, bool) {
+	switch v := m[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+		return int(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err == nil
+	}
+	return 0, false
+}
+
+// GetBool returns the boolean at key and whether there is one.
+func (m Metadata) GetBool(key string) (bool, bool) {
+	b, ok := m[key].(bool)
+	return b, ok
+}
+
+// Merge returns a copy of m with the keys of other set on it, other winning
+// on conflicts. Neither m nor other are modified.
+func (m Metadata) Merge(other Metadata) Metadata {
+	merged := make(Metadata, len(m)+len(other))
+	for k, v := range m {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Bind decodes the metadata into v, usually a pointer to a struct with json
+// tags, through a JSON round trip.
+func (m Metadata) Bind(v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
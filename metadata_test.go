@@ -0,0 +1,59 @@
+package smooch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataGetters(t *testing.T) {
+	message := &Message{}
+	err := json.Unmarshal([]byte(`{
+		"type": "text",
+		"role": "appUser",
+		"metadata": {"booking": "B-42", "nights": 3, "price": 99.5, "paid": true}
+	}`), message)
+	assert.NoError(t, err)
+
+	booking, ok := message.Metadata.GetString("booking")
+	assert.True(t, ok)
+	assert.Equal(t, "B-42", booking)
+
+	nights, ok := message.Metadata.GetInt("nights")
+	assert.True(t, ok)
+	assert.Equal(t, 3, nights)
+
+	_, ok = message.Metadata.GetInt("price")
+	assert.False(t, ok)
+	_, ok = message.Metadata.GetInt("booking")
+	assert.False(t, ok)
+	_, ok = message.Metadata.GetString("missing")
+	assert.False(t, ok)
+
+	paid, ok := message.Metadata.GetBool("paid")
+	assert.True(t, ok)
+	assert.True(t, paid)
+}
+
+func TestMetadataMerge(t *testing.T) {
+	m := Metadata{"a": 1, "b": 2}
+	merged := m.Merge(Metadata{"b": 3, "c": 4})
+	assert.Equal(t, Metadata{"a": 1, "b": 3, "c": 4}, merged)
+	assert.Equal(t, Metadata{"a": 1, "b": 2}, m)
+}
+
+func TestMetadataBind(t *testing.T) {
+	type booking struct {
+		ID     string `json:"booking"`
+		Nights int    `json:"nights"`
+	}
+
+	m, err := NewMetadata(booking{ID: "B-42", Nights: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{"booking": "B-42", "nights": float64(3)}, m)
+
+	var b booking
+	assert.NoError(t, m.Bind(&b))
+	assert.Equal(t, booking{ID: "B-42", Nights: 3}, b)
+}
@@ -0,0 +1,38 @@
+package smooch
+
+import (
+	"net/http"
+)
+
+// Middleware wraps the transport used for outbound API calls. It can inspect
+// or modify requests before they are sent and responses before they are
+// decoded, e.g. to add headers, record metrics or inject faults.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chainMiddlewares returns a copy of client whose transport is wrapped by the
+// given middlewares. The first middleware is the outermost one, so it sees
+// the request first and the response last.
+func chainMiddlewares(client *http.Client, middlewares []Middleware) *http.Client {
+	if len(middlewares) == 0 {
+		return client
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+
+	wrapped := *client
+	wrapped.Transport = transport
+	return &wrapped
+}
@@ -0,0 +1,57 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewares(t *testing.T) {
+	var order []string
+
+	fn := func(req *http.Request) *http.Response {
+		order = append(order, "transport")
+		assert.Equal(t, "abc", req.Header.Get("X-Trace-Id"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetUserJson))),
+		}
+	}
+
+	tracing := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			order = append(order, "tracing")
+			req.Header.Set("X-Trace-Id", "abc")
+			return next.RoundTrip(req)
+		})
+	}
+
+	audit := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			order = append(order, "audit")
+			resp, err := next.RoundTrip(req)
+			order = append(order, "audit done")
+			return resp, err
+		})
+	}
+
+	httpClient := NewTestClient(fn)
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   httpClient,
+		Middlewares:  []Middleware{tracing, audit},
+	})
+	assert.NoError(t, err)
+
+	appUser, _, err := sc.GetAppUser("123")
+	assert.NoError(t, err)
+	assert.NotNil(t, appUser)
+	assert.Equal(t, []string{"tracing", "audit", "transport", "audit done"}, order)
+
+	// the client passed in Options is left untouched
+	_, ok := httpClient.Transport.(RoundTripFunc)
+	assert.True(t, ok)
+}
@@ -0,0 +1,116 @@
+package smooch
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	idempotencyKeyHeaderKey = "Idempotency-Key"
+)
+
+// RequestOption customizes a single API call.
+type RequestOption func(o *requestOptions)
+
+type requestOptions struct {
+	ctx    context.Context
+	header http.Header
+	query  url.Values
+	// chunkChannel is set by WithTextChunking
+	chunkChannel string
+	// rateLimitChannel is set by WithRateLimitChannel
+	rateLimitChannel string
+}
+
+func newRequestOptions(opts []RequestOption) *requestOptions {
+	ro := &requestOptions{
+		header: http.Header{},
+		query:  url.Values{},
+	}
+	for _, opt := range opts {
+		opt(ro)
+	}
+	return ro
+}
+
+// queryParams merges the query parameters set by the caller into the ones
+// required by the endpoint. Endpoint parameters take precedence.
+func (o *requestOptions) queryParams(values url.Values) url.Values {
+	if len(o.query) == 0 {
+		return values
+	}
+
+	merged := url.Values{}
+	for key, v := range o.query {
+		merged[key] = v
+	}
+	for key, v := range values {
+		merged[key] = v
+	}
+	return merged
+}
+
+// context returns the context of the call, carrying the rate limit
+// channel for sendRequest.
+func (o *requestOptions) context() context.Context {
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if o.rateLimitChannel != "" {
+		ctx = context.WithValue(ctx, rateLimitChannelKey{}, o.rateLimitChannel)
+	}
+	return ctx
+}
+
+// WithContext makes the call, retries included, stop once ctx is done and
+// carries ctx to the transport and tracing.
+func WithContext(ctx context.Context) RequestOption {
+	return func(o *requestOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithHeader sets an extra header on the call, e.g. a tracing header or a
+// beta feature flag. The Authorization header cannot be overridden.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.header.Set(key, value)
+	}
+}
+
+// WithQueryParam adds an extra query parameter to the call.
+func WithQueryParam(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.query.Add(key, value)
+	}
+}
+
+// WithIdempotencyKey attaches the given idempotency key to the call, so
+// Smooch ignores repeated deliveries of the same request.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(o *requestOptions) {
+		o.header.Set(idempotencyKeyHeaderKey, key)
+	}
+}
+
+// WithNewIdempotencyKey attaches a freshly generated random idempotency key.
+// The key is kept for all retries of the call made by the RetryPolicy.
+func WithNewIdempotencyKey() RequestOption {
+	return func(o *requestOptions) {
+		o.header.Set(idempotencyKeyHeaderKey, newUUID())
+	}
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
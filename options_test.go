@@ -0,0 +1,96 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendWithIdempotencyKey(t *testing.T) {
+	var keys []string
+	fn := func(req *http.Request) *http.Response {
+		keys = append(keys, req.Header.Get(idempotencyKeyHeaderKey))
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+
+		if len(keys)%2 == 1 {
+			return &http.Response{
+				StatusCode: http.StatusGatewayTimeout,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+			}
+		}
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		RetryPolicy: &ExponentialBackoffPolicy{
+			MaxAttempts: 2,
+			BaseDelay:   time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+
+	message := &Message{
+		Role: RoleAppMaker,
+		Type: MessageTypeText,
+		Text: "Hello",
+	}
+
+	_, _, err = sc.Send("TestUser", message, WithIdempotencyKey("my-key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"my-key", "my-key"}, keys)
+
+	keys = nil
+	_, _, err = sc.Send("TestUser", message, WithNewIdempotencyKey())
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, keys[0], keys[1])
+	assert.Regexp(t,
+		regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`),
+		keys[0],
+	)
+
+	keys = nil
+	_, _, err = sc.Send("TestUser", message)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{""}, keys[:1])
+}
+
+func TestRequestOptionsHeaderAndQuery(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, "abc", req.Header.Get("X-Trace-Id"))
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "1", req.URL.Query().Get("beta"))
+		assert.Equal(t, "public", req.URL.Query().Get("access"))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleUploadAttachmentJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.UploadAttachment(
+		bytes.NewReader([]byte("data")),
+		NewAttachmentUpload("image/png"),
+		WithHeader("X-Trace-Id", "abc"),
+		WithHeader(authorizationHeaderKey, "Bearer forged"),
+		WithQueryParam("beta", "1"),
+		WithQueryParam("access", "private"),
+	)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,313 @@
+package smooch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	queueKeyPrefix      = "smooch:queue:"
+	deadLetterKeyPrefix = "smooch:deadletters:"
+
+	// DefaultQueueMaxAttempts is how many times a queued message is sent
+	// before it is dead-lettered when QueueOptions.MaxAttempts is not set.
+	DefaultQueueMaxAttempts = 10
+	// DefaultQueuePollInterval is how often a queue looks for messages to
+	// send when QueueOptions.PollInterval is not set.
+	DefaultQueuePollInterval = time.Second
+
+	defaultQueueBaseDelay = time.Second
+	defaultQueueMaxDelay  = time.Hour
+)
+
+// SendState tracks the sends of a stored message. The error fields
+// describe the last failed send; for a dead letter, the one that made the
+// sender give up.
+type SendState struct {
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	Attempts      int       `json:"attempts,omitempty"`
+	LastError     string    `json:"lastError,omitempty"`
+	StatusCode    int       `json:"statusCode,omitempty"`
+	ErrorCode     string    `json:"errorCode,omitempty"`
+}
+
+// QueuedMessage is a message stored by a Queue until Smooch accepts it.
+type QueuedMessage struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userId"`
+	Message    *Message  `json:"message"`
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+	SendState
+}
+
+// QueueOptions configures a Queue. The delay between attempts starts at
+// BaseDelay and doubles up to MaxDelay.
+type QueueOptions struct {
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	PollInterval time.Duration
+	Logger       Logger
+}
+
+// Queue sends messages in the background, keeping them in the storage
+// until Smooch accepts them so they survive restarts and API outages.
+//
+// Sends failing with a network error, 429 or 5xx are tried again with
+// exponential backoff. Messages Smooch rejects otherwise, or still failing
+// after MaxAttempts sends, are moved to the dead letters with the error
+// attached. Every send of a message carries the same idempotency key.
+type Queue struct {
+	client       Client
+	storage      Storage
+	retry        *sendRetry
+	pollInterval time.Duration
+	logger       Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func NewQueue(client Client, storage Storage, o QueueOptions) *Queue {
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultQueuePollInterval
+	}
+	if o.Logger == nil {
+		o.Logger = &nopLogger{}
+	}
+	return &Queue{
+		client:       client,
+		storage:      storage,
+		retry:        newSendRetry(o.MaxAttempts, o.BaseDelay, o.MaxDelay),
+		pollInterval: o.PollInterval,
+		logger:       o.Logger,
+		now:          time.Now,
+	}
+}
+
+// Enqueue stores the message to be sent to the user on the next poll. The
+// message is validated right away.
+func (q *Queue) Enqueue(ctx context.Context, userID string, message *Message) (*QueuedMessage, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+	if message == nil {
+		return nil, ErrMessageNil
+	}
+	if err := message.Validate(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := q.now()
+	m := &QueuedMessage{
+		ID:         hex.EncodeToString(id),
+		UserID:     userID,
+		Message:    message,
+		EnqueuedAt: now,
+		SendState:  SendState{NextAttemptAt: now},
+	}
+	if err := q.store(ctx, queueKey(m), m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Pending returns the messages waiting to be sent, in the order they will
+// be attempted.
+func (q *Queue) Pending(ctx context.Context) ([]*QueuedMessage, error) {
+	return q.list(ctx, queueKeyPrefix)
+}
+
+// DeadLetters returns the messages the queue gave up on.
+func (q *Queue) DeadLetters(ctx context.Context) ([]*QueuedMessage, error) {
+	return q.list(ctx, deadLetterKeyPrefix)
+}
+
+// Requeue moves a dead letter back to the queue, with its attempts reset,
+// e.g. once the cause of the failure is fixed.
+func (q *Queue) Requeue(ctx context.Context, id string) error {
+	key := deadLetterKeyPrefix + id
+	m, err := q.load(ctx, key)
+	if err != nil || m == nil {
+		return err
+	}
+
+	m.Attempts = 0
+	m.NextAttemptAt = q.now()
+	if err := q.store(ctx, queueKey(m), m); err != nil {
+		return err
+	}
+	return q.storage.Delete(ctx, key)
+}
+
+// Discard removes a dead letter.
+func (q *Queue) Discard(ctx context.Context, id string) error {
+	return q.storage.Delete(ctx, deadLetterKeyPrefix+id)
+}
+
+// Run sends the queued messages until ctx is done.
+func (q *Queue) Run(ctx context.Context) error {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := q.Process(ctx); err != nil && ctx.Err() == nil {
+			q.logger.Errorw("queue processing failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process sends the messages that are due for an attempt. Run calls it on
+// every poll.
+func (q *Queue) Process(ctx context.Context) error {
+	keys, err := q.storage.Keys(ctx, queueKeyPrefix)
+	if err != nil {
+		return err
+	}
+
+	due := fmt.Sprintf("%020d", q.now().UnixNano())
+	for _, key := range keys {
+		if id := strings.TrimPrefix(key, queueKeyPrefix); len(id) >= len(due) && id[:len(due)] > due {
+			// keys are sorted, so the rest isn't due either
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := q.attempt(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *Queue) attempt(ctx context.Context, key string) error {
+	m, err := q.load(ctx, key)
+	if err != nil || m == nil {
+		return err
+	}
+
+	_, _, err = q.client.Send(m.UserID, m.Message, WithIdempotencyKey(m.ID))
+	if err == nil {
+		return q.storage.Delete(ctx, key)
+	}
+
+	var next string
+	if q.retry.failed(&m.SendState, err, q.now()) {
+		q.logger.Errorw("queued message send failed", "id", m.ID, "userId", m.UserID, "attempt", m.Attempts, "err", err)
+		next = queueKey(m)
+	} else {
+		q.logger.Errorw("queued message dead-lettered", "id", m.ID, "userId", m.UserID, "attempts", m.Attempts, "err", err)
+		next = deadLetterKeyPrefix + m.ID
+	}
+
+	// stored before the old key is removed, so a crash in between sends
+	// the message again rather than losing it
+	if err := q.store(ctx, next, m); err != nil {
+		return err
+	}
+	return q.storage.Delete(ctx, key)
+}
+
+func (q *Queue) list(ctx context.Context, prefix string) ([]*QueuedMessage, error) {
+	keys, err := q.storage.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*QueuedMessage
+	for _, key := range keys {
+		m, err := q.load(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+// load returns the message at key, or nil when it is gone.
+func (q *Queue) load(ctx context.Context, key string) (*QueuedMessage, error) {
+	value, ok, err := q.storage.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var m QueuedMessage
+	if err := json.Unmarshal(value, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (q *Queue) store(ctx context.Context, key string, m *QueuedMessage) error {
+	value, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return q.storage.Set(ctx, key, value, 0)
+}
+
+// queueKey sorts the messages by their next attempt.
+func queueKey(m *QueuedMessage) string {
+	return fmt.Sprintf("%s%020d-%s", queueKeyPrefix, m.NextAttemptAt.UnixNano(), m.ID)
+}
+
+// sendRetry decides whether a failed send of a stored message is tried
+// again, and when. Queue and Scheduler share it.
+type sendRetry struct {
+	maxAttempts int
+	backoff     *ExponentialBackoffPolicy
+}
+
+func newSendRetry(maxAttempts int, baseDelay, maxDelay time.Duration) *sendRetry {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultQueueMaxAttempts
+	}
+	if baseDelay <= 0 {
+		baseDelay = defaultQueueBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultQueueMaxDelay
+	}
+	return &sendRetry{
+		maxAttempts: maxAttempts,
+		backoff:     &ExponentialBackoffPolicy{BaseDelay: baseDelay, MaxDelay: maxDelay},
+	}
+}
+
+// failed records the failed send in state and reports whether the message
+// should be sent again, at state.NextAttemptAt. Network errors, 429 and
+// 5xx are retried until maxAttempts sends.
+func (r *sendRetry) failed(state *SendState, err error, now time.Time) bool {
+	state.Attempts++
+	state.LastError = err.Error()
+	var smoochErr *SmoochError
+	if errors.As(err, &smoochErr) {
+		state.StatusCode = smoochErr.Code()
+		state.ErrorCode = smoochErr.ErrorCode()
+		if !isRetryableStatus(smoochErr.Code()) {
+			return false
+		}
+	}
+	if state.Attempts >= r.maxAttempts {
+		return false
+	}
+	state.NextAttemptAt = now.Add(r.backoff.NextDelay(nil, state.Attempts))
+	return true
+}
@@ -0,0 +1,109 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	statuses := map[string]int{}
+	var sent []string
+	fn := func(req *http.Request) *http.Response {
+		key := req.Header.Get(idempotencyKeyHeaderKey)
+		sent = append(sent, key)
+		status := statuses[key]
+		body := `{"error":{"code":"bad_request","description":"invalid user"}}`
+		switch status {
+		case 0:
+			status = http.StatusCreated
+			body = sampleResponse
+		case http.StatusServiceUnavailable:
+			body = `{"error":{"code":"unavailable"}}`
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Unix(1444348338, 0)
+	q := NewQueue(sc, NewMemoryStorage(), QueueOptions{MaxAttempts: 2, BaseDelay: time.Minute})
+	q.now = func() time.Time { return now }
+
+	message := &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "Your order shipped"}
+	delivered, err := q.Enqueue(ctx, "TestUser", message)
+	assert.NoError(t, err)
+	flaky, err := q.Enqueue(ctx, "TestUser", message)
+	assert.NoError(t, err)
+	rejected, err := q.Enqueue(ctx, "Unknown", message)
+	assert.NoError(t, err)
+	statuses[flaky.ID] = http.StatusServiceUnavailable
+	statuses[rejected.ID] = http.StatusBadRequest
+
+	assert.NoError(t, q.Process(ctx))
+	assert.ElementsMatch(t, []string{delivered.ID, flaky.ID, rejected.ID}, sent)
+
+	pending, err := q.Pending(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, flaky.ID, pending[0].ID)
+		assert.Equal(t, 1, pending[0].Attempts)
+		assert.Equal(t, now.Add(time.Minute), pending[0].NextAttemptAt.Local())
+		assert.Equal(t, http.StatusServiceUnavailable, pending[0].StatusCode)
+	}
+
+	deadLetters, err := q.DeadLetters(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, rejected.ID, deadLetters[0].ID)
+		assert.Equal(t, http.StatusBadRequest, deadLetters[0].StatusCode)
+		assert.Equal(t, "bad_request", deadLetters[0].ErrorCode)
+		assert.Contains(t, deadLetters[0].LastError, "invalid user")
+	}
+
+	// the retry isn't due yet
+	sent = nil
+	assert.NoError(t, q.Process(ctx))
+	assert.Empty(t, sent)
+
+	// the second failure uses up the attempts
+	now = now.Add(time.Minute)
+	assert.NoError(t, q.Process(ctx))
+	assert.Equal(t, []string{flaky.ID}, sent)
+	pending, err = q.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	deadLetters, err = q.DeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 2)
+
+	// requeued once the api recovers
+	delete(statuses, flaky.ID)
+	assert.NoError(t, q.Requeue(ctx, flaky.ID))
+	assert.NoError(t, q.Discard(ctx, rejected.ID))
+	assert.NoError(t, q.Process(ctx))
+	assert.Equal(t, []string{flaky.ID, flaky.ID}, sent)
+	pending, err = q.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	deadLetters, err = q.DeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, deadLetters)
+
+	_, err = q.Enqueue(ctx, "", message)
+	assert.Equal(t, ErrUserIDEmpty, err)
+	_, err = q.Enqueue(ctx, "TestUser", nil)
+	assert.Equal(t, ErrMessageNil, err)
+}
@@ -0,0 +1,62 @@
+package smooch
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	rateLimitLimitHeaderKey     = "X-RateLimit-Limit"
+	rateLimitRemainingHeaderKey = "X-RateLimit-Remaining"
+	rateLimitResetHeaderKey     = "X-RateLimit-Reset"
+
+	// reset values above this are unix timestamps, below it they are seconds
+	// left until the window resets
+	rateLimitResetEpochThreshold = 1e9
+)
+
+// RateLimit holds the rate limit state reported by Smooch for a response.
+type RateLimit struct {
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+func parseRateLimit(header http.Header, now time.Time) *RateLimit {
+	limit, hasLimit := parseIntHeader(header, rateLimitLimitHeaderKey)
+	remaining, hasRemaining := parseIntHeader(header, rateLimitRemainingHeaderKey)
+	reset, hasReset := parseIntHeader(header, rateLimitResetHeaderKey)
+	retryAfter, hasRetryAfter := parseRetryAfter(header.Get(retryAfterHeaderKey))
+
+	if !hasLimit && !hasRemaining && !hasReset && !hasRetryAfter {
+		return nil
+	}
+
+	rl := &RateLimit{
+		Limit:      limit,
+		Remaining:  remaining,
+		RetryAfter: retryAfter,
+	}
+	if hasReset {
+		if reset > rateLimitResetEpochThreshold {
+			rl.Reset = time.Unix(int64(reset), 0)
+		} else {
+			rl.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+	return rl
+}
+
+func parseIntHeader(header http.Header, key string) (int, bool) {
+	value := header.Get(key)
+	if value == "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
@@ -0,0 +1,60 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Unix(1444348338, 0)
+
+	assert.Nil(t, parseRateLimit(http.Header{}, now))
+
+	header := http.Header{}
+	header.Set(rateLimitLimitHeaderKey, "100")
+	header.Set(rateLimitRemainingHeaderKey, "7")
+	header.Set(rateLimitResetHeaderKey, "30")
+	rl := parseRateLimit(header, now)
+	assert.Equal(t, 100, rl.Limit)
+	assert.Equal(t, 7, rl.Remaining)
+	assert.Equal(t, now.Add(30*time.Second), rl.Reset)
+	assert.Equal(t, time.Duration(0), rl.RetryAfter)
+
+	header.Set(rateLimitResetHeaderKey, "1444348400")
+	header.Set(retryAfterHeaderKey, "5")
+	rl = parseRateLimit(header, now)
+	assert.Equal(t, time.Unix(1444348400, 0), rl.Reset)
+	assert.Equal(t, 5*time.Second, rl.RetryAfter)
+}
+
+func TestResponseDataRateLimit(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		header := http.Header{}
+		header.Set(rateLimitLimitHeaderKey, "100")
+		header.Set(rateLimitRemainingHeaderKey, "0")
+		header.Set(retryAfterHeaderKey, "2")
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     header,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error":{"code":"too_many_requests"}}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	_, respData, err := sc.GetAppUser("123")
+	assert.Error(t, err)
+	assert.NotNil(t, respData.RateLimit)
+	assert.Equal(t, 100, respData.RateLimit.Limit)
+	assert.Equal(t, 0, respData.RateLimit.Remaining)
+	assert.Equal(t, 2*time.Second, respData.RateLimit.RetryAfter)
+}
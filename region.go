@@ -0,0 +1,55 @@
+package smooch
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	regionsMu sync.RWMutex
+	regions   = map[string]string{
+		RegionUS: usRootURL,
+		RegionEU: euRootURL,
+	}
+)
+
+// RegisterRegion adds or replaces the API root URL used for a region code.
+// It has to be called before New is called with that region.
+func RegisterRegion(region string, rootURL string) error {
+	if region == "" {
+		return ErrRegionEmpty
+	}
+
+	u, err := url.Parse(rootURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return ErrRegionURLInvalid
+	}
+
+	regionsMu.Lock()
+	defer regionsMu.Unlock()
+	regions[strings.ToUpper(region)] = strings.TrimRight(rootURL, "/")
+	return nil
+}
+
+// RegionRootURL returns the API root URL registered for a region code.
+func RegionRootURL(region string) (string, bool) {
+	regionsMu.RLock()
+	defer regionsMu.RUnlock()
+	rootURL, ok := regions[strings.ToUpper(region)]
+	return rootURL, ok
+}
+
+// Regions returns the region codes currently registered.
+func Regions() []string {
+	regionsMu.RLock()
+	defer regionsMu.RUnlock()
+	codes := make([]string, 0, len(regions))
+	for code := range regions {
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,50 @@
+package smooch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionRegistry(t *testing.T) {
+	rootURL, ok := RegionRootURL(RegionEU)
+	assert.True(t, ok)
+	assert.Equal(t, euRootURL, rootURL)
+
+	_, ok = RegionRootURL("AU")
+	assert.False(t, ok)
+
+	assert.Equal(t, ErrRegionEmpty, RegisterRegion("", "https://api.au-1.smooch.io"))
+	assert.Equal(t, ErrRegionURLInvalid, RegisterRegion("AU", "api.au-1.smooch.io"))
+
+	assert.NoError(t, RegisterRegion("au", "https://api.au-1.smooch.io/"))
+	defer func() {
+		regionsMu.Lock()
+		delete(regions, "AU")
+		regionsMu.Unlock()
+	}()
+	assert.Contains(t, Regions(), "AU")
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		Region:       "AU",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.au-1.smooch.io/v1.1/apps/app", sc.getURL("/v1.1/apps/app", nil))
+
+	sc, err = New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		Region:       "eu",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.eu-1.smooch.io/v1.1/apps/app", sc.getURL("/v1.1/apps/app", nil))
+
+	sc, err = New(Options{
+		VerifySecret: "very-secure-test-secret",
+		Region:       "MARS",
+	})
+	assert.Nil(t, sc)
+	assert.Equal(t, ErrRegionUnknown, err)
+}
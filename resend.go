@@ -0,0 +1,187 @@
+package smooch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	resendKeyPrefix = "smooch:resend:"
+
+	// DefaultResendTTL is how long a ResendPolicy remembers sent messages
+	// when ResendOptions.TTL is not set.
+	DefaultResendTTL = 24 * time.Hour
+)
+
+// ResendOptions configures a ResendPolicy. Retries is how many times a
+// message is sent again on the channel it failed on. Fallbacks are the
+// channel types, e.g. SourceTypeWhatsApp, to try next in order, skipping
+// the ones the user hasn't linked.
+type ResendOptions struct {
+	Retries   int
+	Fallbacks []string
+	TTL       time.Duration
+	Logger    Logger
+}
+
+// ResendPolicy sends messages again when Smooch reports they couldn't be
+// delivered at all, with a final delivery failure webhook: first on the
+// same channel, then on the fallback channels.
+// Only the messages sent with ResendPolicy.Send are handled; they are kept
+// in the storage for TTL, since delivery failure webhooks carry no more
+// than the message id.
+type ResendPolicy struct {
+	client    Client
+	storage   Storage
+	retries   int
+	fallbacks []string
+	ttl       time.Duration
+	logger    Logger
+}
+
+// resendRecord is what a ResendPolicy remembers of a sent message.
+type resendRecord struct {
+	UserID  string   `json:"userId"`
+	Message *Message `json:"message"`
+	// Attempts counts the resends on the current channel
+	Attempts int `json:"attempts,omitempty"`
+	// Tried holds the channel types the message failed on
+	Tried []string `json:"tried,omitempty"`
+}
+
+// NewResendPolicy returns a resend policy and registers it for the
+// delivery failure webhooks of the client.
+func NewResendPolicy(client Client, storage Storage, o ResendOptions) *ResendPolicy {
+	if o.TTL <= 0 {
+		o.TTL = DefaultResendTTL
+	}
+	if o.Logger == nil {
+		o.Logger = &nopLogger{}
+	}
+	p := &ResendPolicy{
+		client:    client,
+		storage:   storage,
+		retries:   o.Retries,
+		fallbacks: o.Fallbacks,
+		ttl:       o.TTL,
+		logger:    o.Logger,
+	}
+	client.OnDeliveryFailure(p.handleDeliveryFailure)
+	return p
+}
+
+// Send sends the message to the user like Client.Send, and remembers it to
+// send it again when its delivery fails. opts don't apply to the resends.
+func (p *ResendPolicy) Send(userID string, message *Message, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	response, respData, err := p.client.Send(userID, message, opts...)
+	if err != nil {
+		return response, respData, err
+	}
+
+	err = p.remember(context.Background(), response, &resendRecord{UserID: userID, Message: message})
+	return response, respData, err
+}
+
+func (p *ResendPolicy) handleDeliveryFailure(e *DeliveryFailureEvent) {
+	// Smooch may still be trying other channels after a failure that isn't
+	// final, so resending then could deliver the message twice
+	if !e.IsFinalEvent || e.Message == nil || e.Message.ID == "" {
+		return
+	}
+	ctx := context.Background()
+	if err := p.resend(ctx, e); err != nil {
+		p.logger.Errorw("message resend failed", "messageId", e.Message.ID, "appUserId", e.AppUser.ID, "err", err)
+	}
+}
+
+func (p *ResendPolicy) resend(ctx context.Context, e *DeliveryFailureEvent) error {
+	key := resendKeyPrefix + e.Message.ID
+	value, ok, err := p.storage.Get(ctx, key)
+	if err != nil || !ok {
+		return err
+	}
+	var record resendRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return err
+	}
+
+	var failed MessageDestination
+	if e.Destination != nil {
+		failed = MessageDestination{IntegrationID: e.Destination.IntegrationId, IntegrationType: e.Destination.Type}
+	}
+
+	destination := &failed
+	if record.Attempts < p.retries && failed != (MessageDestination{}) {
+		record.Attempts++
+	} else {
+		record.Tried = append(record.Tried, failed.IntegrationType)
+		record.Attempts = 0
+		destination, err = p.fallback(record.UserID, record.Tried)
+		if err != nil {
+			return err
+		}
+		if destination == nil {
+			p.logger.Errorw("message undeliverable", "messageId", e.Message.ID, "appUserId", e.AppUser.ID, "tried", record.Tried)
+			return p.storage.Delete(ctx, key)
+		}
+	}
+
+	message := *record.Message
+	message.Destination = destination
+	record.Message = &message
+	response, _, err := p.client.Send(record.UserID, &message)
+	if err != nil {
+		return err
+	}
+	p.logger.Infow("message resent", "messageId", e.Message.ID, "appUserId", e.AppUser.ID, "channel", destination.IntegrationType)
+
+	if err := p.remember(ctx, response, &record); err != nil {
+		return err
+	}
+	return p.storage.Delete(ctx, key)
+}
+
+// fallback returns the first fallback channel the user has linked and the
+// message wasn't tried on, or nil.
+func (p *ResendPolicy) fallback(userID string, tried []string) (*MessageDestination, error) {
+	if len(p.fallbacks) == 0 {
+		return nil, nil
+	}
+	appUser, _, err := p.client.GetAppUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range p.fallbacks {
+		if containsString(tried, channel) {
+			continue
+		}
+		for _, client := range appUser.Clients {
+			if client.Platform == channel && client.Active && !client.Blocked {
+				return &MessageDestination{IntegrationID: client.IntegrationId, IntegrationType: channel}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (p *ResendPolicy) remember(ctx context.Context, response *ResponsePayload, record *resendRecord) error {
+	if response == nil || response.Message == nil || response.Message.ID == "" {
+		return nil
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return p.storage.Set(ctx, resendKeyPrefix+response.Message.ID, value, p.ttl)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResendPolicy(t *testing.T) {
+	var destinations []*MessageDestination
+	fn := func(req *http.Request) *http.Response {
+		body := `{"appUser": {"_id": "123", "clients": [
+			{"platform": "messenger", "integrationId": "fb", "active": true},
+			{"platform": "telegram", "integrationId": "tg", "active": true, "blocked": true},
+			{"platform": "viber", "integrationId": "vb", "active": true}
+		]}}`
+		if req.Method == http.MethodPost {
+			var message Message
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&message))
+			assert.Equal(t, "Your table is ready", message.Text)
+			destinations = append(destinations, message.Destination)
+			body = fmt.Sprintf(`{"message": {"_id": "m%d", "role": "appMaker", "type": "text"}}`, len(destinations))
+		}
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	p := NewResendPolicy(sc, NewMemoryStorage(), ResendOptions{
+		Retries:   1,
+		Fallbacks: []string{SourceTypeTelegram, SourceTypeMessenger, SourceTypeViber},
+	})
+	_, _, err = p.Send("123", &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "Your table is ready"})
+	assert.NoError(t, err)
+
+	failOn := func(messageID string, channel string, integrationID string, final bool) {
+		p.handleDeliveryFailure(&DeliveryFailureEvent{
+			Event:        Event{AppUser: AppUser{ID: "123"}},
+			Destination:  &SourceDestination{Type: channel, IntegrationId: integrationID},
+			Message:      &TruncatedMessage{ID: messageID},
+			Error:        &Error{Code: "unavailable"},
+			IsFinalEvent: final,
+		})
+	}
+	fail := func(messageID string, channel string, integrationID string) {
+		failOn(messageID, channel, integrationID, true)
+	}
+
+	// Smooch is still trying other channels
+	failOn("m1", SourceTypeWhatsApp, "wa", false)
+	failOn("m1", SourceTypeMessenger, "fb", false)
+	assert.Equal(t, []*MessageDestination{nil}, destinations)
+
+	// retried on whatsapp, then moved to messenger, skipping the blocked
+	// telegram client, and retried there too
+	fail("m1", SourceTypeWhatsApp, "wa")
+	fail("m2", SourceTypeWhatsApp, "wa")
+	fail("m3", SourceTypeMessenger, "fb")
+	fail("m4", SourceTypeMessenger, "fb")
+	// viber is the last fallback
+	fail("m5", SourceTypeViber, "vb")
+	fail("m6", SourceTypeViber, "vb")
+	fail("m7", SourceTypeViber, "vb")
+	// unknown messages are left alone
+	fail("m1", SourceTypeWhatsApp, "wa")
+
+	whatsapp := &MessageDestination{IntegrationID: "wa", IntegrationType: SourceTypeWhatsApp}
+	messenger := &MessageDestination{IntegrationID: "fb", IntegrationType: SourceTypeMessenger}
+	viber := &MessageDestination{IntegrationID: "vb", IntegrationType: SourceTypeViber}
+	assert.Equal(t, []*MessageDestination{nil, whatsapp, messenger, messenger, viber, viber}, destinations)
+}
@@ -0,0 +1,127 @@
+package smooch
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	retryAfterHeaderKey = "Retry-After"
+
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 200 * time.Millisecond
+	defaultRetryMaxDelay    = 10 * time.Second
+)
+
+// RetryPolicy decides whether a failed API call should be attempted again
+// and how long to wait before doing so. attempt starts at 1 for the first
+// call that was made.
+type RetryPolicy interface {
+	ShouldRetry(resp *http.Response, err error, attempt int) bool
+	NextDelay(resp *http.Response, attempt int) time.Duration
+}
+
+// NoRetryPolicy never retries. It is used when Options.RetryPolicy is nil.
+type NoRetryPolicy struct{}
+
+func (NoRetryPolicy) ShouldRetry(resp *http.Response, err error, attempt int) bool {
+	return false
+}
+
+func (NoRetryPolicy) NextDelay(resp *http.Response, attempt int) time.Duration {
+	return 0
+}
+
+// ExponentialBackoffPolicy retries transport errors and 5xx/429 responses,
+// doubling the delay after every attempt.
+type ExponentialBackoffPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func NewExponentialBackoffPolicy() *ExponentialBackoffPolicy {
+	return &ExponentialBackoffPolicy{
+		MaxAttempts: defaultRetryMaxAttempts,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+	}
+}
+
+func (p *ExponentialBackoffPolicy) ShouldRetry(resp *http.Response, err error, attempt int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return isRetryableStatus(resp.StatusCode)
+}
+
+func (p *ExponentialBackoffPolicy) NextDelay(resp *http.Response, attempt int) time.Duration {
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(attempt-1)))
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// RateLimitPolicy retries only 429 responses, waiting for the duration
+// advertised in the Retry-After header when present and falling back to
+// exponential backoff otherwise.
+type RateLimitPolicy struct {
+	ExponentialBackoffPolicy
+}
+
+func NewRateLimitPolicy() *RateLimitPolicy {
+	return &RateLimitPolicy{
+		ExponentialBackoffPolicy: *NewExponentialBackoffPolicy(),
+	}
+}
+
+func (p *RateLimitPolicy) ShouldRetry(resp *http.Response, err error, attempt int) bool {
+	if attempt >= p.MaxAttempts || err != nil {
+		return false
+	}
+	return resp.StatusCode == http.StatusTooManyRequests
+}
+
+func (p *RateLimitPolicy) NextDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if delay, ok := parseRetryAfter(resp.Header.Get(retryAfterHeaderKey)); ok {
+			if p.MaxDelay > 0 && delay > p.MaxDelay {
+				delay = p.MaxDelay
+			}
+			return delay
+		}
+	}
+	return p.ExponentialBackoffPolicy.NextDelay(resp, attempt)
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		delay := time.Until(t)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,129 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoRetryPolicy(t *testing.T) {
+	p := NoRetryPolicy{}
+	assert.False(t, p.ShouldRetry(nil, errors.New("connection reset"), 1))
+	assert.False(t, p.ShouldRetry(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil, 1))
+	assert.Equal(t, time.Duration(0), p.NextDelay(nil, 1))
+}
+
+func TestExponentialBackoffPolicy(t *testing.T) {
+	p := &ExponentialBackoffPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    300 * time.Millisecond,
+	}
+
+	assert.True(t, p.ShouldRetry(nil, errors.New("connection reset"), 1))
+	assert.True(t, p.ShouldRetry(&http.Response{StatusCode: http.StatusBadGateway}, nil, 1))
+	assert.True(t, p.ShouldRetry(&http.Response{StatusCode: http.StatusTooManyRequests}, nil, 2))
+	assert.False(t, p.ShouldRetry(&http.Response{StatusCode: http.StatusBadRequest}, nil, 1))
+	assert.False(t, p.ShouldRetry(&http.Response{StatusCode: http.StatusBadGateway}, nil, 3))
+	assert.False(t, p.ShouldRetry(nil, context.Canceled, 1))
+	assert.False(t, p.ShouldRetry(nil, fmt.Errorf("post: %w", context.DeadlineExceeded), 1))
+
+	assert.Equal(t, 100*time.Millisecond, p.NextDelay(nil, 1))
+	assert.Equal(t, 200*time.Millisecond, p.NextDelay(nil, 2))
+	assert.Equal(t, 300*time.Millisecond, p.NextDelay(nil, 3))
+}
+
+func TestRateLimitPolicy(t *testing.T) {
+	p := NewRateLimitPolicy()
+
+	assert.False(t, p.ShouldRetry(nil, errors.New("connection reset"), 1))
+	assert.False(t, p.ShouldRetry(&http.Response{StatusCode: http.StatusBadGateway}, nil, 1))
+	assert.True(t, p.ShouldRetry(&http.Response{StatusCode: http.StatusTooManyRequests}, nil, 1))
+
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{},
+	}
+	resp.Header.Set(retryAfterHeaderKey, "2")
+	assert.Equal(t, 2*time.Second, p.NextDelay(resp, 1))
+
+	resp.Header.Del(retryAfterHeaderKey)
+	assert.Equal(t, defaultRetryBaseDelay, p.NextDelay(resp, 1))
+}
+
+func TestSendWithRetryPolicy(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		calls++
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), `"type":"text"`)
+
+		if calls < 3 {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+			}
+		}
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		RetryPolicy: &ExponentialBackoffPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+
+	response, _, err := sc.Send("TestUser", &Message{
+		Role: RoleAppMaker,
+		Type: MessageTypeText,
+		Text: "hello",
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryStopsWhenCanceled(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		RetryPolicy: &ExponentialBackoffPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Hour,
+		},
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = sc.Do(ctx, http.MethodGet, "/v1.1/apps", nil, nil, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,239 @@
+package smooch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	scheduleKeyPrefix           = "smooch:scheduled:"
+	scheduleDeadLetterKeyPrefix = "smooch:scheduled-deadletters:"
+
+	// DefaultSchedulePollInterval is how often a scheduler looks for due
+	// messages when SchedulerOptions.PollInterval is not set.
+	DefaultSchedulePollInterval = 10 * time.Second
+)
+
+// ScheduledMessage is a message stored by a Scheduler until DeliverAt.
+// NextAttemptAt is set once a send failed.
+type ScheduledMessage struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Message   *Message  `json:"message"`
+	DeliverAt time.Time `json:"deliverAt"`
+	SendState
+}
+
+// SchedulerOptions configures a Scheduler. Failed sends are retried like
+// in a Queue, with the same defaults.
+type SchedulerOptions struct {
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	PollInterval time.Duration
+	Logger       Logger
+}
+
+// Scheduler sends messages at a later time, e.g. appointment reminders.
+// Scheduled messages are kept in the storage until they are sent, so they
+// survive restarts. Only one scheduler may run against a storage, as
+// messages are not locked while they are sent.
+//
+// Delivery is at least once: a message is removed only after Smooch
+// accepted it, and is tried again with exponential backoff when the send
+// fails with a network error, 429 or 5xx. Every send of a message carries
+// the same idempotency key. Messages Smooch rejects otherwise, or still
+// failing after MaxAttempts sends, are moved to the dead letters.
+type Scheduler struct {
+	client       Client
+	storage      Storage
+	retry        *sendRetry
+	pollInterval time.Duration
+	logger       Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func NewScheduler(client Client, storage Storage, o SchedulerOptions) *Scheduler {
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultSchedulePollInterval
+	}
+	if o.Logger == nil {
+		o.Logger = &nopLogger{}
+	}
+	return &Scheduler{
+		client:       client,
+		storage:      storage,
+		retry:        newSendRetry(o.MaxAttempts, o.BaseDelay, o.MaxDelay),
+		pollInterval: o.PollInterval,
+		logger:       o.Logger,
+		now:          time.Now,
+	}
+}
+
+// Schedule stores the message to be sent to the user at deliverAt. The
+// message is validated right away.
+func (s *Scheduler) Schedule(ctx context.Context, userID string, message *Message, deliverAt time.Time) (*ScheduledMessage, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+	if message == nil {
+		return nil, ErrMessageNil
+	}
+	if err := message.Validate(); err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	m := &ScheduledMessage{
+		// the time comes first so keys sort in delivery order
+		ID:        fmt.Sprintf("%020d-%s", deliverAt.UnixNano(), hex.EncodeToString(suffix)),
+		UserID:    userID,
+		Message:   message,
+		DeliverAt: deliverAt,
+	}
+	if err := s.store(ctx, scheduleKeyPrefix+m.ID, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Cancel removes a scheduled message. It does nothing when the message
+// was already sent.
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrScheduleIDEmpty
+	}
+	return s.storage.Delete(ctx, scheduleKeyPrefix+id)
+}
+
+// Pending returns the messages not sent yet, in delivery order.
+func (s *Scheduler) Pending(ctx context.Context) ([]*ScheduledMessage, error) {
+	return s.list(ctx, scheduleKeyPrefix)
+}
+
+func (s *Scheduler) list(ctx context.Context, prefix string) ([]*ScheduledMessage, error) {
+	keys, err := s.storage.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*ScheduledMessage
+	for _, key := range keys {
+		value, ok, err := s.storage.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// sent or cancelled since listed
+			continue
+		}
+		var m ScheduledMessage
+		if err := json.Unmarshal(value, &m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+	return messages, nil
+}
+
+// Run sends the messages as they come due until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Errorw("scheduled delivery failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends the messages whose time has come. Run calls it on every
+// poll.
+func (s *Scheduler) DeliverDue(ctx context.Context) error {
+	keys, err := s.storage.Keys(ctx, scheduleKeyPrefix)
+	if err != nil {
+		return err
+	}
+
+	due := fmt.Sprintf("%020d", s.now().UnixNano())
+	for _, key := range keys {
+		if id := strings.TrimPrefix(key, scheduleKeyPrefix); len(id) >= len(due) && id[:len(due)] > due {
+			// keys are sorted, so the rest isn't due either
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.deliver(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) deliver(ctx context.Context, key string) error {
+	value, ok, err := s.storage.Get(ctx, key)
+	if err != nil || !ok {
+		return err
+	}
+	var m ScheduledMessage
+	if err := json.Unmarshal(value, &m); err != nil {
+		return err
+	}
+
+	if s.now().Before(m.NextAttemptAt) {
+		return nil
+	}
+
+	_, _, err = s.client.Send(m.UserID, m.Message, WithIdempotencyKey(m.ID))
+	if err == nil {
+		return s.storage.Delete(ctx, key)
+	}
+
+	if s.retry.failed(&m.SendState, err, s.now()) {
+		s.logger.Errorw("scheduled message send failed", "id", m.ID, "userId", m.UserID, "attempt", m.Attempts, "err", err)
+		return s.store(ctx, key, &m)
+	}
+
+	s.logger.Errorw("scheduled message dead-lettered", "id", m.ID, "userId", m.UserID, "attempts", m.Attempts, "err", err)
+	if err := s.store(ctx, scheduleDeadLetterKeyPrefix+m.ID, &m); err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, key)
+}
+
+// DeadLetters returns the messages the scheduler gave up on.
+func (s *Scheduler) DeadLetters(ctx context.Context) ([]*ScheduledMessage, error) {
+	return s.list(ctx, scheduleDeadLetterKeyPrefix)
+}
+
+// Discard removes a dead letter.
+func (s *Scheduler) Discard(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrScheduleIDEmpty
+	}
+	return s.storage.Delete(ctx, scheduleDeadLetterKeyPrefix+id)
+}
+
+func (s *Scheduler) store(ctx context.Context, key string, m *ScheduledMessage) error {
+	value, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.storage.Set(ctx, key, value, 0)
+}
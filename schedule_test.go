@@ -0,0 +1,187 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	var sent []string
+	fn := func(req *http.Request) *http.Response {
+		sent = append(sent, req.Header.Get(idempotencyKeyHeaderKey))
+		body := sampleResponse
+		if status != http.StatusCreated {
+			body = `{"error":{"code":"unavailable"}}`
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Unix(1444348338, 0)
+	s := NewScheduler(sc, NewMemoryStorage(), SchedulerOptions{MaxAttempts: 3, BaseDelay: time.Minute})
+	s.now = func() time.Time { return now }
+
+	message := &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "See you tomorrow"}
+	later, err := s.Schedule(ctx, "TestUser", message, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	soon, err := s.Schedule(ctx, "TestUser", message, now.Add(time.Hour))
+	assert.NoError(t, err)
+	cancelled, err := s.Schedule(ctx, "TestUser", message, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Cancel(ctx, cancelled.ID))
+
+	pending, err := s.Pending(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 2) {
+		assert.Equal(t, soon.ID, pending[0].ID)
+		assert.Equal(t, later.ID, pending[1].ID)
+	}
+
+	// nothing is due yet
+	assert.NoError(t, s.DeliverDue(ctx))
+	assert.Empty(t, sent)
+
+	// a failed send is kept and tried again after the backoff
+	now = now.Add(time.Hour)
+	assert.NoError(t, s.DeliverDue(ctx))
+	assert.Equal(t, []string{soon.ID}, sent)
+	pending, err = s.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, pending[0].StatusCode)
+	assert.Contains(t, pending[0].LastError, "unavailable")
+	assert.True(t, now.Add(time.Minute).Equal(pending[0].NextAttemptAt))
+
+	assert.NoError(t, s.DeliverDue(ctx))
+	assert.Equal(t, []string{soon.ID}, sent)
+
+	status = http.StatusCreated
+	now = now.Add(time.Minute)
+	assert.NoError(t, s.DeliverDue(ctx))
+	assert.Equal(t, []string{soon.ID, soon.ID}, sent)
+
+	// a rejected message is dead-lettered
+	status = http.StatusBadRequest
+	now = now.Add(time.Hour)
+	assert.NoError(t, s.DeliverDue(ctx))
+	assert.Equal(t, []string{soon.ID, soon.ID, later.ID}, sent)
+	pending, err = s.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	deadLetters, err := s.DeadLetters(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, later.ID, deadLetters[0].ID)
+		assert.Equal(t, http.StatusBadRequest, deadLetters[0].StatusCode)
+	}
+	assert.NoError(t, s.Discard(ctx, later.ID))
+	deadLetters, err = s.DeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, deadLetters)
+
+	_, err = s.Schedule(ctx, "", message, now)
+	assert.Equal(t, ErrUserIDEmpty, err)
+	_, err = s.Schedule(ctx, "TestUser", &Message{Role: RoleAppMaker, Type: MessageTypeText}, now)
+	assert.IsType(t, &MessageValidationError{}, err)
+	assert.Equal(t, ErrScheduleIDEmpty, s.Cancel(ctx, ""))
+}
+
+func TestSchedulerMaxAttempts(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		calls++
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"error":{"code":"unavailable"}}`))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Unix(1444348338, 0)
+	s := NewScheduler(sc, NewMemoryStorage(), SchedulerOptions{MaxAttempts: 3, BaseDelay: time.Minute})
+	s.now = func() time.Time { return now }
+
+	message := &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "See you tomorrow"}
+	scheduled, err := s.Schedule(ctx, "TestUser", message, now)
+	assert.NoError(t, err)
+
+	// the delay doubles after every attempt, and polls in between don't
+	// send
+	for _, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		assert.NoError(t, s.DeliverDue(ctx))
+		now = now.Add(delay - time.Second)
+		assert.NoError(t, s.DeliverDue(ctx))
+		now = now.Add(time.Second)
+	}
+	assert.NoError(t, s.DeliverDue(ctx))
+	assert.Equal(t, 3, calls)
+
+	pending, err := s.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	deadLetters, err := s.DeadLetters(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, scheduled.ID, deadLetters[0].ID)
+		assert.Equal(t, 3, deadLetters[0].Attempts)
+	}
+
+	// nothing is sent anymore
+	now = now.Add(time.Hour)
+	assert.NoError(t, s.DeliverDue(ctx))
+	assert.Equal(t, 3, calls)
+}
+
+func TestSchedulerRun(t *testing.T) {
+	sent := make(chan struct{}, 1)
+	fn := func(req *http.Request) *http.Response {
+		sent <- struct{}{}
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	s := NewScheduler(sc, NewMemoryStorage(), SchedulerOptions{PollInterval: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	message := &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "Now"}
+	_, err = s.Schedule(ctx, "TestUser", message, time.Now())
+	assert.NoError(t, err)
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("scheduled message not sent")
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
@@ -0,0 +1,124 @@
+package smooch
+
+import (
+	"io"
+	"strings"
+)
+
+// SendText sends a text message to the user as the app maker.
+func (sc *smoochClient) SendText(userID string, text string, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	if text == "" {
+		return nil, nil, ErrMessageTextEmpty
+	}
+
+	return sc.Send(userID, &Message{
+		Role: RoleAppMaker,
+		Type: MessageTypeText,
+		Text: text,
+	}, opts...)
+}
+
+// SendImage sends the image at mediaURL to the user, caption is optional.
+func (sc *smoochClient) SendImage(userID string, mediaURL string, caption string, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	if mediaURL == "" {
+		return nil, nil, ErrMediaURLEmpty
+	}
+
+	return sc.Send(userID, &Message{
+		Role:     RoleAppMaker,
+		Type:     MessageTypeImage,
+		Text:     caption,
+		MediaURL: mediaURL,
+	}, opts...)
+}
+
+// SendFile sends the file at mediaURL to the user, caption is optional.
+func (sc *smoochClient) SendFile(userID string, mediaURL string, caption string, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	if mediaURL == "" {
+		return nil, nil, ErrMediaURLEmpty
+	}
+
+	return sc.Send(userID, &Message{
+		Role:     RoleAppMaker,
+		Type:     MessageTypeFile,
+		Text:     caption,
+		MediaURL: mediaURL,
+	}, opts...)
+}
+
+// SendLocation sends a location message pointing at lat, long.
+func (sc *smoochClient) SendLocation(userID string, lat float64, long float64, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	return sc.Send(userID, &Message{
+		Role:        RoleAppMaker,
+		Type:        MessageTypeLocation,
+		Coordinates: &Coordinates{Lat: lat, Long: long},
+	}, opts...)
+}
+
+// SendQuickReplies sends text with replies shown as suggestion chips, see
+// NewReplyAction and NewLocationRequestAction.
+func (sc *smoochClient) SendQuickReplies(userID string, text string, replies []*Action, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	message, err := NewMessage().Text(text).Action(replies...).Build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sc.Send(userID, message, opts...)
+}
+
+// SendFileMessage uploads r and sends it to the user as an image message
+// when mimeType is an image, or as a file message otherwise. The attachment
+// is deleted again when the message can't be sent. An empty mimeType is
+// detected from filename or the content. opts apply to both the upload and
+// the send; an idempotency key is suffixed with "-upload" for the upload,
+// since Smooch would answer the send with the upload's response otherwise.
+func (sc *smoochClient) SendFileMessage(
+	userID string,
+	r io.Reader,
+	filename string,
+	mimeType string,
+	caption string,
+	opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	if filename != "" && readerFilename(r) == "" {
+		r = &namedReader{Reader: r, filename: filename}
+	}
+
+	uploadOpts := opts
+	if key := newRequestOptions(opts).header.Get(idempotencyKeyHeaderKey); key != "" {
+		uploadOpts = append(opts[:len(opts):len(opts)], WithIdempotencyKey(key+"-upload"))
+	}
+	attachment, respData, err := sc.UploadAttachment(r, NewAttachmentUpload(mimeType), uploadOpts...)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	if attachment.MediaType != "" {
+		mimeType = attachment.MediaType
+	}
+
+	message := &Message{
+		Role:      RoleAppMaker,
+		Type:      MessageTypeFile,
+		Text:      caption,
+		MediaURL:  attachment.MediaURL,
+		MediaType: mimeType,
+	}
+	if strings.HasPrefix(mimeType, "image/") {
+		message.Type = MessageTypeImage
+	}
+
+	response, respData, err := sc.Send(userID, message, opts...)
+	if err != nil {
+		if _, deleteErr := sc.DeleteAttachment(attachment); deleteErr != nil {
+			sc.logger.Errorw("attachment cleanup failed", "mediaUrl", attachment.MediaURL, "err", deleteErr)
+		}
+		return nil, respData, err
+	}
+
+	return response, respData, nil
+}
@@ -0,0 +1,159 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendFileMessage(t *testing.T) {
+	sendStatus := http.StatusCreated
+	deleteStatus := http.StatusOK
+	var sent Message
+	deleted := false
+	keys := map[string]string{}
+
+	fn := func(req *http.Request) *http.Response {
+		switch {
+		case strings.HasPrefix(req.Header.Get(contentTypeHeaderKey), "multipart/form-data"):
+			keys["upload"] = req.Header.Get(idempotencyKeyHeaderKey)
+			form, _ := readUpload(t, req)
+			assert.Equal(t, "report.pdf", form.File["source"][0].Filename)
+			assert.Equal(t, "application/pdf", form.Value["type"][0])
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: ioutil.NopCloser(bytes.NewReader([]byte(
+					`{"mediaUrl":"https://media.smooch.io/apps/app/report.pdf","mediaType":"application/pdf"}`,
+				))),
+			}
+		case strings.HasSuffix(req.URL.Path, "/messages"):
+			keys["send"] = req.Header.Get(idempotencyKeyHeaderKey)
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&sent))
+			return &http.Response{
+				StatusCode: sendStatus,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+			}
+		default:
+			deleted = true
+			return &http.Response{
+				StatusCode: deleteStatus,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+			}
+		}
+	}
+
+	logger := &testLogger{}
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		Logger:       logger,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.SendFileMessage("123", strings.NewReader("%PDF-1.4"), "report.pdf", "", "Your report", WithIdempotencyKey("k"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"upload": "k-upload", "send": "k"}, keys)
+	assert.Equal(t, MessageTypeFile, sent.Type)
+	assert.Equal(t, RoleAppMaker, sent.Role)
+	assert.Equal(t, "Your report", sent.Text)
+	assert.Equal(t, "https://media.smooch.io/apps/app/report.pdf", sent.MediaURL)
+	assert.False(t, deleted)
+
+	sendStatus = http.StatusBadRequest
+	_, _, err = sc.SendFileMessage("123", strings.NewReader("%PDF-1.4"), "report.pdf", "", "Your report")
+	assert.Error(t, err)
+	assert.True(t, deleted)
+	assert.Empty(t, logger.find("error", "attachment cleanup failed"))
+
+	deleteStatus = http.StatusInternalServerError
+	_, _, err = sc.SendFileMessage("123", strings.NewReader("%PDF-1.4"), "report.pdf", "", "Your report")
+	assert.Error(t, err)
+	assert.Len(t, logger.find("error", "attachment cleanup failed"), 1)
+}
+
+func TestSendHelpers(t *testing.T) {
+	var sent []map[string]interface{}
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/messages", req.URL.Path)
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		delete(body, "received")
+		sent = append(sent, body)
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.SendText("123", "hello")
+	assert.NoError(t, err)
+	_, _, err = sc.SendImage("123", "https://example.org/a.png", "look")
+	assert.NoError(t, err)
+	_, _, err = sc.SendFile("123", "https://example.org/a.pdf", "")
+	assert.NoError(t, err)
+	_, _, err = sc.SendLocation("123", 45.5, -73.6)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []map[string]interface{}{
+		{"role": "appMaker", "type": "text", "text": "hello"},
+		{"role": "appMaker", "type": "image", "text": "look", "mediaUrl": "https://example.org/a.png"},
+		{"role": "appMaker", "type": "file", "mediaUrl": "https://example.org/a.pdf"},
+		{"role": "appMaker", "type": "location", "coordinates": map[string]interface{}{"lat": 45.5, "long": -73.6}},
+	}, sent)
+
+	_, _, err = sc.SendText("123", "")
+	assert.Equal(t, ErrMessageTextEmpty, err)
+	_, _, err = sc.SendImage("123", "", "look")
+	assert.Equal(t, ErrMediaURLEmpty, err)
+	_, _, err = sc.SendText("", "hello")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestSendQuickReplies(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"type": "reply", "text": "Yes", "payload": "YES", "iconUrl": "https://example.org/yes.png"},
+			map[string]interface{}{"type": "locationRequest", "text": "Share location"},
+		}, body["actions"])
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.SendQuickReplies("123", "Are you there?", []*Action{
+		NewReplyAction("Yes", "YES", "https://example.org/yes.png"),
+		NewLocationRequestAction("Share location"),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.SendQuickReplies("123", "Are you there?", []*Action{
+		NewReplyAction("Yes", "YES", ""),
+		NewLinkAction("Help", "https://example.org/help"),
+	})
+	assert.IsType(t, &MessageValidationError{}, err)
+}
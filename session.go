@@ -0,0 +1,149 @@
+package smooch
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+const (
+	sessionKeyPrefix = "smooch:session:"
+
+	// DefaultSessionWindow is the WhatsApp customer care window: businesses
+	// can send freeform messages up to 24 hours after the user's last
+	// message, and only templates after that.
+	DefaultSessionWindow = 24 * time.Hour
+)
+
+// SessionWindowOptions configures SessionWindows. Window defaults to
+// DefaultSessionWindow, and Channel, the channel type whose window is
+// tracked, to SourceTypeWhatsApp.
+type SessionWindowOptions struct {
+	Window  time.Duration
+	Channel string
+	Logger  Logger
+}
+
+// SessionWindows tracks the customer care window of every app user on a
+// channel, from the messages the users send on it. Records are kept in the
+// storage until the window closes, keyed by both the app user id and the
+// external user id.
+type SessionWindows struct {
+	client  Client
+	storage Storage
+	window  time.Duration
+	channel string
+	logger  Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewSessionWindows returns a tracker and registers it for the
+// message:appUser webhooks of the client.
+func NewSessionWindows(client Client, storage Storage, o SessionWindowOptions) *SessionWindows {
+	if o.Window <= 0 {
+		o.Window = DefaultSessionWindow
+	}
+	if o.Channel == "" {
+		o.Channel = SourceTypeWhatsApp
+	}
+	if o.Logger == nil {
+		o.Logger = &nopLogger{}
+	}
+	w := &SessionWindows{
+		client:  client,
+		storage: storage,
+		window:  o.Window,
+		channel: o.Channel,
+		logger:  o.Logger,
+		now:     time.Now,
+	}
+	client.OnMessageAppUser(w.handleMessage)
+	return w
+}
+
+// Record opens the window of the user at the given time, as a message of
+// the user does.
+func (w *SessionWindows) Record(ctx context.Context, userID string, at time.Time) error {
+	if userID == "" {
+		return ErrUserIDEmpty
+	}
+	ttl := at.Add(w.window).Sub(w.now())
+	if ttl <= 0 {
+		return nil
+	}
+	value := []byte(strconv.FormatInt(at.UnixNano(), 10))
+	return w.storage.Set(ctx, sessionKeyPrefix+userID, value, ttl)
+}
+
+// LastMessageAt returns the time of the last message of the user, and
+// false when there was none within the window.
+func (w *SessionWindows) LastMessageAt(ctx context.Context, userID string) (time.Time, bool, error) {
+	if userID == "" {
+		return time.Time{}, false, ErrUserIDEmpty
+	}
+	value, ok, err := w.storage.Get(ctx, sessionKeyPrefix+userID)
+	if err != nil || !ok {
+		return time.Time{}, false, err
+	}
+	nanos, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	at := time.Unix(0, nanos)
+	// the storage may keep expired entries a little longer
+	if !w.now().Before(at.Add(w.window)) {
+		return time.Time{}, false, nil
+	}
+	return at, true, nil
+}
+
+// CanSendFreeform reports whether the window of the user is open, so
+// messages other than templates can be sent.
+func (w *SessionWindows) CanSendFreeform(ctx context.Context, userID string) (bool, error) {
+	_, ok, err := w.LastMessageAt(ctx, userID)
+	return ok, err
+}
+
+// Send sends the message when the window of the user is open, and the
+// template otherwise. It returns ErrSessionWindowClosed when the window is
+// closed and template is nil.
+func (w *SessionWindows) Send(ctx context.Context, userID string, message *Message, template *WhatsAppTemplate, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	open, err := w.CanSendFreeform(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if open {
+		return w.client.Send(userID, message, opts...)
+	}
+	if template == nil {
+		return nil, nil, ErrSessionWindowClosed
+	}
+	return w.client.SendTemplate(userID, template, opts...)
+}
+
+// handleMessage records the time the webhook came in, as the window runs
+// from when Smooch got the message.
+func (w *SessionWindows) handleMessage(e *MessageAppUserEvent) {
+	fromUser := false
+	for _, message := range e.Messages {
+		if message.Role == RoleAppUser && message.Source != nil && message.Source.Type == w.channel {
+			fromUser = true
+		}
+	}
+	if !fromUser {
+		return
+	}
+
+	ctx := context.Background()
+	now := w.now()
+	for _, userID := range []string{e.AppUser.ID, e.AppUser.UserID} {
+		if userID == "" {
+			continue
+		}
+		if err := w.Record(ctx, userID, now); err != nil {
+			w.logger.Errorw("session window record failed", "userId", userID, "err", err)
+		}
+	}
+}
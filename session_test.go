@@ -0,0 +1,103 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionWindows(t *testing.T) {
+	var schemas []string
+	fn := func(req *http.Request) *http.Response {
+		var body struct {
+			MessageSchema string `json:"messageSchema"`
+		}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		schemas = append(schemas, body.MessageSchema)
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Unix(1444348338, 0)
+	storage := NewMemoryStorage()
+	storage.now = func() time.Time { return now }
+	w := NewSessionWindows(sc, storage, SessionWindowOptions{})
+	w.now = func() time.Time { return now }
+
+	open, err := w.CanSendFreeform(ctx, "steve")
+	assert.NoError(t, err)
+	assert.False(t, open)
+
+	// messages of the business don't open the window
+	w.handleMessage(&MessageAppUserEvent{
+		Event:    Event{AppUser: AppUser{ID: "123", UserID: "steve"}},
+		Messages: []*Message{{Role: RoleAppMaker, Type: MessageTypeText, Text: "Hi"}},
+	})
+	open, err = w.CanSendFreeform(ctx, "steve")
+	assert.NoError(t, err)
+	assert.False(t, open)
+
+	// nor do messages on other channels
+	w.handleMessage(&MessageAppUserEvent{
+		Event: Event{AppUser: AppUser{ID: "123", UserID: "steve"}},
+		Messages: []*Message{
+			{Role: RoleAppUser, Type: MessageTypeText, Text: "Hello", Source: &SourceDestination{Type: SourceTypeMessenger}},
+			{Role: RoleAppUser, Type: MessageTypeText, Text: "Hello"},
+		},
+	})
+	open, err = w.CanSendFreeform(ctx, "steve")
+	assert.NoError(t, err)
+	assert.False(t, open)
+
+	w.handleMessage(&MessageAppUserEvent{
+		Event:    Event{AppUser: AppUser{ID: "123", UserID: "steve"}},
+		Messages: []*Message{{Role: RoleAppUser, Type: MessageTypeText, Text: "Hello", Source: &SourceDestination{Type: SourceTypeWhatsApp}}},
+	})
+	for _, userID := range []string{"123", "steve"} {
+		at, ok, err := w.LastMessageAt(ctx, userID)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, now.Equal(at))
+	}
+
+	message := &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "Your room is ready"}
+	template := &WhatsAppTemplate{Name: "room_ready", Language: &TemplateLanguage{Code: "en"}}
+
+	now = now.Add(23 * time.Hour)
+	_, _, err = w.Send(ctx, "steve", message, template)
+	assert.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	open, err = w.CanSendFreeform(ctx, "123")
+	assert.NoError(t, err)
+	assert.False(t, open)
+	_, _, err = w.Send(ctx, "steve", message, template)
+	assert.NoError(t, err)
+	_, _, err = w.Send(ctx, "steve", message, nil)
+	assert.Equal(t, ErrSessionWindowClosed, err)
+
+	assert.Equal(t, []string{"", "whatsapp"}, schemas)
+
+	// a message older than the window isn't recorded
+	assert.NoError(t, w.Record(ctx, "bob", now.Add(-25*time.Hour)))
+	open, err = w.CanSendFreeform(ctx, "bob")
+	assert.NoError(t, err)
+	assert.False(t, open)
+
+	_, err = w.CanSendFreeform(ctx, "")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
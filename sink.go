@@ -0,0 +1,30 @@
+package smooch
+
+import "context"
+
+// EventSink receives every decoded webhook event, e.g. to forward it to a
+// message broker. The sinks package has sinks for Kafka and NATS.
+type EventSink interface {
+	Publish(ctx context.Context, event WebhookEvent) error
+}
+
+// EventSinkFunc adapts a function to EventSink.
+type EventSinkFunc func(ctx context.Context, event WebhookEvent) error
+
+func (f EventSinkFunc) Publish(ctx context.Context, event WebhookEvent) error {
+	return f(ctx, event)
+}
+
+// AddEventSink publishes the events of every webhook to sink, after the
+// typed handlers ran. Its errors are logged.
+func (ep *WebhookEndpoint) AddEventSink(sink EventSink) {
+	ep.sinks = append(ep.sinks, sink)
+}
+
+func (ep *WebhookEndpoint) publish(ctx context.Context, e WebhookEvent) {
+	for i, sink := range ep.sinks {
+		if err := sink.Publish(ctx, e); err != nil {
+			ep.logger.Errorw("event sink failed", "trigger", e.Base().Trigger, "sink", i, "err", err)
+		}
+	}
+}
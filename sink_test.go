@@ -0,0 +1,41 @@
+package smooch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventSink(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var published []WebhookEvent
+	sc.AddEventSink(EventSinkFunc(func(ctx context.Context, event WebhookEvent) error {
+		published = append(published, event)
+		return nil
+	}))
+	// a failing sink doesn't keep the others from publishing
+	sc.AddEventSink(EventSinkFunc(func(ctx context.Context, event WebhookEvent) error {
+		return errors.New("broker unavailable")
+	}))
+	sc.AddEventSink(EventSinkFunc(func(ctx context.Context, event WebhookEvent) error {
+		published = append(published, event)
+		return nil
+	}))
+
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(payloadExample1), payload))
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	assert.Len(t, published, 2)
+	event, ok := published[0].(*MessageAppUserEvent)
+	assert.True(t, ok)
+	assert.Equal(t, TriggerMessageAppUser, event.Base().Trigger)
+	assert.Len(t, event.Messages, 1)
+}
@@ -0,0 +1,79 @@
+// Package sinks forwards webhook events to message brokers. The sinks only
+// depend on small interfaces satisfied by the common client libraries, so
+// importing this package doesn't pull any broker client in.
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/EddyTravels/smooch"
+)
+
+// KafkaProducer writes a record to a Kafka topic. Wrap the producer of your
+// Kafka library in it, e.g. for segmentio/kafka-go:
+//
+//	type producer struct{ w *kafka.Writer }
+//
+//	func (p producer) Produce(ctx context.Context, topic string, key, value []byte) error {
+//		return p.w.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: value})
+//	}
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key []byte, value []byte) error
+}
+
+// KafkaSink publishes events as JSON to a Kafka topic. Records are keyed by
+// conversation, so the events of a conversation land on the same partition
+// and stay in order.
+type KafkaSink struct {
+	producer KafkaProducer
+	topic    string
+}
+
+func NewKafkaSink(producer KafkaProducer, topic string) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic}
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, event smooch.WebhookEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.producer.Produce(ctx, s.topic, []byte(partitionKey(event)), value)
+}
+
+// NATSPublisher publishes a message on a NATS subject. *nats.Conn satisfies
+// it.
+type NATSPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATSSink publishes events as JSON on the subject prefix.trigger, with
+// the colons of the trigger turned into dots, e.g. "smooch.message.appUser",
+// so subscribers can pick triggers with wildcards like "smooch.message.>".
+type NATSSink struct {
+	publisher NATSPublisher
+	prefix    string
+}
+
+func NewNATSSink(publisher NATSPublisher, prefix string) *NATSSink {
+	return &NATSSink{publisher: publisher, prefix: prefix}
+}
+
+func (s *NATSSink) Publish(ctx context.Context, event smooch.WebhookEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	subject := s.prefix + "." + strings.ReplaceAll(event.Base().Trigger, ":", ".")
+	return s.publisher.Publish(subject, data)
+}
+
+func partitionKey(event smooch.WebhookEvent) string {
+	e := event.Base()
+	if e.Conversation.ID != "" {
+		return e.Conversation.ID
+	}
+	return e.AppUser.ID
+}
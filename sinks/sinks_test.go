@@ -0,0 +1,74 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/EddyTravels/smooch"
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	topic string
+	key   string
+	value []byte
+}
+
+type producerFunc func(ctx context.Context, topic string, key []byte, value []byte) error
+
+func (f producerFunc) Produce(ctx context.Context, topic string, key []byte, value []byte) error {
+	return f(ctx, topic, key, value)
+}
+
+type publisherFunc func(subject string, data []byte) error
+
+func (f publisherFunc) Publish(subject string, data []byte) error {
+	return f(subject, data)
+}
+
+func newEvent() smooch.WebhookEvent {
+	return smooch.DecodeEvent(&smooch.Payload{
+		Trigger:      smooch.TriggerMessageAppUser,
+		AppUser:      smooch.AppUser{ID: "user"},
+		Conversation: smooch.Conversation{ID: "conv"},
+		Messages:     []*smooch.Message{{ID: "message", Text: "hi"}},
+	})
+}
+
+func TestKafkaSink(t *testing.T) {
+	var records []record
+	sink := NewKafkaSink(producerFunc(func(ctx context.Context, topic string, key []byte, value []byte) error {
+		records = append(records, record{topic: topic, key: string(key), value: value})
+		return nil
+	}), "smooch-events")
+
+	assert.NoError(t, sink.Publish(context.Background(), newEvent()))
+	assert.Len(t, records, 1)
+	assert.Equal(t, "smooch-events", records[0].topic)
+	assert.Equal(t, "conv", records[0].key)
+
+	var event smooch.MessageAppUserEvent
+	assert.NoError(t, json.Unmarshal(records[0].value, &event))
+	assert.Equal(t, smooch.TriggerMessageAppUser, event.Trigger)
+	assert.Equal(t, "message", event.Messages[0].ID)
+
+	// app users key the events without a conversation
+	assert.NoError(t, sink.Publish(context.Background(), smooch.DecodeEvent(&smooch.Payload{
+		Trigger: smooch.TriggerAppUserDelete,
+		AppUser: smooch.AppUser{ID: "user"},
+	})))
+	assert.Equal(t, "user", records[1].key)
+}
+
+func TestNATSSink(t *testing.T) {
+	var subjects []string
+	sink := NewNATSSink(publisherFunc(func(subject string, data []byte) error {
+		subjects = append(subjects, subject)
+		assert.True(t, json.Valid(data))
+		return nil
+	}), "smooch")
+
+	assert.NoError(t, sink.Publish(context.Background(), newEvent()))
+	assert.Equal(t, []string{"smooch.message.appUser"}, subjects)
+}
@@ -2,25 +2,64 @@ package smooch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	ErrUserIDEmpty       = errors.New("user id is empty")
-	ErrMessageNil        = errors.New("message is nil")
-	ErrMessageRoleEmpty  = errors.New("message.Role is empty")
-	ErrMessageTypeEmpty  = errors.New("message.Type is empty")
-	ErrVerifySecretEmpty = errors.New("verify secret is empty")
+	ErrUserIDEmpty            = errors.New("user id is empty")
+	ErrMessageNil             = errors.New("message is nil")
+	ErrMessageRoleEmpty       = errors.New("message.Role is empty")
+	ErrMessageTypeEmpty       = errors.New("message.Type is empty")
+	ErrVerifySecretEmpty      = errors.New("verify secret is empty")
+	ErrCircuitOpen            = errors.New("circuit breaker is open")
+	ErrRegionEmpty            = errors.New("region is empty")
+	ErrRegionUnknown          = errors.New("region is not registered")
+	ErrRegionURLInvalid       = errors.New("region root url is invalid")
+	ErrMessagesCursorConflict = errors.New("only one of before and after can be set")
+	ErrMessageIDEmpty         = errors.New("message id is empty")
+	ErrPropertiesEmpty        = errors.New("properties are empty")
+	ErrPropertyKeyEmpty       = errors.New("property key is empty")
+	ErrChannelTypeEmpty       = errors.New("channel type is empty")
+	ErrIntegrationIDsEmpty    = errors.New("integration ids are empty")
+	ErrClientIDEmpty          = errors.New("client id is empty")
+	ErrIntegrationIDEmpty     = errors.New("integration id is empty")
+	ErrIntegrationConfigNil   = errors.New("integration config is nil")
+	ErrAppIDEmpty             = errors.New("app id is empty")
+	ErrAppNameEmpty           = errors.New("app name is empty")
+	ErrAppNil                 = errors.New("app is nil")
+	ErrAppKeyIDEmpty          = errors.New("app key id is empty")
+	ErrAppKeyNameEmpty        = errors.New("app key name is empty")
+	ErrScopeInvalid           = errors.New("jwt scope is invalid")
+	ErrDeploymentIDEmpty      = errors.New("deployment id is empty")
+	ErrPhoneNumberEmpty       = errors.New("phone number is empty")
+	ErrVerificationCodeEmpty  = errors.New("verification code is empty")
+	ErrUploadTooLarge         = errors.New("upload exceeds the maximum upload size")
+	ErrMediaURLEmpty          = errors.New("media url is empty")
+	ErrMessageTextEmpty       = errors.New("message text is empty")
+	ErrTemplateNil            = errors.New("template is nil")
+	ErrTemplateNameEmpty      = errors.New("template name is empty")
+	ErrTemplateLanguageEmpty  = errors.New("template language is empty")
+	ErrPostbackActionEmpty    = errors.New("postback action is empty")
+	ErrJWTSigningMethod       = errors.New("unexpected jwt signing method")
+	ErrWebhookPathTaken       = errors.New("webhook path is already registered")
+	ErrConversationIDEmpty    = errors.New("conversation id is empty")
+	ErrAckModeWorkers         = errors.New("ack after handlers can't be used with webhook workers")
+	ErrScheduleIDEmpty        = errors.New("scheduled message id is empty")
+	ErrSessionWindowClosed    = errors.New("customer care window is closed")
 )
 
 const (
@@ -32,48 +71,238 @@ const (
 
 	contentTypeHeaderKey   = "Content-Type"
 	authorizationHeaderKey = "Authorization"
+	requestIDHeaderKey     = "X-Request-Id"
+	webhookSecretHeaderKey = "X-Api-Key"
+
+	attachmentTokenQueryKey = "jwt"
 
 	contentTypeJSON = "application/json"
 )
 
 type Options struct {
-	AppID        string
-	KeyID        string
-	Secret       string
-	VerifySecret string
-	WebhookURL   string
-	Mux          *http.ServeMux
-	Logger       Logger
-	Region       string
-	HttpClient   *http.Client
+	AppID          string
+	KeyID          string
+	Secret         string
+	Scope          string
+	VerifySecret   string
+	WebhookURL     string
+	Mux            *http.ServeMux
+	Logger         Logger
+	Region         string
+	HttpClient     *http.Client
+	RetryPolicy    RetryPolicy
+	CircuitBreaker *CircuitBreaker
+	// RateLimit, when set, holds API calls back to stay under the rate.
+	// ChannelRateLimits adds rates for the calls made with
+	// WithRateLimitChannel, e.g. to keep bulk sends on a channel from
+	// starving the others.
+	RateLimit         *Rate
+	ChannelRateLimits map[string]Rate
+	// AppUserCache, when set, serves GetAppUser and its variants from the
+	// cache. Cached users come without ResponseData.
+	AppUserCache   *AppUserCache
+	Middlewares    []Middleware
+	TracerProvider trace.TracerProvider
+	Debug          bool
+	MaxUploadSize  int64
+	// WebhookBasicAuth, when set, requires webhook requests to carry these
+	// basic credentials, as configured in the webhook url.
+	WebhookBasicAuth *BasicAuth
+	// VerifyWebhookJWT accepts webhook requests with an Authorization bearer
+	// token signed with WebhookJWTSecret, or Secret when it is empty, in
+	// place of the X-Api-Key header. VerifySecret is optional then.
+	VerifyWebhookJWT bool
+	WebhookJWTSecret string
+	// WebhookDeduplicator, when set, drops webhooks that were already
+	// dispatched.
+	WebhookDeduplicator *Deduplicator
+	// WebhookWorkers and WebhookQueueSize set WebhookEndpointOptions.Workers
+	// and WebhookEndpointOptions.QueueSize of the webhook endpoint.
+	WebhookWorkers   int
+	WebhookQueueSize int
+	// WebhookEventBufferSize and WebhookOverflowPolicy set
+	// WebhookEndpointOptions.EventBufferSize and
+	// WebhookEndpointOptions.OverflowPolicy of the webhook endpoint.
+	WebhookEventBufferSize int
+	WebhookOverflowPolicy  OverflowPolicy
+	// WebhookAuditLog sets WebhookEndpointOptions.AuditLog of the webhook
+	// endpoint.
+	WebhookAuditLog *AuditLog
+	// WebhookAckAfterHandlers sets WebhookEndpointOptions.AckAfterHandlers
+	// of the webhook endpoint.
+	WebhookAckAfterHandlers bool
 }
 
 type WebhookEventHandler func(payload *Payload)
 
+// WebhookEventHandlerE is a webhook handler that can fail. Its errors are
+// passed to the OnHandlerError callback.
+type WebhookEventHandlerE func(payload *Payload) error
+
+// WebhookContextHandler is a webhook handler receiving the context of the
+// webhook request, which carries its tracing span, and the request
+// metadata. Its errors are passed to the OnHandlerError callback.
+type WebhookContextHandler func(ctx context.Context, payload *Payload, meta WebhookMeta) error
+
 type Client interface {
 	Handler() http.Handler
+	WebhookHandlerFunc() http.HandlerFunc
+	AddWebhookEndpoint(path string, o WebhookEndpointOptions) (*WebhookEndpoint, error)
+	Close(ctx context.Context) error
 	AddWebhookEventHandler(handler WebhookEventHandler)
-	Send(userID string, message *Message) (*ResponsePayload, error)
+	AddWebhookEventHandlerE(handler WebhookEventHandlerE)
+	AddWebhookContextHandler(handler WebhookContextHandler)
+	OnHandlerError(fn func(payload *Payload, index int, err error))
+	AddEventSink(sink EventSink)
+	Events() <-chan *Payload
+	Replay(ctx context.Context, e *AuditEntry) error
+	OnMessageAppUser(fn func(e *MessageAppUserEvent))
+	OnMessageAppMaker(fn func(e *MessageAppMakerEvent))
+	OnDelivery(fn func(e *DeliveryEvent))
+	OnDeliveryFailure(fn func(e *DeliveryFailureEvent))
+	OnPostback(fn func(e *PostbackEvent))
+	OnConversationStart(fn func(e *ConversationStartEvent))
+	OnConversationReferral(fn func(e *ConversationReferralEvent))
+	OnConversationRead(fn func(e *ConversationReadEvent))
+	OnTyping(fn func(e *TypingEvent))
+	OnLink(fn func(e *LinkEvent))
+	OnLinkFailure(fn func(e *LinkFailureEvent))
+	OnPaymentSuccess(fn func(e *PaymentEvent))
+	OnClientAdd(fn func(e *ClientEvent))
+	OnClientRemove(fn func(e *ClientEvent))
+	OnAppUserDelete(fn func(e *AppUserDeleteEvent))
+	Send(userID string, message *Message, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendText(userID string, text string, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendImage(userID string, mediaURL string, caption string, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendFile(userID string, mediaURL string, caption string, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendLocation(userID string, lat float64, long float64, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendQuickReplies(userID string, text string, replies []*Action, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendTemplate(userID string, template *WhatsAppTemplate, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendInteractive(userID string, interactive *WhatsAppInteractive, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendFileMessage(userID string, r io.Reader, filename string, mimeType string, caption string, opts ...RequestOption) (*ResponsePayload, *ResponseData, error)
+	SendBatch(userIDs []string, message *Message, o BatchOptions, opts ...RequestOption) BatchResults
 	VerifyRequest(r *http.Request) bool
-	GetAppUser(userID string) (*AppUser, error)
-	UploadFileAttachment(filepath string, upload AttachmentUpload) (*Attachment, error)
-	UploadAttachment(r io.Reader, upload AttachmentUpload) (*Attachment, error)
+	GetAppUser(userID string, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	GetAppUserByID(appUserID string, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	GetAppUserByExternalID(userID string, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	PreCreateAppUser(user AppUserCreate, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	UpdateAppUser(userID string, update AppUserUpdate, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	SetAppUserProperties(userID string, properties map[string]interface{}, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	DeleteAppUserProperty(userID string, key string, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	DeleteAppUser(userID string, opts ...RequestOption) (*ResponseData, error)
+	DeleteAppUserProfile(userID string, opts ...RequestOption) (*AppUser, *ResponseData, error)
+	CreateUser(user UserCreate, opts ...RequestOption) (*User, *ResponseData, error)
+	GetUser(userID string, opts ...RequestOption) (*User, *ResponseData, error)
+	UpdateUser(userID string, update UserUpdate, opts ...RequestOption) (*User, *ResponseData, error)
+	DeleteUser(userID string, opts ...RequestOption) (*ResponseData, error)
+	DeleteUserPersonalInformation(userID string, opts ...RequestOption) (*User, *ResponseData, error)
+	ListUserDevices(userID string, opts ...RequestOption) ([]*Device, *ResponseData, error)
+	ListUserClients(userID string, opts ...RequestOption) ([]*UserClient, *ResponseData, error)
+	LinkUserClient(userID string, link ClientLink, opts ...RequestOption) (*UserClient, *ResponseData, error)
+	UnlinkUserClient(userID string, clientID string, opts ...RequestOption) (*ResponseData, error)
+	MergeUsers(survivingID string, discardedID string, opts ...RequestOption) (*User, *ResponseData, error)
+	GetAppUserChannels(userID string, opts ...RequestOption) ([]*ChannelEntity, *ResponseData, error)
+	UnlinkAppUserChannel(userID string, channelType string, opts ...RequestOption) (*ResponseData, error)
+	GetLinkRequests(userID string, integrationIDs []string, opts ...RequestOption) ([]*LinkRequest, *ResponseData, error)
+	AddAppUserClient(userID string, client ClientCreate, opts ...RequestOption) (*AppUserClient, *ResponseData, error)
+	RemoveAppUserClient(userID string, clientID string, opts ...RequestOption) (*ResponseData, error)
+	GetMessages(userID string, params GetMessagesParams, opts ...RequestOption) (*GetMessagesResponse, *ResponseData, error)
+	DeleteMessage(userID string, messageID string, opts ...RequestOption) (*ResponseData, error)
+	DeleteConversationHistory(userID string, opts ...RequestOption) (*ResponseData, error)
+	CreateConversation(conversation ConversationCreate, opts ...RequestOption) (*ConversationEntity, *ResponseData, error)
+	ListConversations(userID string, opts ...RequestOption) ([]*ConversationEntity, *ResponseData, error)
+	GetConversation(conversationID string, opts ...RequestOption) (*ConversationEntity, *ResponseData, error)
+	UpdateConversationMetadata(conversationID string, metadata Metadata, opts ...RequestOption) (*ConversationEntity, *ResponseData, error)
+	SendToConversation(conversationID string, message *Message, opts ...RequestOption) ([]*ConversationMessage, *ResponseData, error)
+	MessageIterator(userID string, params GetMessagesParams, opts ...RequestOption) *MessageIterator
+	ForEachMessage(userID string, params GetMessagesParams, fn func(m *Message) error, opts ...RequestOption) error
+	ListIntegrations(types []string, opts ...RequestOption) ([]*Integration, *ResponseData, error)
+	GetIntegration(integrationID string, opts ...RequestOption) (*Integration, *ResponseData, error)
+	CreateIntegration(displayName string, config IntegrationConfig, opts ...RequestOption) (*Integration, *ResponseData, error)
+	UpdateIntegration(integrationID string, displayName string, config IntegrationConfig, opts ...RequestOption) (*Integration, *ResponseData, error)
+	DeleteIntegration(integrationID string, opts ...RequestOption) (*ResponseData, error)
+	ListMessageTemplates(integrationID string, params ListMessageTemplatesParams, opts ...RequestOption) (*ListMessageTemplatesResponse, *ResponseData, error)
+	UploadFileAttachment(filepath string, upload AttachmentUpload, opts ...RequestOption) (*Attachment, *ResponseData, error)
+	UploadAttachment(r io.Reader, upload AttachmentUpload, opts ...RequestOption) (*Attachment, *ResponseData, error)
+	ListAttachments(params ListAttachmentsParams, opts ...RequestOption) (*ListAttachmentsResponse, *ResponseData, error)
+	DeleteAttachment(attachment *Attachment, opts ...RequestOption) (*ResponseData, error)
+	SignAttachmentURL(mediaURL string, ttl time.Duration) (string, error)
+	Do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}, opts ...RequestOption) (*ResponseData, error)
 }
 
 type smoochClient struct {
-	mux                  *http.ServeMux
-	appID                string
-	jwtToken             string
-	verifySecret         string
-	logger               Logger
-	region               string
-	webhookEventHandlers []WebhookEventHandler
-	httpClient           *http.Client
+	*WebhookEndpoint
+
+	mux              *http.ServeMux
+	appID            string
+	jwtToken         string
+	scope            string
+	keyID            string
+	secret           string
+	logger           Logger
+	region           string
+	rootURL          string
+	httpClient       *http.Client
+	retryPolicy      RetryPolicy
+	circuitBreaker   *CircuitBreaker
+	rateLimiter      *rateLimiter
+	appUserCache     *AppUserCache
+	tracer           trace.Tracer
+	maxUploadSize    int64
+	webhookEndpoints map[string]*WebhookEndpoint
 }
 
 func New(o Options) (*smoochClient, error) {
-	if o.VerifySecret == "" {
-		return nil, ErrVerifySecretEmpty
+	endpointOptions := WebhookEndpointOptions{
+		VerifySecret:     o.VerifySecret,
+		BasicAuth:        o.WebhookBasicAuth,
+		Deduplicator:     o.WebhookDeduplicator,
+		Workers:          o.WebhookWorkers,
+		QueueSize:        o.WebhookQueueSize,
+		EventBufferSize:  o.WebhookEventBufferSize,
+		OverflowPolicy:   o.WebhookOverflowPolicy,
+		AuditLog:         o.WebhookAuditLog,
+		AckAfterHandlers: o.WebhookAckAfterHandlers,
+	}
+	if o.VerifyWebhookJWT {
+		endpointOptions.JWTSecret = o.WebhookJWTSecret
+		if endpointOptions.JWTSecret == "" {
+			endpointOptions.JWTSecret = o.Secret
+		}
+	}
+
+	if o.WebhookURL == "" {
+		o.WebhookURL = "/"
+	}
+
+	if o.Scope == "" {
+		o.Scope = JWTScopeApp
+	}
+
+	sc, err := newClient(o)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.WebhookEndpoint, err = sc.AddWebhookEndpoint(o.WebhookURL, endpointOptions)
+	if err != nil {
+		return nil, err
+	}
+	if sc.appUserCache != nil {
+		sc.on(sc.invalidateAppUserEvent,
+			TriggerAppUserDelete,
+			TriggerAppUserMerge,
+			TriggerClientAdd,
+			TriggerClientRemove,
+			TriggerLinkSuccess,
+		)
+	}
+	return sc, nil
+}
+
+func newClient(o Options) (*smoochClient, error) {
+	if !validScope(o.Scope) {
+		return nil, ErrScopeInvalid
 	}
 
 	if o.Mux == nil {
@@ -88,35 +317,52 @@ func New(o Options) (*smoochClient, error) {
 		o.Region = RegionUS
 	}
 
-	if o.WebhookURL == "" {
-		o.WebhookURL = "/"
-	}
-
 	if o.Logger == nil {
 		o.Logger = &nopLogger{}
 	}
 
-	region := RegionUS
-	if o.Region == "EU" {
-		region = RegionEU
+	if o.RetryPolicy == nil {
+		o.RetryPolicy = NoRetryPolicy{}
+	}
+
+	if o.MaxUploadSize == 0 {
+		o.MaxUploadSize = defaultMaxUploadSize
+	}
+
+	if o.Debug {
+		// innermost, so the dump shows what actually goes over the wire
+		o.Middlewares = append(append([]Middleware{}, o.Middlewares...), debugMiddleware(o.Logger))
 	}
 
-	jwtToken, err := GenerateJWT("app", o.KeyID, o.Secret)
+	rootURL, ok := RegionRootURL(o.Region)
+	if !ok {
+		return nil, ErrRegionUnknown
+	}
+
+	jwtToken, err := GenerateJWT(o.Scope, o.KeyID, o.Secret)
 	if err != nil {
 		return nil, err
 	}
 
 	sc := &smoochClient{
-		mux:          o.Mux,
-		appID:        o.AppID,
-		verifySecret: o.VerifySecret,
-		logger:       o.Logger,
-		region:       region,
-		httpClient:   o.HttpClient,
-		jwtToken:     jwtToken,
+		mux:              o.Mux,
+		appID:            o.AppID,
+		logger:           o.Logger,
+		region:           strings.ToUpper(o.Region),
+		rootURL:          rootURL,
+		httpClient:       chainMiddlewares(o.HttpClient, o.Middlewares),
+		jwtToken:         jwtToken,
+		scope:            o.Scope,
+		keyID:            o.KeyID,
+		secret:           o.Secret,
+		retryPolicy:      o.RetryPolicy,
+		circuitBreaker:   o.CircuitBreaker,
+		rateLimiter:      newRateLimiter(o.RateLimit, o.ChannelRateLimits),
+		appUserCache:     o.AppUserCache,
+		tracer:           newTracer(o.TracerProvider),
+		maxUploadSize:    o.MaxUploadSize,
+		webhookEndpoints: map[string]*WebhookEndpoint{},
 	}
-
-	sc.mux.HandleFunc(o.WebhookURL, sc.handle)
 	return sc, nil
 }
 
@@ -124,88 +370,515 @@ func (sc *smoochClient) Handler() http.Handler {
 	return sc.mux
 }
 
-func (sc *smoochClient) AddWebhookEventHandler(handler WebhookEventHandler) {
-	sc.webhookEventHandlers = append(sc.webhookEventHandlers, handler)
-}
-
-func (sc *smoochClient) Send(userID string, message *Message) (*ResponsePayload, error) {
+func (sc *smoochClient) Send(userID string, message *Message, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
 	if userID == "" {
-		return nil, ErrUserIDEmpty
+		return nil, nil, ErrUserIDEmpty
 	}
 
 	if message == nil {
-		return nil, ErrMessageNil
+		return nil, nil, ErrMessageNil
 	}
 
-	if message.Role == "" {
-		return nil, ErrMessageRoleEmpty
+	// fail before the request for what the API would reject
+	if err := message.Validate(); err != nil {
+		return nil, nil, err
 	}
 
-	if message.Type == "" {
-		return nil, ErrMessageTypeEmpty
+	ro := newRequestOptions(opts)
+	if messages := ro.splitMessage(message); messages != nil {
+		return sc.sendChunks(userID, messages, ro, opts)
 	}
 
 	url := sc.getURL(
-		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/messages", sc.appID, userID),
-		nil,
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/messages", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
 	)
 
 	buf := new(bytes.Buffer)
 	err := json.NewEncoder(buf).Encode(message)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var responsePayload ResponsePayload
+	respData, err := sc.sendRequest(req, &responsePayload)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return &responsePayload, respData, nil
+}
+
+// GetAppUser fetches an app user by either its Smooch _id or its external
+// userId; Smooch resolves both on the same endpoint. Prefer GetAppUserByID or
+// GetAppUserByExternalID when the kind of identifier is known.
+func (sc *smoochClient) GetAppUser(userID string, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	return sc.getAppUser(userID, opts)
+}
+
+// GetAppUserByID fetches an app user by its Smooch _id, which is what webhook
+// payloads carry in Payload.AppUser.ID and Message.AuthorID.
+func (sc *smoochClient) GetAppUserByID(appUserID string, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	return sc.getAppUser(appUserID, opts)
+}
+
+// GetAppUserByExternalID fetches an app user by the userId assigned by your
+// own system, which webhook payloads carry in Payload.AppUser.UserID. The id
+// is escaped, so e-mail addresses and other ids with reserved characters are
+// safe to pass.
+func (sc *smoochClient) GetAppUserByExternalID(userID string, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	return sc.getAppUser(userID, opts)
+}
+
+func (sc *smoochClient) getAppUser(userID string, opts []RequestOption) (*AppUser, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	if sc.appUserCache != nil {
+		appUser, ok, err := sc.appUserCache.Get(context.Background(), userID)
+		if err != nil {
+			sc.logger.Errorw("app user cache lookup failed", "userId", userID, "err", err)
+		} else if ok {
+			return appUser, nil, nil
+		}
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response GetAppUserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	if sc.appUserCache != nil && response.AppUser != nil {
+		if err := sc.appUserCache.Put(context.Background(), response.AppUser); err != nil {
+			sc.logger.Errorw("app user cache update failed", "userId", userID, "err", err)
+		}
+	}
+
+	return response.AppUser, respData, nil
+}
+
+// PreCreateAppUser creates an app user before it has talked to the app, so
+// its profile is in place once it starts a conversation. Only UserID is
+// required.
+func (sc *smoochClient) PreCreateAppUser(user AppUserCreate, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	if user.UserID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers", sc.appID),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response GetAppUserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.AppUser, respData, nil
+}
+
+// UpdateAppUser updates the profile of an app user. Only the fields set on
+// update are changed; properties are merged into the existing ones.
+func (sc *smoochClient) UpdateAppUser(userID string, update AppUserUpdate, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(update)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPut, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response GetAppUserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return response.AppUser, respData, nil
+}
+
+// SetAppUserProperties merges properties into the custom properties of an
+// app user. Properties that are not mentioned are left untouched.
+func (sc *smoochClient) SetAppUserProperties(userID string, properties map[string]interface{}, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	if len(properties) == 0 {
+		return nil, nil, ErrPropertiesEmpty
+	}
+
+	return sc.UpdateAppUser(userID, AppUserUpdate{Properties: properties}, opts...)
+}
+
+// DeleteAppUserProperty removes a custom property from an app user. Smooch
+// deletes a property when it is updated to null.
+func (sc *smoochClient) DeleteAppUserProperty(userID string, key string, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	if key == "" {
+		return nil, nil, ErrPropertyKeyEmpty
+	}
+
+	properties := map[string]interface{}{
+		key: nil,
+	}
+	return sc.UpdateAppUser(userID, AppUserUpdate{Properties: properties}, opts...)
+}
+
+func (sc *smoochClient) DeleteAppUser(userID string, opts ...RequestOption) (*ResponseData, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := sc.createRequest(http.MethodPost, url, buf, nil)
+	respData, err := sc.sendRequest(req, nil)
+	if err != nil {
+		return respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return respData, nil
+}
+
+// DeleteAppUserProfile scrubs the personal information of an app user (name,
+// email, properties, client info) while keeping the user and conversation.
+func (sc *smoochClient) DeleteAppUserProfile(userID string, opts ...RequestOption) (*AppUser, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/profile", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response GetAppUserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return response.AppUser, respData, nil
+}
+
+// GetAppUserChannels lists the channels the app user is reachable on.
+func (sc *smoochClient) GetAppUserChannels(userID string, opts ...RequestOption) ([]*ChannelEntity, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/channels", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response GetAppUserChannelsResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Channels, respData, nil
+}
+
+// UnlinkAppUserChannel removes the link between the app user and a channel,
+// e.g. SourceTypeWhatsApp.
+func (sc *smoochClient) UnlinkAppUserChannel(userID string, channelType string, opts ...RequestOption) (*ResponseData, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	if channelType == "" {
+		return nil, ErrChannelTypeEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/channels/%s", sc.appID, url.PathEscape(userID), url.PathEscape(channelType)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
 	if err != nil {
 		return nil, err
 	}
 
-	var responsePayload ResponsePayload
-	err = sc.sendRequest(req, &responsePayload)
+	respData, err := sc.sendRequest(req, nil)
+	if err != nil {
+		return respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return respData, nil
+}
+
+// GetLinkRequests generates link request codes and URLs that let the app
+// user connect the given integrations, e.g. a Messenger m.me link.
+func (sc *smoochClient) GetLinkRequests(userID string, integrationIDs []string, opts ...RequestOption) ([]*LinkRequest, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	if len(integrationIDs) == 0 {
+		return nil, nil, ErrIntegrationIDsEmpty
+	}
+
+	queryParams := url.Values{
+		"integrationIds": []string{strings.Join(integrationIDs, ",")},
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/linkrequest", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(queryParams),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response GetLinkRequestsResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.LinkRequests, respData, nil
+}
+
+// AddAppUserClient registers a device, e.g. with its push notification
+// token, for the app user.
+func (sc *smoochClient) AddAppUserClient(userID string, client ClientCreate, opts ...RequestOption) (*AppUserClient, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	if client.ID == "" {
+		return nil, nil, ErrClientIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/clients", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ClientResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return response.Client, respData, nil
+}
+
+// RemoveAppUserClient removes a device of the app user, e.g. when its push
+// notification token went stale. clientID is the Smooch _id of the client.
+func (sc *smoochClient) RemoveAppUserClient(userID string, clientID string, opts ...RequestOption) (*ResponseData, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	if clientID == "" {
+		return nil, ErrClientIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/clients/%s", sc.appID, url.PathEscape(userID), url.PathEscape(clientID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
 	if err != nil {
 		return nil, err
 	}
 
-	return &responsePayload, nil
+	respData, err := sc.sendRequest(req, nil)
+	if err != nil {
+		return respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return respData, nil
 }
 
-func (sc *smoochClient) VerifyRequest(r *http.Request) bool {
-	givenSecret := r.Header.Get("X-Api-Key")
-	return sc.verifySecret == givenSecret
+func (sc *smoochClient) GetMessages(userID string, params GetMessagesParams, opts ...RequestOption) (*GetMessagesResponse, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	if params.Before != "" && params.After != "" {
+		return nil, nil, ErrMessagesCursorConflict
+	}
+
+	queryParams := url.Values{}
+	if params.Before != "" {
+		queryParams.Set("before", params.Before)
+	}
+	if params.After != "" {
+		queryParams.Set("after", params.After)
+	}
+	if params.Limit > 0 {
+		queryParams.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/messages", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(queryParams),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response GetMessagesResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return &response, respData, nil
 }
 
-func (sc *smoochClient) GetAppUser(userID string) (*AppUser, error) {
+func (sc *smoochClient) DeleteMessage(userID string, messageID string, opts ...RequestOption) (*ResponseData, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	if messageID == "" {
+		return nil, ErrMessageIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
 	url := sc.getURL(
-		fmt.Sprintf("/v1.1/apps/%s/appusers/%s", sc.appID, userID),
-		nil,
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/messages/%s", sc.appID, url.PathEscape(userID), url.PathEscape(messageID)),
+		ro.queryParams(nil),
 	)
 
-	req, err := sc.createRequest(http.MethodGet, url, nil, nil)
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
 	if err != nil {
 		return nil, err
 	}
 
-	var response GetAppUserResponse
-	err = sc.sendRequest(req, &response)
+	return sc.sendRequest(req, nil)
+}
+
+// DeleteConversationHistory deletes all messages of the user's conversation.
+func (sc *smoochClient) DeleteConversationHistory(userID string, opts ...RequestOption) (*ResponseData, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/messages", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
 	if err != nil {
 		return nil, err
 	}
 
-	return response.AppUser, nil
+	return sc.sendRequest(req, nil)
 }
 
-func (sc *smoochClient) UploadFileAttachment(filepath string, upload AttachmentUpload) (*Attachment, error) {
+func (sc *smoochClient) UploadFileAttachment(filepath string, upload AttachmentUpload, opts ...RequestOption) (*Attachment, *ResponseData, error) {
 	r, err := os.Open(filepath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer r.Close()
 
-	return sc.UploadAttachment(r, upload)
+	return sc.UploadAttachment(r, upload, opts...)
 
 }
-func (sc *smoochClient) UploadAttachment(r io.Reader, upload AttachmentUpload) (*Attachment, error) {
+func (sc *smoochClient) UploadAttachment(r io.Reader, upload AttachmentUpload, opts ...RequestOption) (*Attachment, *ResponseData, error) {
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	ro := newRequestOptions(opts)
 
 	queryParams := url.Values{
 		"access": []string{upload.Access},
@@ -222,98 +895,175 @@ func (sc *smoochClient) UploadAttachment(r io.Reader, upload AttachmentUpload) (
 
 	url := sc.getURL(
 		fmt.Sprintf("/v1.1/apps/%s/attachments", sc.appID),
-		queryParams,
+		ro.queryParams(queryParams),
 	)
 
+	if upload.MIMEType == "" {
+		var err error
+		upload.MIMEType, r, err = detectMIMEType(r)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if upload.Channel != "" {
+		err := ValidateAttachment(upload.Channel, upload.MIMEType, readerSize(r))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	formData := map[string]io.Reader{
 		"source": r,
 		"type":   strings.NewReader(upload.MIMEType),
 	}
 
-	req, err := sc.createMultipartRequest(url, formData)
+	req, err := sc.createMultipartRequest(url, formData, ro)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var response Attachment
-	err = sc.sendRequest(req, &response)
+	respData, err := sc.sendRequest(req, &response)
 	if err != nil {
-		return nil, err
+		return nil, respData, err
+	}
+
+	return &response, respData, nil
+}
+
+// ListAttachments lists a page of the attachments uploaded to the app. Keep
+// requesting the next Offset while HasMore is true to go through all of them.
+func (sc *smoochClient) ListAttachments(params ListAttachmentsParams, opts ...RequestOption) (*ListAttachmentsResponse, *ResponseData, error) {
+	queryParams := url.Values{}
+	if params.Limit > 0 {
+		queryParams.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Offset > 0 {
+		queryParams.Set("offset", strconv.Itoa(params.Offset))
+	}
+	if params.AppUserID != "" {
+		queryParams.Set("appUserId", params.AppUserID)
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/attachments", sc.appID),
+		ro.queryParams(queryParams),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListAttachmentsResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
 	}
 
-	return &response, nil
+	return &response, respData, nil
 }
 
-func (sc *smoochClient) DeleteAttachment(attachment *Attachment) error {
+func (sc *smoochClient) DeleteAttachment(attachment *Attachment, opts ...RequestOption) (*ResponseData, error) {
+	ro := newRequestOptions(opts)
 	url := sc.getURL(
 		fmt.Sprintf("/v1.1/apps/%s/attachments", sc.appID),
-		nil,
+		ro.queryParams(nil),
 	)
 
 	buf := new(bytes.Buffer)
 	err := json.NewEncoder(buf).Encode(attachment)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := sc.createRequest(http.MethodPost, url, buf, nil)
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = sc.sendRequest(req, nil)
+	respData, err := sc.sendRequest(req, nil)
 	if err != nil {
-		return err
+		return respData, err
 	}
 
-	return nil
+	return respData, nil
 }
 
-func (sc *smoochClient) handle(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	if r.Method != http.MethodPost || !sc.VerifyRequest(r) {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+// SignAttachmentURL returns mediaURL with a token that grants access to a
+// private attachment for ttl. The token is signed with the client's key, so
+// only hand the URL to whoever should see the attachment.
+func (sc *smoochClient) SignAttachmentURL(mediaURL string, ttl time.Duration) (string, error) {
+	if mediaURL == "" {
+		return "", ErrMediaURLEmpty
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	u, err := url.Parse(mediaURL)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		sc.logger.Errorw("request body read failed", "err", err)
-		return
+		return "", err
 	}
 
-	var payload Payload
-	err = json.Unmarshal(body, &payload)
+	token, err := generateExpiringJWT(sc.scope, sc.keyID, sc.secret, ttl)
 	if err != nil {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		sc.logger.Errorw("could not decode response", "err", err)
-		return
+		return "", err
 	}
 
-	w.WriteHeader(http.StatusOK)
-
-	sc.dispatch(&payload)
+	query := u.Query()
+	query.Set(attachmentTokenQueryKey, token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
 }
 
-func (sc *smoochClient) dispatch(p *Payload) {
-	for _, handler := range sc.webhookEventHandlers {
-		handler(p)
+// Do calls an arbitrary API endpoint, for endpoints this package does not
+// wrap yet. path is relative to the region root URL, e.g.
+// "/v1.1/apps/{appId}/integrations". body, when not nil, is sent as JSON and
+// a successful response is decoded into out, when not nil.
+func (sc *smoochClient) Do(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	body interface{},
+	out interface{},
+	opts ...RequestOption) (*ResponseData, error) {
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(path, ro.queryParams(query))
+
+	var buf *bytes.Buffer
+	if body != nil {
+		buf = new(bytes.Buffer)
+		err := json.NewEncoder(buf).Encode(body)
+		if err != nil {
+			return nil, err
+		}
 	}
-}
 
-func (sc *smoochClient) getURL(endpoint string, values url.Values) string {
-	rootURL := usRootURL
-	if sc.region == RegionEU {
-		rootURL = euRootURL
+	ro.ctx = ctx
+	req, err := sc.createRequest(method, url, buf, ro)
+	if err != nil {
+		return nil, err
 	}
 
-	u, err := url.Parse(rootURL)
+	return sc.sendRequest(req, out)
+}
+
+func (sc *smoochClient) getURL(endpoint string, values url.Values) string {
+	u, err := url.Parse(sc.rootURL)
 	if err != nil {
 		panic(err)
 	}
 
-	u.Path = path.Join(u.Path, endpoint)
+	// endpoint may contain escaped path segments, e.g. an e-mail as user id
+	escapedPath := path.Join(u.Path, endpoint)
+	u.Path, err = url.PathUnescape(escapedPath)
+	if err != nil {
+		u.Path = escapedPath
+	} else {
+		u.RawPath = escapedPath
+	}
 	if len(values) > 0 {
 		u.RawQuery = values.Encode()
 	}
@@ -324,12 +1074,12 @@ func (sc *smoochClient) createRequest(
 	method string,
 	url string,
 	buf *bytes.Buffer,
-	header http.Header) (*http.Request, error) {
+	ro *requestOptions) (*http.Request, error) {
 
-	if header == nil {
-		header = http.Header{}
+	if ro == nil {
+		ro = newRequestOptions(nil)
 	}
-
+	header := ro.header
 	if header.Get(contentTypeHeaderKey) == "" {
 		header.Set(contentTypeHeaderKey, contentTypeJSON)
 	}
@@ -338,9 +1088,9 @@ func (sc *smoochClient) createRequest(
 	var req *http.Request
 	var err error
 	if buf == nil {
-		req, err = http.NewRequest(method, url, nil)
+		req, err = http.NewRequestWithContext(ro.context(), method, url, nil)
 	} else {
-		req, err = http.NewRequest(method, url, buf)
+		req, err = http.NewRequestWithContext(ro.context(), method, url, buf)
 	}
 	if err != nil {
 		return nil, err
@@ -350,65 +1100,152 @@ func (sc *smoochClient) createRequest(
 	return req, nil
 }
 
+// createMultipartRequest streams values as multipart/form-data. Fields that
+// are files are sent with their base name.
 func (sc *smoochClient) createMultipartRequest(
 	url string,
-	values map[string]io.Reader) (*http.Request, error) {
-	buf := new(bytes.Buffer)
-	w := multipart.NewWriter(buf)
-	var err error
-
-	for key, r := range values {
-		var fw io.Writer
-		if x, ok := r.(io.Closer); ok {
-			defer x.Close()
-		}
-		// Add an image file
-		if x, ok := r.(*os.File); ok {
-			if fw, err = w.CreateFormFile(key, x.Name()); err != nil {
-				return nil, err
-			}
-		} else if fbr, ok := r.(*BytesFileReader); ok {
-			if fw, err = w.CreateFormFile(key, fbr.Filename); err != nil {
-				return nil, err
-			}
-		} else {
-			// Add other fields
-			if fw, err = w.CreateFormField(key); err != nil {
-				return nil, err
-			}
-		}
+	values map[string]io.Reader,
+	ro *requestOptions) (*http.Request, error) {
+	body, err := newMultipartBody(values, sc.maxUploadSize)
+	if err != nil {
+		return nil, err
+	}
 
-		if _, err = io.Copy(fw, r); err != nil {
-			return nil, err
-		}
+	if ro == nil {
+		ro = newRequestOptions(nil)
 	}
-	w.Close()
+	ro.header.Set(contentTypeHeaderKey, body.contentType())
 
-	header := http.Header{}
-	header.Set("Content-Type", w.FormDataContentType())
+	req, err := sc.createRequest(http.MethodPost, url, nil, ro)
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := sc.createRequest(http.MethodPost, url, buf, header)
+	req.Body, err = body.reader()
 	if err != nil {
 		return nil, err
 	}
+	if body.size >= 0 {
+		req.ContentLength = body.size
+	}
+	if body.seekable {
+		req.GetBody = body.reader
+	}
 	return req, nil
 }
 
-func (sc *smoochClient) sendRequest(req *http.Request, v interface{}) error {
-	response, err := sc.httpClient.Do(req)
+func (sc *smoochClient) sendRequest(req *http.Request, v interface{}) (respData *ResponseData, err error) {
+	req, span := sc.startRequestSpan(req)
+	var response *http.Response
+	start := time.Now()
+	defer func() {
+		sc.logRequest(req, response, err, time.Since(start))
+		endRequestSpan(span, response, err)
+	}()
+
+	// waited for before the breaker lets the call through, so a canceled
+	// wait can't leave a half-open breaker without its probe
+	channel := rateLimitChannel(req.Context())
+	if err := sc.waitRateLimit(req, channel); err != nil {
+		return nil, err
+	}
+	if sc.circuitBreaker != nil {
+		if err := sc.circuitBreaker.Allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err = sc.doWithRetry(req, channel)
+	if sc.circuitBreaker != nil {
+		sc.circuitBreaker.Record(response, err)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer response.Body.Close()
 
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	respData = newResponseData(response, body)
+
 	if response.StatusCode >= 200 && response.StatusCode < 300 {
 		if v != nil {
-			err := json.NewDecoder(response.Body).Decode(&v)
+			err := json.Unmarshal(body, &v)
+			if err != nil {
+				return respData, err
+			}
+		}
+		return respData, nil
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	err = checkSmoochError(response)
+	if smoochErr, ok := err.(*SmoochError); ok {
+		smoochErr.responseData = respData
+	}
+	return respData, err
+}
+
+func (sc *smoochClient) logRequest(req *http.Request, response *http.Response, err error, latency time.Duration) {
+	keysAndValues := []interface{}{
+		"method", req.Method,
+		"endpoint", req.URL.Path,
+		"latency", latency,
+	}
+	if response != nil {
+		keysAndValues = append(keysAndValues, "status", response.StatusCode)
+	}
+
+	if err == nil {
+		sc.logger.Infow("smooch api call", keysAndValues...)
+		return
+	}
+
+	if smoochErr, ok := err.(*SmoochError); ok {
+		keysAndValues = append(keysAndValues, "code", smoochErr.ErrorCode())
+	}
+	keysAndValues = append(keysAndValues, "err", err)
+	sc.logger.Errorw("smooch api call failed", keysAndValues...)
+}
+
+func (sc *smoochClient) doWithRetry(req *http.Request, channel string) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		response, err := sc.httpClient.Do(req)
+		// a canceled call is not retried, whatever the policy says
+		if ctx.Err() != nil || !sc.retryPolicy.ShouldRetry(response, err, attempt) {
+			return response, err
+		}
+
+		// a request body that cannot be replayed cannot be retried
+		if req.Body != nil && req.GetBody == nil {
+			return response, err
+		}
+
+		delay := sc.retryPolicy.NextDelay(response, attempt)
+		if response != nil {
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		if err := sc.waitRateLimit(req, channel); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
 			if err != nil {
-				return err
+				return nil, err
 			}
+			req.Body = body
 		}
-		return nil
 	}
-	return checkSmoochError(response)
 }
@@ -2,11 +2,13 @@ package smooch
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"time"
 
@@ -227,17 +229,17 @@ func TestSendOKResponse(t *testing.T) {
 	assert.NoError(t, err)
 
 	message := &Message{}
-	response, err := sc.Send("", message)
+	response, _, err := sc.Send("", message)
 	assert.Nil(t, response)
 	assert.Error(t, err)
 	assert.EqualError(t, err, ErrUserIDEmpty.Error())
 
-	response, err = sc.Send("TestUser", nil)
+	response, _, err = sc.Send("TestUser", nil)
 	assert.Nil(t, response)
 	assert.Error(t, err)
 	assert.EqualError(t, err, ErrMessageNil.Error())
 
-	response, err = sc.Send("TestUser", message)
+	response, _, err = sc.Send("TestUser", message)
 	assert.Nil(t, response)
 	assert.Error(t, err)
 	assert.EqualError(t, err, ErrMessageRoleEmpty.Error())
@@ -245,7 +247,7 @@ func TestSendOKResponse(t *testing.T) {
 	message = &Message{
 		Role: RoleAppUser,
 	}
-	response, err = sc.Send("TestUser", message)
+	response, _, err = sc.Send("TestUser", message)
 	assert.Nil(t, response)
 	assert.Error(t, err)
 	assert.EqualError(t, err, ErrMessageTypeEmpty.Error())
@@ -254,12 +256,20 @@ func TestSendOKResponse(t *testing.T) {
 		Role: RoleAppUser,
 		Type: MessageTypeText,
 	}
-	response, err = sc.Send("TestUser", message)
+	response, _, err = sc.Send("TestUser", message)
+	assert.Nil(t, response)
+	assert.IsType(t, &MessageValidationError{}, err)
+
+	message.Text = "Hello"
+	response, respData, err := sc.Send("TestUser", message)
 	assert.NotNil(t, response)
 	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, respData.HTTPCode)
+	assert.JSONEq(t, sampleResponse, string(respData.Body))
 
 	assert.NotNil(t, response.Message)
 	assert.Equal(t, "55c8c1498590aa1900b9b9b1", response.Message.ID)
+	assert.Equal(t, "https://www.gravatar.com/image.jpg", response.Message.AvatarURL)
 	assert.Equal(t, 1, len(response.ExtraMessages))
 	assert.Equal(t, "507f1f77bcf86cd799439011", response.ExtraMessages[0].ID)
 	assert.Equal(t, "df0ebe56cbeab98589b8bfa7", response.Conversation.ID)
@@ -283,6 +293,7 @@ func TestSendErrorResponse(t *testing.T) {
 
 		return &http.Response{
 			StatusCode: http.StatusUnauthorized,
+			Header:     http.Header{"X-Request-Id": []string{"req-123"}},
 			Body:       ioutil.NopCloser(bytes.NewReader([]byte(errorResponseJson))),
 		}
 	}
@@ -296,13 +307,20 @@ func TestSendErrorResponse(t *testing.T) {
 	message := &Message{
 		Role: RoleAppUser,
 		Type: MessageTypeText,
+		Text: "Hello",
 	}
-	response, err := sc.Send("TestUser", message)
+	response, respData, err := sc.Send("TestUser", message)
 	assert.Nil(t, response)
 	assert.Error(t, err)
 
+	assert.NotNil(t, respData)
+	assert.Equal(t, http.StatusUnauthorized, respData.HTTPCode)
+	assert.Equal(t, "req-123", respData.RequestID)
+	assert.JSONEq(t, errorResponseJson, string(respData.Body))
+
 	smoochErr := err.(*SmoochError)
 	assert.Equal(t, http.StatusUnauthorized, smoochErr.Code())
+	assert.Equal(t, respData, smoochErr.ResponseData())
 	assert.Equal(t,
 		"StatusCode: 401 Code: unauthorized Message: Authorization is required",
 		smoochErr.Error(),
@@ -323,6 +341,10 @@ func TestHandlerOK(t *testing.T) {
 
 	sc.AddWebhookEventHandler(func(payload *Payload) {
 		handlerInvokeCounter++
+		assert.NotNil(t, payload.Client)
+		assert.Equal(t, "5c9d2f34a1d3a2504bc89511", payload.Client.ID)
+		assert.Equal(t, SourceTypeWeb, payload.Client.Platform)
+		assert.Equal(t, "en-US", payload.Client.Info["browserLanguage"])
 	})
 
 	mockData := bytes.NewReader([]byte(sampleWebhookData))
@@ -342,6 +364,67 @@ func TestHandlerOK(t *testing.T) {
 	assert.Equal(t, 2, handlerInvokeCounter)
 }
 
+func TestHandlerPostback(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var postbacks []*Postback
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		assert.Equal(t, TriggerPostback, payload.Trigger)
+		assert.Empty(t, payload.Messages)
+		postbacks = payload.Postbacks
+	})
+
+	mockData := bytes.NewReader([]byte(`{
+		"trigger": "postback",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+		"postbacks": [{
+			"message": {"_id": "55c8c1498590aa1900b9b9b1", "type": "text", "role": "appMaker", "text": "Book?"},
+			"action": {"_id": "571530ee4fae94c32b78b170", "type": "postback", "text": "Book", "payload": "BOOK"}
+		}]
+	}`))
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/foo", mockData)
+	req.Header.Set("X-Api-Key", "very-secure-test-secret")
+	w := httptest.NewRecorder()
+
+	sc.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Len(t, postbacks, 1)
+	assert.Equal(t, "BOOK", postbacks[0].Action.Payload)
+	assert.Equal(t, "55c8c1498590aa1900b9b9b1", postbacks[0].Message.ID)
+}
+
+func TestHandlerUnauthorized(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		t.Error("handler should not be called")
+	})
+
+	for _, secret := range []string{"", "very-secure-test-secret-wrong", "very-secure"} {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/foo", bytes.NewReader([]byte(sampleWebhookData)))
+		if secret != "" {
+			req.Header.Set("X-Api-Key", secret)
+		}
+		w := httptest.NewRecorder()
+
+		sc.Handler().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/foo", nil)
+	req.Header.Set("X-Api-Key", "very-secure-test-secret")
+	w := httptest.NewRecorder()
+	sc.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
 func TestVerifyRequest(t *testing.T) {
 	sc, err := New(Options{
 		VerifySecret: "very-secure-test-secret",
@@ -398,7 +481,7 @@ func TestGetAppUser(t *testing.T) {
 	})
 	assert.NoError(t, err)
 
-	appUser, err := sc.GetAppUser("123")
+	appUser, _, err := sc.GetAppUser("123")
 	assert.NotNil(t, appUser)
 	assert.NoError(t, err)
 
@@ -438,7 +521,7 @@ func TestUploadAttachment(t *testing.T) {
 		assert.NoError(t, err)
 
 		assert.Equal(t, "image/png", form.Value["type"][0])
-		assert.Equal(t, "fixtures/smooch.png", form.File["source"][0].Filename)
+		assert.Equal(t, "smooch.png", form.File["source"][0].Filename)
 		assert.Equal(t, int64(5834), form.File["source"][0].Size)
 
 		return &http.Response{
@@ -453,7 +536,7 @@ func TestUploadAttachment(t *testing.T) {
 	})
 	assert.NoError(t, err)
 
-	r, err := sc.UploadFileAttachment("fixtures/smooch.png", NewAttachmentUpload("image/png"))
+	r, _, err := sc.UploadFileAttachment("fixtures/smooch.png", NewAttachmentUpload("image/png"))
 	assert.NotNil(t, r)
 	assert.NoError(t, err)
 
@@ -463,7 +546,7 @@ func TestUploadAttachment(t *testing.T) {
 		r.MediaURL,
 	)
 
-	r, err = sc.UploadFileAttachment("fixtures/smooch-not-exists.png", NewAttachmentUpload("image/png"))
+	r, _, err = sc.UploadFileAttachment("fixtures/smooch-not-exists.png", NewAttachmentUpload("image/png"))
 	assert.Nil(t, r)
 	assert.Error(t, err)
 }
@@ -488,9 +571,694 @@ func TestDeleteAttachment(t *testing.T) {
 	})
 	assert.NoError(t, err)
 
-	err = sc.DeleteAttachment(&Attachment{
+	_, err = sc.DeleteAttachment(&Attachment{
 		MediaURL:  "https://media.smooch.io/conversation/c7f6e6d6c3a637261bd9656f/a77caae4cbbd263a0938eba00016b7c8/test.png",
 		MediaType: "",
 	})
 	assert.NoError(t, err)
 }
+
+func TestListAttachments(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/attachments", req.URL.Path)
+		assert.Equal(t, "25", req.URL.Query().Get("limit"))
+		assert.Equal(t, "50", req.URL.Query().Get("offset"))
+		assert.Equal(t, "c7f6e6d6c3a637261bd9656f", req.URL.Query().Get("appUserId"))
+
+		body := `{"attachments":[{"mediaUrl":"https://media.smooch.io/apps/app/test.png","mediaType":"image/png"}],"hasMore":true}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	response, _, err := sc.ListAttachments(ListAttachmentsParams{
+		Limit:     25,
+		Offset:    50,
+		AppUserID: "c7f6e6d6c3a637261bd9656f",
+	})
+	assert.NoError(t, err)
+	assert.True(t, response.HasMore)
+	assert.Len(t, response.Attachments, 1)
+	assert.Equal(t, "image/png", response.Attachments[0].MediaType)
+}
+
+func TestSendRequestLogging(t *testing.T) {
+	status := http.StatusOK
+	fn := func(req *http.Request) *http.Response {
+		body := sampleGetUserJson
+		if status != http.StatusOK {
+			body = `{"error":{"code":"not_found","description":"App user not found"}}`
+		}
+		return &http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	logger := &testLogger{}
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		Logger:       logger,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.GetAppUser("123")
+	assert.NoError(t, err)
+
+	entries := logger.find("info", "smooch api call")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, http.MethodGet, entries[0].fields["method"])
+	assert.Equal(t, "/v1.1/apps/app/appusers/123", entries[0].fields["endpoint"])
+	assert.Equal(t, http.StatusOK, entries[0].fields["status"])
+	assert.IsType(t, time.Duration(0), entries[0].fields["latency"])
+
+	status = http.StatusNotFound
+	_, _, err = sc.GetAppUser("456")
+	assert.Error(t, err)
+
+	entries = logger.find("error", "smooch api call failed")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "/v1.1/apps/app/appusers/456", entries[0].fields["endpoint"])
+	assert.Equal(t, http.StatusNotFound, entries[0].fields["status"])
+	assert.Equal(t, "not_found", entries[0].fields["code"])
+	assert.Equal(t, err, entries[0].fields["err"])
+}
+
+func TestDo(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPut, req.Method)
+		assert.Equal(t, "https://api.smooch.io/v1.1/apps/app/integrations/abc?dryRun=true", req.URL.String())
+		assert.Equal(t, "application/json", req.Header.Get(contentTypeHeaderKey))
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "yes", req.Context().Value(testContextKey("trace")))
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"displayName":"Support"}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"integration":{"_id":"abc"}}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	var out struct {
+		Integration struct {
+			ID string `json:"_id"`
+		} `json:"integration"`
+	}
+	ctx := context.WithValue(context.Background(), testContextKey("trace"), "yes")
+	respData, err := sc.Do(
+		ctx,
+		http.MethodPut,
+		"/v1.1/apps/app/integrations/abc",
+		url.Values{"dryRun": []string{"true"}},
+		map[string]string{"displayName": "Support"},
+		&out,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respData.HTTPCode)
+	assert.Equal(t, "abc", out.Integration.ID)
+}
+
+type testContextKey string
+
+func TestGetMessages(t *testing.T) {
+	sampleGetMessagesJson := `
+	{
+		"messages": [
+			{
+				"_id": "55c8c1498590aa1900b9b9b1",
+				"authorId": "c7f6e6d6c3a637261bd9656f",
+				"role": "appUser",
+				"type": "text",
+				"name": "Steve",
+				"text": "Just put some vinegar on it",
+				"received": 1439220041.586
+			}
+		],
+		"next": "https://api.smooch.io/v1.1/apps/app/appusers/123/messages?after=1439220041.586",
+		"previous": "https://api.smooch.io/v1.1/apps/app/appusers/123/messages?before=1439220041.586"
+	}`
+
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/messages", req.URL.Path)
+		assert.Equal(t, "1471995721", req.URL.Query().Get("before"))
+		assert.Equal(t, "", req.URL.Query().Get("after"))
+		assert.Equal(t, "50", req.URL.Query().Get("limit"))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetMessagesJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	response, _, err := sc.GetMessages("123", GetMessagesParams{
+		Before: "1471995721",
+		Limit:  50,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, response.Messages, 1)
+	assert.Equal(t, "55c8c1498590aa1900b9b9b1", response.Messages[0].ID)
+	assert.Equal(t, "1439220041.586", response.BeforeCursor())
+	assert.Equal(t, "1439220041.586", response.AfterCursor())
+
+	_, _, err = sc.GetMessages("", GetMessagesParams{})
+	assert.Equal(t, ErrUserIDEmpty, err)
+
+	_, _, err = sc.GetMessages("123", GetMessagesParams{Before: "1", After: "2"})
+	assert.Equal(t, ErrMessagesCursorConflict, err)
+}
+
+func TestDeleteMessage(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/messages/55c8c1498590aa1900b9b9b1", req.URL.Path)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	respData, err := sc.DeleteMessage("123", "55c8c1498590aa1900b9b9b1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respData.HTTPCode)
+
+	_, err = sc.DeleteMessage("", "55c8c1498590aa1900b9b9b1")
+	assert.Equal(t, ErrUserIDEmpty, err)
+
+	_, err = sc.DeleteMessage("123", "")
+	assert.Equal(t, ErrMessageIDEmpty, err)
+}
+
+func TestDeleteConversationHistory(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/messages", req.URL.Path)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	respData, err := sc.DeleteConversationHistory("123")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respData.HTTPCode)
+
+	_, err = sc.DeleteConversationHistory("")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestDeleteAppUser(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123", req.URL.Path)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	respData, err := sc.DeleteAppUser("123")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respData.HTTPCode)
+
+	_, err = sc.DeleteAppUser("")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestUpdateAppUser(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPut, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get(contentTypeHeaderKey))
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123", req.URL.Path)
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"givenName": "Steve",
+			"email": "steveb@channel5.com",
+			"signedUpAt": "2019-01-14T18:55:12Z",
+			"properties": {"favoriteFood": "prizza"}
+		}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetUserJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	signedUpAt := time.Date(2019, 1, 14, 18, 55, 12, 0, time.UTC)
+	appUser, _, err := sc.UpdateAppUser("123", AppUserUpdate{
+		GivenName:  "Steve",
+		Email:      "steveb@channel5.com",
+		SignedUpAt: &signedUpAt,
+		Properties: map[string]interface{}{"favoriteFood": "prizza"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "7494535bff5cef41a15be74d", appUser.ID)
+	assert.Equal(t, "Steve", appUser.GivenName)
+
+	_, _, err = sc.UpdateAppUser("", AppUserUpdate{})
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestAppUserProperties(t *testing.T) {
+	var bodies []string
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPut, req.Method)
+		assert.Equal(t, "/v1.1/apps/app/appusers/123", req.URL.Path)
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetUserJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	appUser, _, err := sc.SetAppUserProperties("123", map[string]interface{}{"favoriteFood": "prizza", "visits": 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "prizza", appUser.Properties["favoriteFood"])
+
+	_, _, err = sc.DeleteAppUserProperty("123", "visits")
+	assert.NoError(t, err)
+
+	assert.Len(t, bodies, 2)
+	assert.JSONEq(t, `{"properties":{"favoriteFood":"prizza","visits":3}}`, bodies[0])
+	assert.JSONEq(t, `{"properties":{"visits":null}}`, bodies[1])
+
+	_, _, err = sc.SetAppUserProperties("123", nil)
+	assert.Equal(t, ErrPropertiesEmpty, err)
+
+	_, _, err = sc.DeleteAppUserProperty("123", "")
+	assert.Equal(t, ErrPropertyKeyEmpty, err)
+}
+
+func TestDeleteAppUserProfile(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/profile", req.URL.Path)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(bytes.NewReader([]byte(`
+			{
+				"appUser": {
+					"_id": "7494535bff5cef41a15be74d",
+					"userId": "steveb@channel5.com",
+					"conversationStarted": true,
+					"properties": {}
+				}
+			}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	appUser, _, err := sc.DeleteAppUserProfile("123")
+	assert.NoError(t, err)
+	assert.Equal(t, "7494535bff5cef41a15be74d", appUser.ID)
+	assert.Equal(t, "", appUser.GivenName)
+	assert.Empty(t, appUser.Properties)
+
+	_, _, err = sc.DeleteAppUserProfile("")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestGetAppUserChannels(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/channels", req.URL.Path)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(bytes.NewReader([]byte(`
+			{
+				"channels": [
+					{"type": "whatsapp", "phoneNumber": "+15145555555"},
+					{"type": "messenger", "userId": "1643398935706298"}
+				]
+			}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	channels, _, err := sc.GetAppUserChannels("123")
+	assert.NoError(t, err)
+	assert.Len(t, channels, 2)
+	assert.Equal(t, SourceTypeWhatsApp, channels[0].Type)
+	assert.Equal(t, "+15145555555", channels[0].PhoneNumber)
+	assert.Equal(t, SourceTypeMessenger, channels[1].Type)
+	assert.Equal(t, "1643398935706298", channels[1].UserID)
+
+	_, _, err = sc.GetAppUserChannels("")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestUnlinkAppUserChannel(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodDelete, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/channels/whatsapp", req.URL.Path)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	respData, err := sc.UnlinkAppUserChannel("123", SourceTypeWhatsApp)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, respData.HTTPCode)
+
+	_, err = sc.UnlinkAppUserChannel("", SourceTypeWhatsApp)
+	assert.Equal(t, ErrUserIDEmpty, err)
+
+	_, err = sc.UnlinkAppUserChannel("123", "")
+	assert.Equal(t, ErrChannelTypeEmpty, err)
+}
+
+func TestGetLinkRequests(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/linkrequest", req.URL.Path)
+		assert.Equal(t, "5a2a2f1b4fe5a8a01900001f,5a2a2f1b4fe5a8a019000020", req.URL.Query().Get("integrationIds"))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(bytes.NewReader([]byte(`
+			{
+				"linkRequests": [
+					{
+						"integrationId": "5a2a2f1b4fe5a8a01900001f",
+						"type": "messenger",
+						"code": "lr_2ETy3NlMDPRnRL8LnRgEZRVh",
+						"url": "https://m.me/1234567890?ref=lr_2ETy3NlMDPRnRL8LnRgEZRVh"
+					}
+				]
+			}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	linkRequests, _, err := sc.GetLinkRequests("123", []string{"5a2a2f1b4fe5a8a01900001f", "5a2a2f1b4fe5a8a019000020"})
+	assert.NoError(t, err)
+	assert.Len(t, linkRequests, 1)
+	assert.Equal(t, SourceTypeMessenger, linkRequests[0].Type)
+	assert.Equal(t, "lr_2ETy3NlMDPRnRL8LnRgEZRVh", linkRequests[0].Code)
+	assert.Equal(t, "https://m.me/1234567890?ref=lr_2ETy3NlMDPRnRL8LnRgEZRVh", linkRequests[0].URL)
+
+	_, _, err = sc.GetLinkRequests("", []string{"5a2a2f1b4fe5a8a01900001f"})
+	assert.Equal(t, ErrUserIDEmpty, err)
+
+	_, _, err = sc.GetLinkRequests("123", nil)
+	assert.Equal(t, ErrIntegrationIDsEmpty, err)
+}
+
+func TestPreCreateAppUser(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get(contentTypeHeaderKey))
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+		assert.Equal(t, "/v1.1/apps/app/appusers", req.URL.Path)
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"userId": "steveb@channel5.com",
+			"givenName": "Steve",
+			"email": "steveb@channel5.com",
+			"locale": "en-US",
+			"signedUpAt": "2019-01-14T18:55:12Z",
+			"properties": {"favoriteFood": "prizza"}
+		}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetUserJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	signedUpAt := time.Date(2019, 1, 14, 18, 55, 12, 0, time.UTC)
+	appUser, _, err := sc.PreCreateAppUser(AppUserCreate{
+		UserID:     "steveb@channel5.com",
+		GivenName:  "Steve",
+		Email:      "steveb@channel5.com",
+		Locale:     "en-US",
+		SignedUpAt: &signedUpAt,
+		Properties: map[string]interface{}{"favoriteFood": "prizza"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "7494535bff5cef41a15be74d", appUser.ID)
+	assert.Equal(t, "steveb@channel5.com", appUser.UserID)
+
+	_, _, err = sc.PreCreateAppUser(AppUserCreate{GivenName: "Steve"})
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestAppUserClients(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, expectedAuthorizationHeader, req.Header.Get(authorizationHeaderKey))
+
+		if req.Method == http.MethodDelete {
+			assert.Equal(t, "/v1.1/apps/app/appusers/123/clients/5c93cb748f63db54ff3b51dd", req.URL.Path)
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+			}
+		}
+
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/clients", req.URL.Path)
+
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"id": "F272EB80-D512-4C19-9AC0-BD259DAEAD91",
+			"platform": "ios",
+			"pushNotificationToken": "0cfc626c9fed1e3d"
+		}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body: ioutil.NopCloser(bytes.NewReader([]byte(`
+			{
+				"client": {
+					"_id": "5c93cb748f63db54ff3b51dd",
+					"platform": "ios",
+					"deviceId": "F272EB80-D512-4C19-9AC0-BD259DAEAD91",
+					"active": true,
+					"primary": false
+				}
+			}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	client, _, err := sc.AddAppUserClient("123", ClientCreate{
+		ID:                    "F272EB80-D512-4C19-9AC0-BD259DAEAD91",
+		Platform:              SourceTypeIOS,
+		PushNotificationToken: "0cfc626c9fed1e3d",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "5c93cb748f63db54ff3b51dd", client.ID)
+	assert.True(t, client.Active)
+
+	_, err = sc.RemoveAppUserClient("123", client.ID)
+	assert.NoError(t, err)
+
+	_, _, err = sc.AddAppUserClient("123", ClientCreate{})
+	assert.Equal(t, ErrClientIDEmpty, err)
+
+	_, err = sc.RemoveAppUserClient("", "5c93cb748f63db54ff3b51dd")
+	assert.Equal(t, ErrUserIDEmpty, err)
+
+	_, err = sc.RemoveAppUserClient("123", "")
+	assert.Equal(t, ErrClientIDEmpty, err)
+}
+
+func TestGetAppUserByIdentifier(t *testing.T) {
+	var paths []string
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodGet, req.Method)
+		paths = append(paths, req.URL.EscapedPath())
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetUserJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	appUser, _, err := sc.GetAppUserByID("7494535bff5cef41a15be74d")
+	assert.NoError(t, err)
+	assert.Equal(t, "7494535bff5cef41a15be74d", appUser.ID)
+
+	appUser, _, err = sc.GetAppUserByExternalID("steve b/c@channel5.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "steveb@channel5.com", appUser.UserID)
+
+	assert.Equal(t, []string{
+		"/v1.1/apps/app/appusers/7494535bff5cef41a15be74d",
+		"/v1.1/apps/app/appusers/steve%20b%2Fc@channel5.com",
+	}, paths)
+
+	_, _, err = sc.GetAppUserByExternalID("")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestAppUserEndpointsEscapeUserID(t *testing.T) {
+	var paths []string
+	fn := func(req *http.Request) *http.Response {
+		paths = append(paths, req.URL.EscapedPath())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{}`))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	userID := "steve/b?c#d@channel5.com"
+	sc.UpdateAppUser(userID, AppUserUpdate{GivenName: "Steve"})
+	sc.DeleteAppUser(userID)
+	sc.DeleteAppUserProfile(userID)
+	sc.GetAppUserChannels(userID)
+	sc.UnlinkAppUserChannel(userID, SourceTypeMessenger)
+	sc.GetLinkRequests(userID, []string{"integration"})
+	sc.AddAppUserClient(userID, ClientCreate{ID: "client", Platform: "messenger"})
+	sc.RemoveAppUserClient(userID, "client")
+	sc.GetMessages(userID, GetMessagesParams{})
+	sc.DeleteMessage(userID, "message")
+	sc.DeleteConversationHistory(userID)
+	sc.Send(userID, &Message{Role: RoleAppMaker, Type: MessageTypeText, Text: "Hello"})
+
+	assert.Len(t, paths, 12)
+	for _, path := range paths {
+		assert.True(t, strings.HasPrefix(path, "/v1.1/apps/app/appusers/steve%2Fb%3Fc%23d@channel5.com"), path)
+	}
+}
@@ -0,0 +1,225 @@
+package smoochtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Mode tells whether a Recorder calls the API or replays a cassette.
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette, without calling the
+	// API. It is the mode to run in CI.
+	ModeReplay Mode = iota
+	// ModeRecord calls the API and writes the interactions to the cassette
+	// on Stop.
+	ModeRecord
+)
+
+// Interaction is a request and its response, as stored in a cassette.
+// Request headers aren't recorded, so credentials never end up in
+// cassettes.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// RecorderOptions configures a Recorder.
+type RecorderOptions struct {
+	Mode Mode
+	// Transport makes the requests when recording, http.DefaultTransport
+	// when not set.
+	Transport http.RoundTripper
+	// Sanitize, when set, is called on every recorded interaction before it
+	// is written, to scrub ids, names or phone numbers from it.
+	Sanitize func(i *Interaction)
+}
+
+// Recorder is an http.RoundTripper recording the interactions with the API
+// to a cassette file, and replaying them in place of the API:
+//
+//	mode := smoochtest.ModeReplay
+//	if os.Getenv("SMOOCH_RECORD") != "" {
+//		mode = smoochtest.ModeRecord
+//	}
+//	recorder, err := smoochtest.NewRecorder("testdata/send.json", smoochtest.RecorderOptions{Mode: mode})
+//	...
+//	defer recorder.Stop()
+//	sc, err := smooch.New(smooch.Options{..., HttpClient: recorder.Client()})
+//
+// Replayed requests are matched on their method and URL, in the order they
+// were recorded.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	sanitize  func(i *Interaction)
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewRecorder returns a recorder of the cassette at path. In ModeReplay the
+// cassette has to exist.
+func NewRecorder(path string, o RecorderOptions) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      o.Mode,
+		transport: o.Transport,
+		sanitize:  o.Sanitize,
+	}
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+
+	if r.mode == ModeReplay {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var c cassette
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("smoochtest: invalid cassette %s: %w", path, err)
+		}
+		r.interactions = c.Interactions
+		r.used = make([]bool, len(c.Interactions))
+	}
+	return r, nil
+}
+
+// Client returns an HTTP client going through the recorder, to set as
+// smooch.Options.HttpClient.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url := req.URL.String()
+	for i, interaction := range r.interactions {
+		if r.used[i] || interaction.Request.Method != req.Method || interaction.Request.URL != url {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			StatusCode: interaction.Response.StatusCode,
+			Status:     fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			Header:     interaction.Response.Header.Clone(),
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			Request:    req,
+		}, nil
+	}
+	return nil, fmt.Errorf("smoochtest: no recorded interaction left for %s %s", req.Method, url)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	response, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	header := response.Header.Clone()
+	header.Del("Set-Cookie")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Body:   string(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: response.StatusCode,
+			Header:     header,
+			Body:       string(responseBody),
+		},
+	})
+	return response, nil
+}
+
+// Stop writes the cassette when recording, after sanitizing the
+// interactions. It does nothing when replaying.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sanitize != nil {
+		for _, interaction := range r.interactions {
+			r.sanitize(interaction)
+		}
+	}
+
+	data, err := json.MarshalIndent(cassette{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, data, 0644)
+}
+
+// Unused returns the recorded interactions that weren't replayed, to check
+// that a test made every call it used to.
+func (r *Recorder) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []*Interaction
+	for i, interaction := range r.interactions {
+		if r.mode == ModeReplay && !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,83 @@
+package smoochtest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EddyTravels/smooch"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.NotEmpty(t, req.Header.Get("Authorization"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}, "Set-Cookie": []string{"session=secret"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"appUser": {"_id": "user", "email": "jane@example.com"}}`)),
+		}, nil
+	})
+
+	recorder, err := NewRecorder(path, RecorderOptions{
+		Mode:      ModeRecord,
+		Transport: api,
+		Sanitize: func(i *Interaction) {
+			i.Response.Body = strings.ReplaceAll(i.Response.Body, "jane@example.com", "user@example.com")
+		},
+	})
+	assert.NoError(t, err)
+
+	sc, err := smooch.New(smooch.Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   recorder.Client(),
+	})
+	assert.NoError(t, err)
+
+	user, _, err := sc.GetAppUser("user")
+	assert.NoError(t, err)
+	assert.Equal(t, "jane@example.com", user.Email)
+	assert.NoError(t, recorder.Stop())
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "jane@example.com")
+	assert.NotContains(t, string(data), "session=secret")
+	assert.False(t, bytes.Contains(data, []byte("Bearer")))
+
+	replayer, err := NewRecorder(path, RecorderOptions{Mode: ModeReplay})
+	assert.NoError(t, err)
+	sc, err = smooch.New(smooch.Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   replayer.Client(),
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, replayer.Unused(), 1)
+	user, _, err = sc.GetAppUser("user")
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", user.Email)
+	assert.Empty(t, replayer.Unused())
+
+	// every interaction is replayed once
+	_, _, err = sc.GetAppUser("user")
+	assert.Error(t, err)
+}
+
+func TestRecorderMissingCassette(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), RecorderOptions{})
+	assert.Error(t, err)
+}
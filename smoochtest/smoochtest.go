@@ -0,0 +1,207 @@
+// Package smoochtest builds realistic webhook payloads for testing webhook
+// handlers:
+//
+//	payload := smoochtest.TextMessage("hello").AppUser("user").Payload()
+//	handler(payload)
+//
+// or, going through the client's handler:
+//
+//	req := smoochtest.ImageMessage("https://example.com/cat.jpg").Request("verify-secret")
+//	sc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+//
+// Every payload gets fresh message ids, so deduplication doesn't drop them.
+package smoochtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/EddyTravels/smooch"
+)
+
+const (
+	DefaultAppID          = "5698edbf2a43bd081be982f1"
+	DefaultAppUserID      = "c7f6e6d6c3a637261bd9656f"
+	DefaultUserID         = "bob@example.com"
+	DefaultConversationID = "105e47578be874292d365ee8"
+	DefaultIntegrationID  = "5a3174a1c92ed3a7d3eb5b21"
+	DefaultChannel        = smooch.SourceTypeMessenger
+
+	webhookVersion = "v1.1"
+)
+
+var lastID uint64
+
+// newID returns a new id shaped like the ones of Smooch.
+func newID() string {
+	return fmt.Sprintf("%024x", atomic.AddUint64(&lastID, 1))
+}
+
+// PayloadBuilder builds a webhook payload. Its methods override the
+// defaults and return the builder so calls can be chained.
+type PayloadBuilder struct {
+	payload *smooch.Payload
+}
+
+func newPayload(trigger string) *PayloadBuilder {
+	now := time.Now().Truncate(time.Millisecond)
+	return &PayloadBuilder{payload: &smooch.Payload{
+		Trigger: trigger,
+		App:     smooch.Application{ID: DefaultAppID},
+		AppUser: smooch.AppUser{
+			ID:                  DefaultAppUserID,
+			UserID:              DefaultUserID,
+			Properties:          map[string]interface{}{},
+			SignedUpAt:          now.Add(-24 * time.Hour),
+			ConversationStarted: true,
+		},
+		Conversation: smooch.Conversation{ID: DefaultConversationID},
+		Version:      webhookVersion,
+		Timestamp:    now,
+	}}
+}
+
+func newAppUserMessage(messageType smooch.MessageType) *smooch.Message {
+	return &smooch.Message{
+		ID:       newID(),
+		Type:     messageType,
+		Role:     smooch.RoleAppUser,
+		AuthorID: DefaultAppUserID,
+		Name:     "Bob",
+		Received: time.Now().Truncate(time.Millisecond),
+		Source: &smooch.SourceDestination{
+			Type:          DefaultChannel,
+			IntegrationId: DefaultIntegrationID,
+		},
+	}
+}
+
+// TextMessage builds a message:appUser webhook with a text message.
+func TextMessage(text string) *PayloadBuilder {
+	b := newPayload(smooch.TriggerMessageAppUser)
+	message := newAppUserMessage(smooch.MessageTypeText)
+	message.Text = text
+	b.payload.Messages = []*smooch.Message{message}
+	return b
+}
+
+// ImageMessage builds a message:appUser webhook with an image message.
+func ImageMessage(mediaURL string) *PayloadBuilder {
+	b := newPayload(smooch.TriggerMessageAppUser)
+	message := newAppUserMessage(smooch.MessageTypeImage)
+	message.MediaURL = mediaURL
+	message.MediaType = "image/jpeg"
+	message.MediaSize = 48123
+	b.payload.Messages = []*smooch.Message{message}
+	return b
+}
+
+// DeliveryFailure builds a message:delivery:failure webhook for the
+// message with the given id, failing with code.
+func DeliveryFailure(messageID string, code string) *PayloadBuilder {
+	b := newPayload(smooch.TriggerMessageDeliveryFailure)
+	b.payload.Destination = &smooch.SourceDestination{
+		Type:          DefaultChannel,
+		IntegrationId: DefaultIntegrationID,
+	}
+	b.payload.Message = &smooch.TruncatedMessage{ID: messageID}
+	b.payload.Error = &smooch.Error{
+		Code:    code,
+		Message: "The message could not be delivered",
+		UnderlyingError: map[string]interface{}{
+			"message": "The user could not be reached",
+		},
+	}
+	b.payload.IsFinalEvent = true
+	return b
+}
+
+// Postback builds a postback webhook for a postback action with the given
+// payload.
+func Postback(payload string) *PayloadBuilder {
+	b := newPayload(smooch.TriggerPostback)
+	b.payload.Postbacks = []*smooch.Postback{{
+		Action: &smooch.Action{
+			ID:      newID(),
+			Type:    smooch.ActionTypePostback,
+			Text:    "Yes",
+			Payload: payload,
+		},
+	}}
+	return b
+}
+
+func (b *PayloadBuilder) AppID(appID string) *PayloadBuilder {
+	b.payload.App.ID = appID
+	return b
+}
+
+// AppUser sets the id of the app user, who is also the author of the
+// messages.
+func (b *PayloadBuilder) AppUser(appUserID string) *PayloadBuilder {
+	b.payload.AppUser.ID = appUserID
+	for _, message := range b.payload.Messages {
+		message.AuthorID = appUserID
+	}
+	return b
+}
+
+func (b *PayloadBuilder) Conversation(conversationID string) *PayloadBuilder {
+	b.payload.Conversation.ID = conversationID
+	return b
+}
+
+// Channel sets the channel the messages come from, or the delivery failed
+// on.
+func (b *PayloadBuilder) Channel(channel string) *PayloadBuilder {
+	for _, message := range b.payload.Messages {
+		message.Source.Type = channel
+	}
+	if b.payload.Destination != nil {
+		b.payload.Destination.Type = channel
+	}
+	return b
+}
+
+func (b *PayloadBuilder) Timestamp(timestamp time.Time) *PayloadBuilder {
+	b.payload.Timestamp = timestamp
+	return b
+}
+
+// With calls fn to change any other field of the payload.
+func (b *PayloadBuilder) With(fn func(p *smooch.Payload)) *PayloadBuilder {
+	fn(b.payload)
+	return b
+}
+
+// Payload returns the payload, with Raw set to its JSON as when it is
+// decoded from a webhook.
+func (b *PayloadBuilder) Payload() *smooch.Payload {
+	p := *b.payload
+	p.Raw = b.JSON()
+	return &p
+}
+
+// JSON returns the body of the webhook.
+func (b *PayloadBuilder) JSON() []byte {
+	body, err := json.Marshal(b.payload)
+	if err != nil {
+		// the payload only holds types that marshal
+		panic(err)
+	}
+	return body
+}
+
+// Request returns a webhook request carrying the verify secret, to pass to
+// the client's handler.
+func (b *PayloadBuilder) Request(verifySecret string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader(b.JSON()))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", verifySecret)
+	return req
+}
@@ -0,0 +1,90 @@
+package smoochtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EddyTravels/smooch"
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, b *PayloadBuilder) *smooch.Payload {
+	var p smooch.Payload
+	assert.NoError(t, json.Unmarshal(b.JSON(), &p))
+	return &p
+}
+
+func TestTextMessage(t *testing.T) {
+	p := decode(t, TextMessage("hello").AppUser("user").Conversation("conv").Channel(smooch.SourceTypeWhatsApp))
+
+	assert.Equal(t, smooch.TriggerMessageAppUser, p.Trigger)
+	assert.Equal(t, DefaultAppID, p.App.ID)
+	assert.Equal(t, "user", p.AppUser.ID)
+	assert.Equal(t, "conv", p.Conversation.ID)
+	assert.Len(t, p.Messages, 1)
+	assert.Equal(t, smooch.MessageTypeText, p.Messages[0].Type)
+	assert.Equal(t, "hello", p.Messages[0].Text)
+	assert.Equal(t, smooch.RoleAppUser, p.Messages[0].Role)
+	assert.Equal(t, "user", p.Messages[0].AuthorID)
+	assert.Equal(t, smooch.SourceTypeWhatsApp, p.Messages[0].Source.Type)
+
+	// message ids are never reused
+	assert.NotEqual(t, p.Messages[0].ID, decode(t, TextMessage("hello")).Messages[0].ID)
+}
+
+func TestImageMessage(t *testing.T) {
+	p := decode(t, ImageMessage("https://example.com/cat.jpg"))
+
+	assert.Equal(t, smooch.MessageTypeImage, p.Messages[0].Type)
+	assert.Equal(t, "https://example.com/cat.jpg", p.Messages[0].MediaURL)
+	assert.Equal(t, "image/jpeg", p.Messages[0].MediaType)
+}
+
+func TestDeliveryFailure(t *testing.T) {
+	timestamp := time.Unix(1600000000, 0)
+	p := decode(t, DeliveryFailure("message", "unauthorized").Timestamp(timestamp))
+
+	event, ok := smooch.DecodeEvent(p).(*smooch.DeliveryFailureEvent)
+	assert.True(t, ok)
+	assert.Equal(t, "message", event.Message.ID)
+	assert.Equal(t, "unauthorized", event.Error.Code)
+	assert.Equal(t, DefaultChannel, event.Destination.Type)
+	assert.True(t, event.IsFinalEvent)
+	assert.True(t, timestamp.Equal(event.Timestamp))
+}
+
+func TestPostback(t *testing.T) {
+	b := Postback(`{"room":42}`).With(func(p *smooch.Payload) {
+		p.AppUser.GivenName = "Bob"
+	})
+	p := decode(t, b)
+
+	var booking struct {
+		Room int `json:"room"`
+	}
+	assert.NoError(t, p.Postbacks[0].DecodePayload(&booking))
+	assert.Equal(t, 42, booking.Room)
+	assert.Equal(t, "Bob", p.AppUser.GivenName)
+
+	assert.JSONEq(t, string(b.JSON()), string(b.Payload().Raw))
+}
+
+func TestRequest(t *testing.T) {
+	sc, err := smooch.New(smooch.Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	var received *smooch.Payload
+	sc.AddWebhookEventHandler(func(payload *smooch.Payload) {
+		received = payload
+	})
+
+	w := httptest.NewRecorder()
+	sc.Handler().ServeHTTP(w, TextMessage("hello").Request("very-secure-test-secret"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", received.Messages[0].Text)
+}
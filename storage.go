@@ -0,0 +1,102 @@
+package smooch
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage is the key-value store behind the optional subsystems that keep
+// state between calls, such as the template catalog. Implement it on top of
+// Redis or a database to share that state between instances; MemoryStorage
+// is enough for a single process.
+type Storage interface {
+	// Get returns the value stored at key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value at key. The value expires after ttl, or never when
+	// ttl is 0.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Keys returns the keys starting with prefix, in lexical order.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryStorage is an in-process Storage.
+type MemoryStorage struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		entries: map[string]memoryEntry{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if s.expired(entry) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+func (s *MemoryStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expiresAt = s.now().Add(ttl)
+	}
+	s.entries[key] = entry
+	return nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStorage) Keys(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key, entry := range s.entries {
+		if s.expired(entry) {
+			delete(s.entries, key)
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *MemoryStorage) expired(entry memoryEntry) bool {
+	return !entry.expiresAt.IsZero() && !s.now().Before(entry.expiresAt)
+}
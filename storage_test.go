@@ -0,0 +1,47 @@
+package smooch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemoryStorage()
+	s.now = func() time.Time { return now }
+
+	_, ok, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Set(ctx, "a:1", []byte("one"), time.Minute))
+	assert.NoError(t, s.Set(ctx, "a:2", []byte("two"), 0))
+	assert.NoError(t, s.Set(ctx, "b:1", []byte("three"), 0))
+
+	value, ok, err := s.Get(ctx, "a:1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "one", string(value))
+
+	keys, err := s.Keys(ctx, "a:")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:1", "a:2"}, keys)
+
+	now = now.Add(time.Minute)
+	_, ok, err = s.Get(ctx, "a:1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	keys, err = s.Keys(ctx, "a:")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:2"}, keys)
+
+	assert.NoError(t, s.Delete(ctx, "a:2"))
+	_, ok, err = s.Get(ctx, "a:2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
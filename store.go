@@ -0,0 +1,109 @@
+package smooch
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const conversationStoreKeyPrefix = "smooch:conversations:"
+
+// ConversationStore keeps state along a conversation, such as where a bot
+// dialog is at, as JSON in the storage. Values are grouped by conversation
+// id, see ConversationKey, and named by key.
+type ConversationStore struct {
+	storage Storage
+	ttl     time.Duration
+}
+
+// NewConversationStore returns a store keeping values for ttl unless Set
+// is given another one, or forever when both are 0.
+func NewConversationStore(storage Storage, ttl time.Duration) *ConversationStore {
+	return &ConversationStore{
+		storage: storage,
+		ttl:     ttl,
+	}
+}
+
+// ConversationKey returns the id the state of the webhook's conversation
+// is kept under: the conversation id, or the app user id for webhooks that
+// carry no conversation.
+func ConversationKey(p *Payload) string {
+	if p.Conversation.ID != "" {
+		return p.Conversation.ID
+	}
+	return p.AppUser.ID
+}
+
+// Get decodes the value stored at key for the conversation into v, and
+// reports whether there was one.
+func (s *ConversationStore) Get(ctx context.Context, conversationID string, key string, v interface{}) (bool, error) {
+	if conversationID == "" {
+		return false, ErrConversationIDEmpty
+	}
+	value, ok, err := s.storage.Get(ctx, s.key(conversationID, key))
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, json.Unmarshal(value, v)
+}
+
+// Set stores v as JSON at key for the conversation. ttl overrides the one
+// of the store when set.
+func (s *ConversationStore) Set(ctx context.Context, conversationID string, key string, v interface{}, ttl time.Duration) error {
+	if conversationID == "" {
+		return ErrConversationIDEmpty
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+	return s.storage.Set(ctx, s.key(conversationID, key), value, ttl)
+}
+
+// Delete removes the value at key for the conversation.
+func (s *ConversationStore) Delete(ctx context.Context, conversationID string, key string) error {
+	if conversationID == "" {
+		return ErrConversationIDEmpty
+	}
+	return s.storage.Delete(ctx, s.key(conversationID, key))
+}
+
+// Keys returns the keys stored for the conversation, in lexical order.
+func (s *ConversationStore) Keys(ctx context.Context, conversationID string) ([]string, error) {
+	if conversationID == "" {
+		return nil, ErrConversationIDEmpty
+	}
+	prefix := s.key(conversationID, "")
+	keys, err := s.storage.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	return keys, nil
+}
+
+// Clear removes every value stored for the conversation, e.g. once a
+// dialog ends or the app user is deleted.
+func (s *ConversationStore) Clear(ctx context.Context, conversationID string) error {
+	keys, err := s.Keys(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, s.key(conversationID, key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ConversationStore) key(conversationID string, key string) string {
+	return conversationStoreKeyPrefix + conversationID + "/" + key
+}
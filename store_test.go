@@ -0,0 +1,65 @@
+package smooch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1444348338, 0)
+	storage := NewMemoryStorage()
+	storage.now = func() time.Time { return now }
+	s := NewConversationStore(storage, time.Hour)
+
+	type booking struct {
+		Step   string `json:"step"`
+		Guests int    `json:"guests"`
+	}
+	assert.NoError(t, s.Set(ctx, "conv", "booking", booking{Step: "date", Guests: 2}, 0))
+	assert.NoError(t, s.Set(ctx, "conv", "locale", "fr", time.Minute))
+	assert.NoError(t, s.Set(ctx, "other", "locale", "en", 0))
+
+	var b booking
+	ok, err := s.Get(ctx, "conv", "booking", &b)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, booking{Step: "date", Guests: 2}, b)
+
+	keys, err := s.Keys(ctx, "conv")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"booking", "locale"}, keys)
+
+	// the ttl given to Set wins over the one of the store
+	now = now.Add(time.Minute)
+	var locale string
+	ok, err = s.Get(ctx, "conv", "locale", &locale)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Delete(ctx, "conv", "booking"))
+	ok, err = s.Get(ctx, "conv", "booking", &b)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Set(ctx, "conv", "booking", booking{Step: "name"}, 0))
+	assert.NoError(t, s.Clear(ctx, "conv"))
+	keys, err = s.Keys(ctx, "conv")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	ok, err = s.Get(ctx, "other", "locale", &locale)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "en", locale)
+
+	_, err = s.Get(ctx, "", "booking", &b)
+	assert.Equal(t, ErrConversationIDEmpty, err)
+}
+
+func TestConversationKey(t *testing.T) {
+	assert.Equal(t, "conv", ConversationKey(&Payload{Conversation: Conversation{ID: "conv"}, AppUser: AppUser{ID: "123"}}))
+	assert.Equal(t, "123", ConversationKey(&Payload{AppUser: AppUser{ID: "123"}}))
+}
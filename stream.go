@@ -0,0 +1,90 @@
+package smooch
+
+import "context"
+
+// DefaultEventBufferSize is the number of payloads the channel returned by
+// Events holds when WebhookEndpointOptions.EventBufferSize is not set.
+const DefaultEventBufferSize = 100
+
+// OverflowPolicy tells what happens to webhooks when the channel returned
+// by Events is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock holds the webhook until the channel has room, slowing
+	// the handling of webhooks down to the pace of the consumer.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop drops the webhook and logs it.
+	OverflowDrop
+)
+
+type eventStream struct {
+	payloads chan *Payload
+	policy   OverflowPolicy
+	closed   bool
+}
+
+// Events returns a channel receiving the payload of every webhook, as an
+// alternative to handlers. The first call adds it after the handlers added
+// so far; later calls return the same channel. Close closes it once the
+// webhooks being handled are dispatched.
+func (ep *WebhookEndpoint) Events() <-chan *Payload {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if ep.stream != nil {
+		return ep.stream.payloads
+	}
+
+	size := ep.eventBufferSize
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	stream := &eventStream{
+		payloads: make(chan *Payload, size),
+		policy:   ep.overflowPolicy,
+	}
+	ep.stream = stream
+	if ep.closed {
+		stream.close()
+		return stream.payloads
+	}
+
+	ep.handlers = append(ep.handlers, func(ctx context.Context, p *Payload, meta WebhookMeta) error {
+		return stream.send(ctx, ep.logger, p)
+	})
+	return stream.payloads
+}
+
+func (s *eventStream) send(ctx context.Context, logger Logger, p *Payload) error {
+	if s.policy == OverflowDrop {
+		select {
+		case s.payloads <- p:
+		default:
+			logger.Errorw("event channel full, webhook dropped", "trigger", p.Trigger)
+		}
+		return nil
+	}
+
+	select {
+	case s.payloads <- p:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *eventStream) close() {
+	if !s.closed {
+		s.closed = true
+		close(s.payloads)
+	}
+}
+
+func (ep *WebhookEndpoint) closeStream() {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.stream != nil {
+		ep.stream.close()
+	}
+}
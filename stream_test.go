@@ -0,0 +1,83 @@
+package smooch
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsChannel(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret:           "very-secure-test-secret",
+		WebhookEventBufferSize: 2,
+	})
+	assert.NoError(t, err)
+
+	events := sc.Events()
+	assert.Equal(t, events, sc.Events())
+
+	payload := &Payload{}
+	assert.NoError(t, json.Unmarshal([]byte(payloadExample1), payload))
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	// blocks on a full channel until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var handlerErr error
+	sc.OnHandlerError(func(payload *Payload, index int, err error) {
+		handlerErr = err
+	})
+	sc.dispatch(ctx, payload, WebhookMeta{})
+	assert.Equal(t, context.DeadlineExceeded, handlerErr)
+
+	assert.NoError(t, sc.Close(context.Background()))
+
+	var received []*Payload
+	for p := range events {
+		received = append(received, p)
+	}
+	assert.Len(t, received, 2)
+	assert.Equal(t, TriggerMessageAppUser, received[0].Trigger)
+}
+
+func TestEventsChannelDrop(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret:           "very-secure-test-secret",
+		WebhookEventBufferSize: 1,
+		WebhookOverflowPolicy:  OverflowDrop,
+	})
+	assert.NoError(t, err)
+
+	events := sc.Events()
+	var handlerErr error
+	sc.OnHandlerError(func(payload *Payload, index int, err error) {
+		handlerErr = err
+	})
+
+	sc.dispatch(context.Background(), &Payload{Trigger: TriggerMessageAppUser}, WebhookMeta{})
+	sc.dispatch(context.Background(), &Payload{Trigger: TriggerPostback}, WebhookMeta{})
+	assert.NoError(t, handlerErr)
+
+	assert.Equal(t, TriggerMessageAppUser, (<-events).Trigger)
+	select {
+	case p := <-events:
+		t.Fatalf("dropped payload received: %v", p.Trigger)
+	default:
+	}
+}
+
+func TestEventsAfterClose(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, sc.Close(context.Background()))
+	_, ok := <-sc.Events()
+	assert.False(t, ok)
+	assert.NoError(t, sc.Close(context.Background()))
+}
@@ -0,0 +1,71 @@
+package smooch
+
+const (
+	TelegramParseModeMarkdown = "MarkdownV2"
+	TelegramParseModeHTML     = "HTML"
+
+	// telegramMaxCallbackData is the maximum size in bytes of the
+	// callback_data of an inline button.
+	telegramMaxCallbackData = 64
+)
+
+// TelegramOverride is the override payload of Telegram, holding sendMessage
+// parameters.
+type TelegramOverride struct {
+	ParseMode             string                  `json:"parse_mode,omitempty"`
+	DisableWebPagePreview bool                    `json:"disable_web_page_preview,omitempty"`
+	DisableNotification   bool                    `json:"disable_notification,omitempty"`
+	ReplyMarkup           *TelegramInlineKeyboard `json:"reply_markup,omitempty"`
+}
+
+func (TelegramOverride) Channel() string { return SourceTypeTelegram }
+
+// NewTelegramInlineKeyboardOverride returns an override showing rows of
+// inline buttons attached to the message.
+func NewTelegramInlineKeyboardOverride(rows ...[]*TelegramInlineButton) *TelegramOverride {
+	return &TelegramOverride{
+		ReplyMarkup: &TelegramInlineKeyboard{InlineKeyboard: rows},
+	}
+}
+
+// Validate checks that every inline button has text and exactly one of a
+// url or callback data, and that callback data fits in 64 bytes.
+func (o *TelegramOverride) Validate() error {
+	if o.ReplyMarkup == nil {
+		return nil
+	}
+	for i, row := range o.ReplyMarkup.InlineKeyboard {
+		for j, button := range row {
+			if button.Text == "" {
+				return invalidMessage("telegram button %d of row %d needs text", j, i)
+			}
+			if (button.CallbackData == "") == (button.URL == "") {
+				return invalidMessage("telegram button %d of row %d needs either a url or callback data", j, i)
+			}
+			if len(button.CallbackData) > telegramMaxCallbackData {
+				return invalidMessage("telegram button %d of row %d has more than %d bytes of callback data", j, i, telegramMaxCallbackData)
+			}
+		}
+	}
+	return nil
+}
+
+type TelegramInlineKeyboard struct {
+	InlineKeyboard [][]*TelegramInlineButton `json:"inline_keyboard"`
+}
+
+// TelegramInlineButton is a button of an inline keyboard. Tapping it opens
+// URL, or sends CallbackData back to the bot as a callback query.
+type TelegramInlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+func NewTelegramCallbackButton(text string, callbackData string) *TelegramInlineButton {
+	return &TelegramInlineButton{Text: text, CallbackData: callbackData}
+}
+
+func NewTelegramURLButton(text string, url string) *TelegramInlineButton {
+	return &TelegramInlineButton{Text: text, URL: url}
+}
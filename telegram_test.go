@@ -0,0 +1,50 @@
+package smooch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramInlineKeyboardOverride(t *testing.T) {
+	message, err := NewMessage().
+		Text("Confirm your booking?").
+		ChannelOverride(NewTelegramInlineKeyboardOverride(
+			[]*TelegramInlineButton{
+				NewTelegramCallbackButton("Yes", "confirm:42"),
+				NewTelegramCallbackButton("No", "cancel:42"),
+			},
+			[]*TelegramInlineButton{
+				NewTelegramURLButton("Details", "https://example.org/bookings/42"),
+			},
+		)).
+		Build()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"reply_markup": {
+			"inline_keyboard": [
+				[{"text": "Yes", "callback_data": "confirm:42"}, {"text": "No", "callback_data": "cancel:42"}],
+				[{"text": "Details", "url": "https://example.org/bookings/42"}]
+			]
+		}
+	}`, string(message.Override[SourceTypeTelegram].Payload))
+}
+
+func TestTelegramOverrideValidate(t *testing.T) {
+	tests := map[string]*TelegramInlineButton{
+		"no text":       {CallbackData: "x"},
+		"no action":     {Text: "Yes"},
+		"both actions":  {Text: "Yes", CallbackData: "x", URL: "https://example.org"},
+		"long callback": NewTelegramCallbackButton("Yes", strings.Repeat("x", 65)),
+	}
+	for name, button := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewMessage().
+				Text("Confirm?").
+				ChannelOverride(NewTelegramInlineKeyboardOverride([]*TelegramInlineButton{button})).
+				Build()
+			assert.IsType(t, &MessageValidationError{}, err)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package smooch
+
+import "strconv"
+
+const (
+	maxTemplateQuickReplies = 3
+	maxTemplateURLButtons   = 2
+)
+
+// TemplateBuilder assembles the components of a WhatsApp template message:
+//
+//	template, err := smooch.NewTemplate("order_shipped", "en").
+//		HeaderImage("https://example.org/box.png").
+//		Body("Bob", "#1234").
+//		QuickReplyButton("TRACK").
+//		Build()
+//
+// Buttons are indexed in the order they are added.
+type TemplateBuilder struct {
+	template       *WhatsAppTemplate
+	header         *TemplateComponent
+	body           *TemplateComponent
+	buttons        []*TemplateComponent
+	expectedParams int
+}
+
+func NewTemplate(name string, languageCode string) *TemplateBuilder {
+	return &TemplateBuilder{
+		template: &WhatsAppTemplate{
+			Name: name,
+			Language: &TemplateLanguage{
+				Policy: TemplateLanguagePolicyDeterministic,
+				Code:   languageCode,
+			},
+		},
+		expectedParams: -1,
+	}
+}
+
+func (b *TemplateBuilder) Namespace(namespace string) *TemplateBuilder {
+	b.template.Namespace = namespace
+	return b
+}
+
+func (b *TemplateBuilder) HeaderText(text string) *TemplateBuilder {
+	return b.headerParameter(NewTemplateTextParameter(text))
+}
+
+func (b *TemplateBuilder) HeaderImage(link string) *TemplateBuilder {
+	return b.headerParameter(&TemplateParameter{
+		Type:  TemplateParameterImage,
+		Image: &TemplateMedia{Link: link},
+	})
+}
+
+func (b *TemplateBuilder) HeaderDocument(link string, filename string) *TemplateBuilder {
+	return b.headerParameter(&TemplateParameter{
+		Type:     TemplateParameterDocument,
+		Document: &TemplateMedia{Link: link, Filename: filename},
+	})
+}
+
+func (b *TemplateBuilder) HeaderVideo(link string) *TemplateBuilder {
+	return b.headerParameter(&TemplateParameter{
+		Type:  TemplateParameterVideo,
+		Video: &TemplateMedia{Link: link},
+	})
+}
+
+func (b *TemplateBuilder) headerParameter(parameter *TemplateParameter) *TemplateBuilder {
+	if b.header == nil {
+		b.header = &TemplateComponent{Type: TemplateComponentHeader}
+	}
+	b.header.Parameters = append(b.header.Parameters, parameter)
+	return b
+}
+
+// Body adds text parameters to the body, in order.
+func (b *TemplateBuilder) Body(texts ...string) *TemplateBuilder {
+	for _, text := range texts {
+		b.BodyParameter(NewTemplateTextParameter(text))
+	}
+	return b
+}
+
+// BodyParameter adds a body parameter of any type, e.g. currency or
+// date_time.
+func (b *TemplateBuilder) BodyParameter(parameter *TemplateParameter) *TemplateBuilder {
+	if b.body == nil {
+		b.body = &TemplateComponent{Type: TemplateComponentBody}
+	}
+	b.body.Parameters = append(b.body.Parameters, parameter)
+	return b
+}
+
+// ExpectBodyParameters makes Build fail unless the body has exactly n
+// parameters, the number of placeholders in the approved template.
+func (b *TemplateBuilder) ExpectBodyParameters(n int) *TemplateBuilder {
+	b.expectedParams = n
+	return b
+}
+
+// QuickReplyButton sets the payload returned when the next quick reply
+// button of the template is tapped.
+func (b *TemplateBuilder) QuickReplyButton(payload string) *TemplateBuilder {
+	return b.button(TemplateButtonQuickReply, &TemplateParameter{
+		Type:    TemplateParameterPayload,
+		Payload: payload,
+	})
+}
+
+// URLButton sets the dynamic suffix of the next call-to-action URL button
+// of the template.
+func (b *TemplateBuilder) URLButton(suffix string) *TemplateBuilder {
+	return b.button(TemplateButtonURL, NewTemplateTextParameter(suffix))
+}
+
+func (b *TemplateBuilder) button(subType string, parameter *TemplateParameter) *TemplateBuilder {
+	b.buttons = append(b.buttons, &TemplateComponent{
+		Type:       TemplateComponentButton,
+		SubType:    subType,
+		Index:      strconv.Itoa(len(b.buttons)),
+		Parameters: []*TemplateParameter{parameter},
+	})
+	return b
+}
+
+// Build returns the template, or a *MessageValidationError when the
+// components can't make a valid template message.
+func (b *TemplateBuilder) Build() (*WhatsAppTemplate, error) {
+	if b.template.Name == "" {
+		return nil, ErrTemplateNameEmpty
+	}
+	if b.template.Language.Code == "" {
+		return nil, ErrTemplateLanguageEmpty
+	}
+
+	template := *b.template
+	template.Components = nil
+
+	if b.header != nil {
+		if len(b.header.Parameters) > 1 {
+			return nil, invalidMessage("template headers take a single parameter, got %d", len(b.header.Parameters))
+		}
+		template.Components = append(template.Components, b.header)
+	}
+
+	var bodyParams []*TemplateParameter
+	if b.body != nil {
+		bodyParams = b.body.Parameters
+		for i, parameter := range bodyParams {
+			switch parameter.Type {
+			case TemplateParameterText, TemplateParameterCurrency, TemplateParameterDateTime:
+			default:
+				return nil, invalidMessage("template body parameter %d can't be of type %s", i, parameter.Type)
+			}
+		}
+		template.Components = append(template.Components, b.body)
+	}
+	if b.expectedParams >= 0 && len(bodyParams) != b.expectedParams {
+		return nil, invalidMessage("template %s expects %d body parameters, got %d", template.Name, b.expectedParams, len(bodyParams))
+	}
+
+	quickReplies, urls := 0, 0
+	for _, button := range b.buttons {
+		if button.SubType == TemplateButtonQuickReply {
+			quickReplies++
+		} else {
+			urls++
+		}
+	}
+	if quickReplies > 0 && urls > 0 {
+		return nil, invalidMessage("template quick reply and url buttons can't be combined")
+	}
+	if quickReplies > maxTemplateQuickReplies {
+		return nil, invalidMessage("templates have at most %d quick reply buttons, got %d", maxTemplateQuickReplies, quickReplies)
+	}
+	if urls > maxTemplateURLButtons {
+		return nil, invalidMessage("templates have at most %d url buttons, got %d", maxTemplateURLButtons, urls)
+	}
+	template.Components = append(template.Components, b.buttons...)
+
+	return &template, nil
+}
@@ -0,0 +1,52 @@
+package smooch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateBuilder(t *testing.T) {
+	template, err := NewTemplate("order_shipped", "en").
+		Namespace("XXXXXXXX_XXXX_XXXX_XXXX_XXXXXXXXXXXX").
+		HeaderDocument("https://example.org/invoice.pdf", "invoice.pdf").
+		Body("Bob", "#1234").
+		ExpectBodyParameters(2).
+		QuickReplyButton("TRACK").
+		QuickReplyButton("CANCEL").
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, TemplateLanguagePolicyDeterministic, template.Language.Policy)
+	assert.Len(t, template.Components, 4)
+	assert.Equal(t, TemplateComponentHeader, template.Components[0].Type)
+	assert.Equal(t, "invoice.pdf", template.Components[0].Parameters[0].Document.Filename)
+	assert.Equal(t, "#1234", template.Components[1].Parameters[1].Text)
+	assert.Equal(t, "1", template.Components[3].Index)
+	assert.Equal(t, "CANCEL", template.Components[3].Parameters[0].Payload)
+}
+
+func TestTemplateBuilderValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *TemplateBuilder
+	}{
+		{"two headers", NewTemplate("t", "en").HeaderText("a").HeaderImage("https://example.org/a.png")},
+		{"image in body", NewTemplate("t", "en").BodyParameter(&TemplateParameter{Type: TemplateParameterImage})},
+		{"parameter count", NewTemplate("t", "en").Body("a").ExpectBodyParameters(2)},
+		{"mixed buttons", NewTemplate("t", "en").QuickReplyButton("A").URLButton("a")},
+		{"too many quick replies", NewTemplate("t", "en").QuickReplyButton("A").QuickReplyButton("B").QuickReplyButton("C").QuickReplyButton("D")},
+		{"too many urls", NewTemplate("t", "en").URLButton("a").URLButton("b").URLButton("c")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.builder.Build()
+			assert.IsType(t, &MessageValidationError{}, err)
+		})
+	}
+
+	_, err := NewTemplate("", "en").Build()
+	assert.Equal(t, ErrTemplateNameEmpty, err)
+	_, err = NewTemplate("t", "").Build()
+	assert.Equal(t, ErrTemplateLanguageEmpty, err)
+}
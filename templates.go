@@ -0,0 +1,261 @@
+package smooch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MessageTemplateStatusApproved = "APPROVED"
+	MessageTemplateStatusPending  = "PENDING"
+	MessageTemplateStatusRejected = "REJECTED"
+
+	templateCatalogKeyPrefix = "smooch:templates:"
+)
+
+// MessageTemplate is a WhatsApp message template as registered with the
+// WhatsApp Business Account of an integration.
+type MessageTemplate struct {
+	ID         string                      `json:"id,omitempty"`
+	Name       string                      `json:"name"`
+	Language   string                      `json:"language"`
+	Status     string                      `json:"status,omitempty"`
+	Category   string                      `json:"category,omitempty"`
+	Components []*MessageTemplateComponent `json:"components,omitempty"`
+}
+
+type MessageTemplateComponent struct {
+	Type string `json:"type"`
+	// Format is the header format, e.g. TEXT, IMAGE or DOCUMENT.
+	Format  string                   `json:"format,omitempty"`
+	Text    string                   `json:"text,omitempty"`
+	Buttons []*MessageTemplateButton `json:"buttons,omitempty"`
+}
+
+type MessageTemplateButton struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type ListMessageTemplatesParams struct {
+	Limit int
+	After string
+}
+
+type ListMessageTemplatesResponse struct {
+	MessageTemplates []*MessageTemplate `json:"messageTemplates"`
+	// After is the cursor of the next page, empty on the last one.
+	After string `json:"after,omitempty"`
+}
+
+func (sc *smoochClient) ListMessageTemplates(integrationID string, params ListMessageTemplatesParams, opts ...RequestOption) (*ListMessageTemplatesResponse, *ResponseData, error) {
+	if integrationID == "" {
+		return nil, nil, ErrIntegrationIDEmpty
+	}
+
+	queryParams := url.Values{}
+	if params.Limit > 0 {
+		queryParams.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.After != "" {
+		queryParams.Set("after", params.After)
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/integrations/%s/messageTemplates", sc.appID, url.PathEscape(integrationID)),
+		ro.queryParams(queryParams),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListMessageTemplatesResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return &response, respData, nil
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{\{(\d+)\}\}`)
+
+// TemplateCatalog keeps the template catalog of WhatsApp integrations in
+// storage and checks template messages against it, since WhatsApp drops
+// messages referencing unknown templates or with the wrong parameters
+// without the API reporting an error.
+type TemplateCatalog struct {
+	client  Client
+	storage Storage
+	ttl     time.Duration
+}
+
+// NewTemplateCatalog returns a catalog that refreshes an integration's
+// templates once they have been stored for longer than ttl.
+func NewTemplateCatalog(client Client, storage Storage, ttl time.Duration) *TemplateCatalog {
+	return &TemplateCatalog{
+		client:  client,
+		storage: storage,
+		ttl:     ttl,
+	}
+}
+
+// Sync pulls every template of the integration and stores them.
+func (c *TemplateCatalog) Sync(ctx context.Context, integrationID string) ([]*MessageTemplate, error) {
+	var templates []*MessageTemplate
+	params := ListMessageTemplatesParams{}
+	for {
+		response, _, err := c.client.ListMessageTemplates(integrationID, params, WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, response.MessageTemplates...)
+		if response.After == "" || response.After == params.After {
+			break
+		}
+		params.After = response.After
+	}
+
+	data, err := json.Marshal(templates)
+	if err != nil {
+		return nil, err
+	}
+	err = c.storage.Set(ctx, templateCatalogKeyPrefix+integrationID, data, c.ttl)
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Templates returns the stored templates of the integration, syncing them
+// first when they aren't stored.
+func (c *TemplateCatalog) Templates(ctx context.Context, integrationID string) ([]*MessageTemplate, error) {
+	templates, _, err := c.templates(ctx, integrationID)
+	return templates, err
+}
+
+// templates is Templates, also reporting whether the templates were just
+// synced.
+func (c *TemplateCatalog) templates(ctx context.Context, integrationID string) ([]*MessageTemplate, bool, error) {
+	data, ok, err := c.storage.Get(ctx, templateCatalogKeyPrefix+integrationID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		templates, err := c.Sync(ctx, integrationID)
+		return templates, true, err
+	}
+
+	var templates []*MessageTemplate
+	err = json.Unmarshal(data, &templates)
+	if err != nil {
+		return nil, false, err
+	}
+	return templates, false, nil
+}
+
+// Validate checks that template is approved in its language for the
+// integration and that it has as many header and body parameters as the
+// approved template has placeholders. It returns a *MessageValidationError
+// otherwise. The catalog is synced once before a template missing from it
+// is reported, so templates created or approved since the last sync pass.
+func (c *TemplateCatalog) Validate(ctx context.Context, integrationID string, template *WhatsAppTemplate) error {
+	if template == nil {
+		return ErrTemplateNil
+	}
+	if template.Language == nil || template.Language.Code == "" {
+		return ErrTemplateLanguageEmpty
+	}
+
+	templates, synced, err := c.templates(ctx, integrationID)
+	if err != nil {
+		return err
+	}
+	known, approved := findTemplate(templates, template)
+	if approved == nil && !synced {
+		templates, err = c.Sync(ctx, integrationID)
+		if err != nil {
+			return err
+		}
+		known, approved = findTemplate(templates, template)
+	}
+	if !known {
+		return invalidMessage("template %s does not exist", template.Name)
+	}
+	if approved == nil {
+		return invalidMessage("template %s is not approved in %s", template.Name, template.Language.Code)
+	}
+
+	for _, componentType := range []string{TemplateComponentHeader, TemplateComponentBody} {
+		expected := 0
+		for _, component := range approved.Components {
+			if strings.EqualFold(component.Type, componentType) {
+				expected = countPlaceholders(component)
+			}
+		}
+		got := 0
+		for _, component := range template.Components {
+			if component.Type == componentType {
+				got += len(component.Parameters)
+			}
+		}
+		if got != expected {
+			return invalidMessage("template %s expects %d %s parameters, got %d", template.Name, expected, componentType, got)
+		}
+	}
+	return nil
+}
+
+// SendTemplate validates template against the catalog of the integration
+// before sending it.
+func (c *TemplateCatalog) SendTemplate(ctx context.Context, integrationID string, userID string, template *WhatsAppTemplate, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	err := c.Validate(ctx, integrationID, template)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.client.SendTemplate(userID, template, opts...)
+}
+
+// findTemplate looks template up in templates. known reports whether a
+// template of that name exists in any language or status.
+func findTemplate(templates []*MessageTemplate, template *WhatsAppTemplate) (known bool, approved *MessageTemplate) {
+	for _, t := range templates {
+		if t.Name != template.Name {
+			continue
+		}
+		known = true
+		if t.Language == template.Language.Code && t.Status == MessageTemplateStatusApproved {
+			return true, t
+		}
+	}
+	return known, nil
+}
+
+// countPlaceholders returns the number of parameters a component takes:
+// the highest {{n}} in its text, or one for media headers.
+func countPlaceholders(component *MessageTemplateComponent) int {
+	switch component.Format {
+	case "IMAGE", "DOCUMENT", "VIDEO", "LOCATION":
+		return 1
+	}
+
+	count := 0
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(component.Text, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err == nil && n > count {
+			count = n
+		}
+	}
+	return count
+}
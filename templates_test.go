@@ -0,0 +1,135 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sampleMessageTemplatesJson = `
+	{
+		"messageTemplates": [
+			{
+				"name": "order_shipped",
+				"language": "en",
+				"status": "APPROVED",
+				"category": "SHIPPING_UPDATE",
+				"components": [
+					{"type": "HEADER", "format": "DOCUMENT"},
+					{"type": "BODY", "text": "Hi {{1}}, order {{2}} is on its way."}
+				]
+			},
+			{
+				"name": "order_shipped",
+				"language": "fr",
+				"status": "PENDING",
+				"components": [{"type": "BODY", "text": "Bonjour {{1}}"}]
+			}
+		]
+	}`
+
+func TestTemplateCatalog(t *testing.T) {
+	calls := 0
+	fn := func(req *http.Request) *http.Response {
+		body := sampleResponse
+		if req.Method == http.MethodGet {
+			calls++
+			assert.Equal(t, "/v1.1/apps/app/integrations/5e9f1cbd2e3ee4000cfa6e04/messageTemplates", req.URL.Path)
+			body = sampleMessageTemplatesJson
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	catalog := NewTemplateCatalog(sc, NewMemoryStorage(), time.Hour)
+	integrationID := "5e9f1cbd2e3ee4000cfa6e04"
+
+	template, err := NewTemplate("order_shipped", "en").
+		HeaderDocument("https://example.org/invoice.pdf", "invoice.pdf").
+		Body("Bob", "#1234").
+		Build()
+	assert.NoError(t, err)
+
+	_, _, err = catalog.SendTemplate(ctx, integrationID, "123", template)
+	assert.NoError(t, err)
+
+	template, err = NewTemplate("order_shipped", "en").Body("Bob").Build()
+	assert.NoError(t, err)
+	err = catalog.Validate(ctx, integrationID, template)
+	assert.IsType(t, &MessageValidationError{}, err)
+	assert.Contains(t, err.Error(), "expects 1 header parameters, got 0")
+
+	// the catalog was only fetched once
+	assert.Equal(t, 1, calls)
+
+	// a miss syncs the catalog again before it is reported
+	template, err = NewTemplate("order_shipped", "fr").Body("Bob").Build()
+	assert.NoError(t, err)
+	err = catalog.Validate(ctx, integrationID, template)
+	assert.Contains(t, err.Error(), "not approved in fr")
+	assert.Equal(t, 2, calls)
+
+	template, err = NewTemplate("welcome", "en").Build()
+	assert.NoError(t, err)
+	err = catalog.Validate(ctx, integrationID, template)
+	assert.Contains(t, err.Error(), "does not exist")
+	assert.Equal(t, 3, calls)
+
+	_, err = catalog.Sync(ctx, integrationID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, calls)
+}
+
+func TestTemplateCatalogSyncsNewTemplates(t *testing.T) {
+	templates := `{"messageTemplates": []}`
+	var ctxErr error
+	fn := func(req *http.Request) *http.Response {
+		ctxErr = req.Context().Err()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(templates))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	catalog := NewTemplateCatalog(sc, NewMemoryStorage(), time.Hour)
+	integrationID := "5e9f1cbd2e3ee4000cfa6e04"
+	_, err = catalog.Sync(ctx, integrationID)
+	assert.NoError(t, err)
+
+	// approved since the catalog was stored
+	templates = sampleMessageTemplatesJson
+	template, err := NewTemplate("order_shipped", "en").
+		HeaderDocument("https://example.org/invoice.pdf", "invoice.pdf").
+		Body("Bob", "#1234").
+		Build()
+	assert.NoError(t, err)
+	assert.NoError(t, catalog.Validate(ctx, integrationID, template))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	catalog.Sync(canceled, integrationID)
+	assert.ErrorIs(t, ctxErr, context.Canceled)
+}
@@ -0,0 +1,117 @@
+package smooch
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	tracerName = "github.com/EddyTravels/smooch"
+
+	attributeHTTPMethod      = attribute.Key("http.method")
+	attributeHTTPStatusCode  = attribute.Key("http.status_code")
+	attributeEndpoint        = attribute.Key("smooch.endpoint")
+	attributeAppID           = attribute.Key("smooch.app_id")
+	attributeTrigger         = attribute.Key("smooch.trigger")
+	attributeConversationID  = attribute.Key("smooch.conversation_id")
+	attributeAppUserID       = attribute.Key("smooch.app_user_id")
+	attributeWebhookMessages = attribute.Key("smooch.messages")
+	attributeWebhookPostback = attribute.Key("smooch.postbacks")
+)
+
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// routeParams names the path segment following each collection, which
+// holds the id of one of its items.
+var routeParams = map[string]string{
+	"apps":          "{appId}",
+	"appusers":      "{userId}",
+	"users":         "{userId}",
+	"channels":      "{channel}",
+	"clients":       "{clientId}",
+	"messages":      "{messageId}",
+	"conversations": "{conversationId}",
+	"integrations":  "{integrationId}",
+	"keys":          "{keyId}",
+	"deployments":   "{deploymentId}",
+}
+
+// routeTemplate replaces the ids in an API path with their parameter names,
+// e.g. /v1.1/apps/{appId}/appusers/{userId}/messages, so spans are named
+// per endpoint rather than per user.
+func routeTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		param, ok := routeParams[segments[i-1]]
+		if !ok || segments[i] == "merge" {
+			continue
+		}
+		segments[i] = param
+		// the next segment is a collection again
+		i++
+	}
+	return strings.Join(segments, "/")
+}
+
+// startRequestSpan starts a client span for an outbound API call and returns
+// the request bound to the span context, so middlewares can propagate it.
+func (sc *smoochClient) startRequestSpan(req *http.Request) (*http.Request, trace.Span) {
+	route := routeTemplate(req.URL.EscapedPath())
+	ctx, span := sc.tracer.Start(
+		req.Context(),
+		"smooch "+req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attributeHTTPMethod.String(req.Method),
+			attributeEndpoint.String(route),
+			attributeAppID.String(sc.appID),
+		),
+	)
+	return req.WithContext(ctx), span
+}
+
+func endRequestSpan(span trace.Span, response *http.Response, err error) {
+	if response != nil {
+		span.SetAttributes(attributeHTTPStatusCode.Int(response.StatusCode))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// startWebhookSpan starts a server span covering the handling of an inbound
+// webhook request.
+func (ep *WebhookEndpoint) startWebhookSpan(r *http.Request) (context.Context, trace.Span) {
+	return ep.tracer.Start(
+		r.Context(),
+		"smooch webhook",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attributeHTTPMethod.String(r.Method),
+			attributeAppID.String(ep.appID),
+		),
+	)
+}
+
+func setWebhookSpanAttributes(span trace.Span, p *Payload) {
+	span.SetAttributes(
+		attributeTrigger.String(p.Trigger),
+		attributeConversationID.String(p.Conversation.ID),
+		attributeAppUserID.String(p.AppUser.ID),
+		attributeWebhookMessages.Int(len(p.Messages)),
+		attributeWebhookPostback.Int(len(p.Postbacks)),
+	)
+}
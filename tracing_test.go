@@ -0,0 +1,98 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracingOutboundCall(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	fn := func(req *http.Request) *http.Response {
+		assert.True(t, trace.SpanContextFromContext(req.Context()).IsValid())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleGetUserJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:          "app",
+		VerifySecret:   "very-secure-test-secret",
+		HttpClient:     NewTestClient(fn),
+		TracerProvider: tp,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.GetAppUser("123")
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "smooch GET /v1.1/apps/{appId}/appusers/{userId}", spans[0].Name())
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+
+	attrs := spanAttributes(spans[0])
+	assert.Equal(t, "/v1.1/apps/{appId}/appusers/{userId}", attrs[attributeEndpoint].AsString())
+	assert.Equal(t, "app", attrs[attributeAppID].AsString())
+	assert.Equal(t, int64(http.StatusOK), attrs[attributeHTTPStatusCode].AsInt64())
+}
+
+func TestRouteTemplate(t *testing.T) {
+	for path, route := range map[string]string{
+		"/v1.1/apps": "/v1.1/apps",
+		"/v1.1/apps/app/appusers/steve%2Fb/messages":       "/v1.1/apps/{appId}/appusers/{userId}/messages",
+		"/v1.1/apps/app/appusers/123/messages/456":         "/v1.1/apps/{appId}/appusers/{userId}/messages/{messageId}",
+		"/v1.1/apps/app/appusers/123/channels/whatsapp":    "/v1.1/apps/{appId}/appusers/{userId}/channels/{channel}",
+		"/v1.1/whatsapp/deployments/5e9f1cbd/register":     "/v1.1/whatsapp/deployments/{deploymentId}/register",
+		"/v2/apps/app/users/merge":                         "/v2/apps/{appId}/users/merge",
+		"/v2/apps/app/conversations/c7f6e6d6/messages":     "/v2/apps/{appId}/conversations/{conversationId}/messages",
+		"/v1.1/apps/app/integrations/abc/messageTemplates": "/v1.1/apps/{appId}/integrations/{integrationId}/messageTemplates",
+	} {
+		assert.Equal(t, route, routeTemplate(path), path)
+	}
+}
+
+func TestTracingWebhook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	sc, err := New(Options{
+		VerifySecret:   "very-secure-test-secret",
+		TracerProvider: tp,
+	})
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(sampleWebhookData)))
+	req.Header.Set("X-Api-Key", "very-secure-test-secret")
+	w := httptest.NewRecorder()
+	sc.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "smooch webhook", spans[0].Name())
+
+	attrs := spanAttributes(spans[0])
+	assert.Equal(t, TriggerMessageAppUser, attrs[attributeTrigger].AsString())
+	assert.Equal(t, "105e47578be874292d365ee8", attrs[attributeConversationID].AsString())
+	assert.Equal(t, "c7f6e6d6c3a637261bd9656f", attrs[attributeAppUserID].AsString())
+}
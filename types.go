@@ -3,6 +3,9 @@ package smooch
 import (
 	"bytes"
 	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -46,6 +49,26 @@ const (
 	TriggerMessageDeliveryFailure = "message:delivery:failure"
 	TriggerMessageDeliveryChannel = "message:delivery:channel"
 	TriggerMessageDeliveryUser    = "message:delivery:user"
+	TriggerPostback               = "postback"
+	TriggerConversationStart      = "conversation:start"
+	TriggerConversationReferral   = "conversation:referral"
+	TriggerConversationRead       = "conversation:read"
+	TriggerTypingAppUser          = "typing:appUser"
+	TriggerLinkMatch              = "link:match"
+	TriggerLinkSuccess            = "link:success"
+	TriggerLinkFailure            = "link:failure"
+	TriggerPaymentSuccess         = "payment:success"
+	TriggerClientAdd              = "client:add"
+	TriggerClientRemove           = "client:remove"
+	TriggerAppUserDelete          = "appUser:delete"
+	TriggerAppUserMerge           = "merge:appUser"
+
+	// ActionStateOffered and ActionStatePaid are the states of buy actions.
+	ActionStateOffered = "offered"
+	ActionStatePaid    = "paid"
+
+	ActivityTypingStart = "typing:start"
+	ActivityTypingStop  = "typing:stop"
 
 	ImageRatioHorizontal = ImageRatio("horizontal")
 	ImageRatioSquare     = ImageRatio("square")
@@ -66,13 +89,111 @@ type Payload struct {
 	Trigger      string             `json:"trigger,omitempty"`
 	App          Application        `json:"app,omitempty"`
 	Messages     []*Message         `json:"messages,omitempty"`
+	Postbacks    []*Postback        `json:"postbacks,omitempty"`
+	Payments     []*Payment         `json:"payments,omitempty"`
 	AppUser      AppUser            `json:"appUser,omitempty"`
 	Conversation Conversation       `json:"conversation,omitempty"`
+	Client       *AppUserClient     `json:"client,omitempty"`
+	Referral     *Referral          `json:"referral,omitempty"`
+	Activity     *Activity          `json:"activity,omitempty"`
+	Source       *SourceDestination `json:"source,omitempty"`
 	Destination  *SourceDestination `json:"destination,omitempty"`
 	IsFinalEvent bool               `json:"isFinalEvent"`
 	Message      *TruncatedMessage  `json:"message,omitempty"`
 	Error        *Error             `json:"error,omitempty"`
+	Surviving    *AppUser           `json:"surviving,omitempty"`
+	Discarded    []*AppUser         `json:"discarded,omitempty"`
 	Version      string             `json:"version,omitempty"`
+	Timestamp    time.Time          `json:"timestamp,omitempty"`
+	// Raw is the JSON the payload was decoded from, for archiving it or
+	// decoding fields the typed ones don't cover.
+	Raw json.RawMessage `json:"-"`
+}
+
+func (p *Payload) UnmarshalJSON(data []byte) error {
+	type Alias Payload
+	aux := &struct {
+		Timestamp float64 `json:"timestamp"`
+		*Alias
+	}{
+		Alias: (*Alias)(p),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	p.Timestamp = time.Time{}
+	if aux.Timestamp != 0 {
+		p.Timestamp = timeFromSeconds(aux.Timestamp)
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func (p *Payload) MarshalJSON() ([]byte, error) {
+	type Alias Payload
+	aux := &struct {
+		Timestamp float64 `json:"timestamp,omitempty"`
+		*Alias
+	}{
+		Alias: (*Alias)(p),
+	}
+	if !p.Timestamp.IsZero() {
+		aux.Timestamp = float64(p.Timestamp.UnixNano()) / nsMultiplier
+	}
+	return json.Marshal(aux)
+}
+
+// timeFromSeconds converts the fractional seconds timestamps of the API,
+// which have a millisecond precision.
+func timeFromSeconds(seconds float64) time.Time {
+	ms := int64(math.Round(seconds * 1000))
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// Postback is a click on a postback action, delivered with the
+// TriggerPostback webhook. Message is the message holding the action.
+type Postback struct {
+	Message *Message `json:"message,omitempty"`
+	Action  *Action  `json:"action"`
+}
+
+// Payment is a buy action paid by an app user, delivered with the
+// TriggerPaymentSuccess webhook. Message is the message holding the action.
+type Payment struct {
+	Source  *SourceDestination `json:"source,omitempty"`
+	Message *Message           `json:"message,omitempty"`
+	Action  *Action            `json:"action"`
+	Charge  *Charge            `json:"charge,omitempty"`
+}
+
+// Charge is the Stripe charge of a payment.
+type Charge struct {
+	ID string `json:"id"`
+}
+
+// Activity is a conversation activity of the app user, e.g.
+// ActivityTypingStart.
+type Activity struct {
+	Type string `json:"type"`
+}
+
+// Referral is how a user got to a conversation, e.g. from an m.me link or a
+// Click to Messenger ad. It is sent with the TriggerConversationStart and
+// TriggerConversationReferral webhooks.
+type Referral struct {
+	// Code is the ref parameter of the link or ad.
+	Code    string           `json:"code,omitempty"`
+	Details *ReferralDetails `json:"details,omitempty"`
+}
+
+type ReferralDetails struct {
+	// Source is the channel type the referral came from, e.g.
+	// SourceTypeMessenger.
+	Source string `json:"source,omitempty"`
+	// Type is the kind of referral, e.g. shortlink, ads or messenger_code.
+	Type string `json:"type,omitempty"`
+	// AdID is set for referrals from ads.
+	AdID string `json:"adId,omitempty"`
 }
 
 type TruncatedMessage struct {
@@ -109,6 +230,24 @@ type AppUser struct {
 	Surname             string                 `json:"surname,omitempty"`
 }
 
+type AppUserCreate struct {
+	UserID     string                 `json:"userId"`
+	GivenName  string                 `json:"givenName,omitempty"`
+	Surname    string                 `json:"surname,omitempty"`
+	Email      string                 `json:"email,omitempty"`
+	Locale     string                 `json:"locale,omitempty"`
+	SignedUpAt *time.Time             `json:"signedUpAt,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type AppUserUpdate struct {
+	GivenName  string                 `json:"givenName,omitempty"`
+	Surname    string                 `json:"surname,omitempty"`
+	Email      string                 `json:"email,omitempty"`
+	SignedUpAt *time.Time             `json:"signedUpAt,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
 type AppUserClient struct {
 	ID            string                 `json:"_id,omitempty"`
 	Platform      string                 `json:"platform,omitempty"`
@@ -126,22 +265,57 @@ type AppUserClient struct {
 	Blocked       bool                   `json:"blocked"`
 }
 
+// ChannelEntity identifies an app user on a channel. Which identifier is set
+// depends on Type: PhoneNumber for SMS and WhatsApp, UserID for Messenger,
+// LINE, Telegram and Viber, Address for email.
+type ChannelEntity struct {
+	Type        string `json:"type"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+	UserID      string `json:"userId,omitempty"`
+	Address     string `json:"address,omitempty"`
+}
+
+// LinkRequest lets an app user connect an additional channel, either by
+// opening URL or by sending Code to the channel.
+type LinkRequest struct {
+	IntegrationID string    `json:"integrationId"`
+	Type          string    `json:"type"`
+	Code          string    `json:"code"`
+	URL           string    `json:"url"`
+	ExpiresAt     time.Time `json:"expiresAt,omitempty"`
+}
+
+// ClientCreate describes a device to register for an app user. ID is the
+// device identifier chosen by the caller.
+type ClientCreate struct {
+	ID                    string                 `json:"id"`
+	Platform              string                 `json:"platform,omitempty"`
+	IntegrationID         string                 `json:"integrationId,omitempty"`
+	PushNotificationToken string                 `json:"pushNotificationToken,omitempty"`
+	AppVersion            string                 `json:"appVersion,omitempty"`
+	Info                  map[string]interface{} `json:"info,omitempty"`
+}
+
+// Conversation is the conversation a webhook is about. Metadata is set when
+// the conversation has some, e.g. from UpdateConversationMetadata.
 type Conversation struct {
-	ID          string `json:"_id"`
-	UnreadCount int    `json:"unreadCount,omitempty"`
+	ID          string   `json:"_id"`
+	UnreadCount int      `json:"unreadCount,omitempty"`
+	Metadata    Metadata `json:"metadata,omitempty"`
 }
 
 type Action struct {
-	ID       string                 `json:"_id,omitempty"`
-	Type     ActionType             `json:"type,omitempty"`
-	Text     string                 `json:"text,omitempty"`
-	Default  bool                   `json:"default,omitempty"`
-	Payload  string                 `json:"payload,omitempty"`
-	URI      string                 `json:"uri,omitempty"`
-	Amount   int                    `json:"amount,omitempty"`
-	Currency string                 `json:"currency,omitempty"`
-	State    string                 `json:"state,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	ID       string     `json:"_id,omitempty"`
+	Type     ActionType `json:"type,omitempty"`
+	Text     string     `json:"text,omitempty"`
+	Default  bool       `json:"default,omitempty"`
+	Payload  string     `json:"payload,omitempty"`
+	URI      string     `json:"uri,omitempty"`
+	IconURL  string     `json:"iconUrl,omitempty"`
+	Amount   int        `json:"amount,omitempty"`
+	Currency string     `json:"currency,omitempty"`
+	State    string     `json:"state,omitempty"`
+	Metadata Metadata   `json:"metadata,omitempty"`
 }
 
 type Item struct {
@@ -155,19 +329,38 @@ type Item struct {
 }
 
 type Message struct {
-	ID              string                 `json:"_id,omitempty"`
-	Type            MessageType            `json:"type"`
-	Text            string                 `json:"text,omitempty"`
-	Role            Role                   `json:"role"`
-	AuthorID        string                 `json:"authorId,omitempty"`
-	Name            string                 `json:"name,omitempty"`
-	Received        time.Time              `json:"received,omitempty"`
-	Source          *SourceDestination     `json:"source,omitempty"`
-	MediaURL        string                 `json:"mediaUrl,omitempty"`
-	Actions         []*Action              `json:"actions,omitempty"`
-	Items           []*Item                `json:"items,omitempty"`
-	Metadata        map[string]interface{} `json:"metadata,omitempty"`
-	DisplaySettings *DisplaySettings       `json:"displaySettings,omitempty"`
+	ID              string               `json:"_id,omitempty"`
+	Type            MessageType          `json:"type"`
+	Text            string               `json:"text,omitempty"`
+	Role            Role                 `json:"role"`
+	AuthorID        string               `json:"authorId,omitempty"`
+	Name            string               `json:"name,omitempty"`
+	AvatarURL       string               `json:"avatarUrl,omitempty"`
+	Received        time.Time            `json:"received,omitempty"`
+	Source          *SourceDestination   `json:"source,omitempty"`
+	MediaURL        string               `json:"mediaUrl,omitempty"`
+	MediaType       string               `json:"mediaType,omitempty"`
+	MediaSize       int64                `json:"mediaSize,omitempty"`
+	AltText         string               `json:"altText,omitempty"`
+	Actions         []*Action            `json:"actions,omitempty"`
+	Items           []*Item              `json:"items,omitempty"`
+	Payload         string               `json:"payload,omitempty"`
+	TextFallback    string               `json:"textFallback,omitempty"`
+	BlockChatInput  bool                 `json:"blockChatInput,omitempty"`
+	Coordinates     *Coordinates         `json:"coordinates,omitempty"`
+	Location        *Location            `json:"location,omitempty"`
+	Metadata        Metadata             `json:"metadata,omitempty"`
+	DisplaySettings *DisplaySettings     `json:"displaySettings,omitempty"`
+	Override        map[string]*Override `json:"override,omitempty"`
+	QuotedMessage   *QuotedMessage       `json:"quotedMessage,omitempty"`
+	Destination     *MessageDestination  `json:"destination,omitempty"`
+}
+
+// MessageDestination sends a message to one channel of the app user
+// rather than the one Smooch picks.
+type MessageDestination struct {
+	IntegrationID   string `json:"integrationId,omitempty"`
+	IntegrationType string `json:"integrationType,omitempty"`
 }
 
 func (m *Message) UnmarshalJSON(data []byte) error {
@@ -199,6 +392,54 @@ func (m *Message) MarshalJSON() ([]byte, error) {
 	return json.Marshal(aux)
 }
 
+type Coordinates struct {
+	Lat  float64 `json:"lat"`
+	Long float64 `json:"long"`
+}
+
+type Location struct {
+	Address string `json:"address,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+const (
+	QuotedMessageTypeMessage           = "message"
+	QuotedMessageTypeMessageID         = "messageId"
+	QuotedMessageTypeExternalMessageID = "externalMessageId"
+)
+
+// QuotedMessage is the message a message replies to. Inbound replies carry
+// the quoted Message, or only its ExternalMessageID when Smooch doesn't know
+// it. Outbound replies reference it by MessageID and are threaded on the
+// channels supporting it, such as WhatsApp and Telegram.
+type QuotedMessage struct {
+	Type              string   `json:"type"`
+	Message           *Message `json:"message,omitempty"`
+	MessageID         string   `json:"messageId,omitempty"`
+	ExternalMessageID string   `json:"externalMessageId,omitempty"`
+}
+
+// Override replaces the payload Smooch sends to one channel, keyed by the
+// channel type in Message.Override, e.g. SourceTypeWhatsApp. Payload is sent
+// as is, so it has to follow the channel's own API.
+type Override struct {
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ChannelPayload is a typed override payload for a single channel.
+type ChannelPayload interface {
+	Channel() string
+}
+
+// NewOverride encodes payload for Message.Override.
+func NewOverride(payload interface{}) (*Override, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Override{Payload: data}, nil
+}
+
 type MenuPayload struct {
 	Menu Menu `json:"menu"`
 }
@@ -235,10 +476,91 @@ type ResponsePayload struct {
 	Conversation  *Conversation `json:"conversation,omitempty"`
 }
 
+// ResponseData describes the HTTP response of an API call. It is returned for
+// successful and failed calls alike, so unexpected payloads can be inspected.
+type ResponseData struct {
+	HTTPCode  int
+	Header    http.Header
+	Body      []byte
+	RequestID string
+
+	// RateLimit is nil when the response carries no rate limit headers.
+	RateLimit *RateLimit
+}
+
+func newResponseData(r *http.Response, body []byte) *ResponseData {
+	return &ResponseData{
+		HTTPCode:  r.StatusCode,
+		Header:    r.Header,
+		Body:      body,
+		RequestID: r.Header.Get(requestIDHeaderKey),
+		RateLimit: parseRateLimit(r.Header, time.Now()),
+	}
+}
+
 type GetAppUserResponse struct {
 	AppUser *AppUser `json:"appUser,omitempty"`
 }
 
+type GetAppUserChannelsResponse struct {
+	Channels []*ChannelEntity `json:"channels"`
+}
+
+type ClientResponse struct {
+	Client *AppUserClient `json:"client,omitempty"`
+}
+
+type GetLinkRequestsResponse struct {
+	LinkRequests []*LinkRequest `json:"linkRequests"`
+}
+
+// GetMessagesParams selects a page of conversation history. Before and After
+// are message timestamps in seconds and are mutually exclusive; when neither
+// is set the most recent messages are returned.
+type GetMessagesParams struct {
+	Before string
+	After  string
+	Limit  int
+}
+
+type GetMessagesResponse struct {
+	Messages []*Message `json:"messages"`
+	Next     string     `json:"next,omitempty"`
+	Previous string     `json:"previous,omitempty"`
+}
+
+// BeforeCursor returns the cursor pointing at the page of older messages, or
+// an empty string when there are none.
+func (r *GetMessagesResponse) BeforeCursor() string {
+	return cursorFromURL(r.Previous, "before")
+}
+
+// AfterCursor returns the cursor pointing at the page of newer messages, or
+// an empty string when there are none.
+func (r *GetMessagesResponse) AfterCursor() string {
+	return cursorFromURL(r.Next, "after")
+}
+
+func cursorFromURL(rawURL string, key string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get(key)
+}
+
+const (
+	AttachmentAccessPublic = "public"
+	// AttachmentAccessPrivate attachments can only be fetched with an app
+	// token, see SignAttachmentURL.
+	AttachmentAccessPrivate = "private"
+)
+
+// AttachmentUpload describes an upload. When MIMEType is empty, it is
+// guessed from the file extension, or else from the content.
 type AttachmentUpload struct {
 	MIMEType string
 	Access   string
@@ -247,12 +569,17 @@ type AttachmentUpload struct {
 	For       string
 	AppUserID string
 	UserID    string
+
+	// Channel, when set, is the channel the attachment will be sent to. The
+	// upload fails with an *AttachmentConstraintError before anything is
+	// sent when it doesn't meet the channel's constraints.
+	Channel string
 }
 
 func NewAttachmentUpload(mime string) AttachmentUpload {
 	return AttachmentUpload{
 		MIMEType: mime,
-		Access:   "public",
+		Access:   AttachmentAccessPublic,
 	}
 }
 
@@ -261,6 +588,19 @@ type Attachment struct {
 	MediaType string `json:"mediaType,omitempty"`
 }
 
+// ListAttachmentsParams selects a page of uploaded attachments. AppUserID
+// restricts the listing to attachments uploaded for that app user.
+type ListAttachmentsParams struct {
+	Limit     int
+	Offset    int
+	AppUserID string
+}
+
+type ListAttachmentsResponse struct {
+	Attachments []*Attachment `json:"attachments"`
+	HasMore     bool          `json:"hasMore"`
+}
+
 type BytesFileReader struct {
 	*bytes.Reader
 	Filename string
@@ -106,6 +106,7 @@ func TestPayloadEncode(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, p.Messages, 1)
 	p.Messages[0].Received = time.Unix(1444348340, 420*nsMultiplier)
+	p.Timestamp = time.Unix(1480001711, 941*int64(time.Millisecond))
 
 	data, err := json.Marshal(p)
 	assert.NoError(t, err)
@@ -116,6 +117,127 @@ func TestPayloadEncode(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, payload.Messages, 1)
 	assert.Equal(t, payload.Messages[0].Received, time.Unix(1444348340, 420*nsMultiplier))
+	assert.Equal(t, p.Timestamp, payload.Timestamp)
+}
+
+func TestLocationMessageDecode(t *testing.T) {
+	data := `{
+		"_id": "5c8a8f6b4ef0c7002f3c8e6a",
+		"type": "location",
+		"role": "appUser",
+		"text": "Location shared:\nhttps://maps.google.com/maps?q=45.5261583,-73.595346",
+		"coordinates": {"lat": 45.5261583, "long": -73.595346},
+		"location": {"address": "5333 Avenue Casgrain, Montreal", "name": "Zendesk"},
+		"received": 1444348338.704
+	}`
+
+	message := &Message{}
+	err := json.Unmarshal([]byte(data), message)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeLocation, message.Type)
+	assert.Equal(t, &Coordinates{Lat: 45.5261583, Long: -73.595346}, message.Coordinates)
+	assert.Equal(t, "Zendesk", message.Location.Name)
+
+	encoded, err := json.Marshal(message)
+	assert.NoError(t, err)
+	decoded := &Message{}
+	assert.NoError(t, json.Unmarshal(encoded, decoded))
+	assert.Equal(t, message.Coordinates, decoded.Coordinates)
+	assert.Equal(t, message.Location, decoded.Location)
+}
+
+func TestLocationWebhookDecode(t *testing.T) {
+	data := `{
+		"trigger": "message:appUser",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"messages": [{
+			"_id": "5c8a8f6b4ef0c7002f3c8e6c",
+			"type": "location",
+			"role": "appUser",
+			"text": "Location shared:\nhttps://maps.google.com/maps?q=54.687157,25.279652",
+			"coordinates": {"lat": 54.687157, "long": 25.279652},
+			"source": {"type": "whatsapp"},
+			"received": 1444348338
+		}]
+	}`
+
+	payload := &Payload{}
+	err := json.Unmarshal([]byte(data), payload)
+	assert.NoError(t, err)
+	assert.Len(t, payload.Messages, 1)
+
+	message := payload.Messages[0]
+	assert.Equal(t, SourceTypeWhatsApp, message.Source.Type)
+	assert.Equal(t, &Coordinates{Lat: 54.687157, Long: 25.279652}, message.Coordinates)
+	// WhatsApp shares without a place name don't have a location
+	assert.Nil(t, message.Location)
+}
+
+func TestQuotedMessageDecode(t *testing.T) {
+	data := `{
+		"_id": "5c8a8f6b4ef0c7002f3c8e6b",
+		"type": "text",
+		"role": "appUser",
+		"text": "Yes, that one",
+		"received": 1444348338.704,
+		"quotedMessage": {
+			"type": "message",
+			"message": {
+				"_id": "5c8a8f6b4ef0c7002f3c8e6a",
+				"type": "text",
+				"role": "appMaker",
+				"text": "Do you want the sea view room?",
+				"received": 1444348330.1
+			}
+		}
+	}`
+
+	message := &Message{}
+	err := json.Unmarshal([]byte(data), message)
+	assert.NoError(t, err)
+	assert.Equal(t, QuotedMessageTypeMessage, message.QuotedMessage.Type)
+	assert.Equal(t, "5c8a8f6b4ef0c7002f3c8e6a", message.QuotedMessage.Message.ID)
+	assert.Equal(t, RoleAppMaker, message.QuotedMessage.Message.Role)
+
+	data = `{
+		"type": "text",
+		"role": "appUser",
+		"text": "Yes",
+		"received": 1444348338.704,
+		"quotedMessage": {"type": "externalMessageId", "externalMessageId": "wamid.HBgLMTU1"}
+	}`
+	message = &Message{}
+	err = json.Unmarshal([]byte(data), message)
+	assert.NoError(t, err)
+	assert.Nil(t, message.QuotedMessage.Message)
+	assert.Equal(t, "wamid.HBgLMTU1", message.QuotedMessage.ExternalMessageID)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	data := `{
+		"_id": "55c8c1498590aa1900b9b9b1",
+		"authorId": "c7f6e6d6c3a637261bd9656f",
+		"role": "appUser",
+		"type": "text",
+		"name": "Steve",
+		"avatarUrl": "https://www.gravatar.com/image.jpg",
+		"text": "Large",
+		"payload": "SIZE_L",
+		"textFallback": "Large please",
+		"blockChatInput": true,
+		"mediaSize": 1024,
+		"altText": "A large hat",
+		"received": 1444348338
+	}`
+
+	message := &Message{}
+	assert.NoError(t, json.Unmarshal([]byte(data), message))
+	assert.Equal(t, "https://www.gravatar.com/image.jpg", message.AvatarURL)
+	assert.Equal(t, "SIZE_L", message.Payload)
+
+	encoded, err := json.Marshal(message)
+	assert.NoError(t, err)
+	assert.JSONEq(t, data, string(encoded))
 }
 
 func TestErrorPayloadDecode(t *testing.T) {
@@ -131,6 +253,7 @@ func TestErrorPayloadDecode(t *testing.T) {
 	assert.Equal(t, "2018-04-02T14:45:46Z", payload.AppUser.SignedUpAt.Format(time.RFC3339))
 	assert.Equal(t, "105e47578be874292d365ee8", payload.Conversation.ID)
 	assert.Equal(t, true, payload.IsFinalEvent)
+	assert.Equal(t, time.Unix(1480001711, 941*int64(time.Millisecond)), payload.Timestamp)
 	assert.Equal(t, "unauthorized", payload.Error.Code)
 	assert.Equal(t,
 		"Authentication failed due to the following reason: invalid token. Confirm that the access token in the authorization header is valid.",
@@ -139,3 +262,97 @@ func TestErrorPayloadDecode(t *testing.T) {
 
 	assert.Equal(t, "5baa610db5bebb000ce855d6", payload.Message.ID)
 }
+
+func TestPostbackDecode(t *testing.T) {
+	data := `{
+		"trigger": "postback",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+		"postbacks": [{
+			"message": {
+				"_id": "55c8c1498590aa1900b9b9b1",
+				"type": "text",
+				"text": "Book this room?",
+				"role": "appMaker",
+				"received": 1444348338.704
+			},
+			"action": {
+				"_id": "571530ee4fae94c32b78b170",
+				"type": "postback",
+				"text": "Book",
+				"payload": "{\"room\":42}"
+			}
+		}]
+	}`
+
+	payload := &Payload{}
+	err := json.Unmarshal([]byte(data), payload)
+	assert.NoError(t, err)
+	assert.Equal(t, TriggerPostback, payload.Trigger)
+	assert.Len(t, payload.Postbacks, 1)
+
+	postback := payload.Postbacks[0]
+	assert.Equal(t, "55c8c1498590aa1900b9b9b1", postback.Message.ID)
+
+	var room struct {
+		Room int `json:"room"`
+	}
+	assert.NoError(t, postback.DecodePayload(&room))
+	assert.Equal(t, 42, room.Room)
+
+	sent, err := NewPostbackActionJSON("Book", map[string]int{"room": 42})
+	assert.NoError(t, err)
+	assert.True(t, postback.Matches(sent))
+	sent.ID = "571530ee4fae94c32b78b170"
+	assert.True(t, postback.Matches(sent))
+	sent.ID = "571530ee4fae94c32b78b171"
+	assert.False(t, postback.Matches(sent))
+
+	assert.Equal(t, ErrPostbackActionEmpty, (&Postback{}).DecodePayload(&room))
+}
+
+func TestReferralDecode(t *testing.T) {
+	data := `{
+		"trigger": "conversation:referral",
+		"app": {"_id": "5698edbf2a43bd081be982f1"},
+		"appUser": {"_id": "c7f6e6d6c3a637261bd9656f"},
+		"conversation": {"_id": "105e47578be874292d365ee8"},
+		"referral": {
+			"code": "summer-sale",
+			"details": {"source": "messenger", "type": "ads", "adId": "6045246247068"}
+		},
+		"timestamp": 1480001711.941,
+		"version": "v1.1"
+	}`
+
+	payload := &Payload{}
+	err := json.Unmarshal([]byte(data), payload)
+	assert.NoError(t, err)
+	assert.Equal(t, TriggerConversationReferral, payload.Trigger)
+	assert.Equal(t, &Referral{
+		Code: "summer-sale",
+		Details: &ReferralDetails{
+			Source: SourceTypeMessenger,
+			Type:   "ads",
+			AdID:   "6045246247068",
+		},
+	}, payload.Referral)
+}
+
+func TestPayloadRaw(t *testing.T) {
+	payload := &Payload{}
+	err := json.Unmarshal([]byte(payloadExample1), payload)
+	assert.NoError(t, err)
+	assert.JSONEq(t, payloadExample1, string(payload.Raw))
+
+	var extra struct {
+		Version string `json:"version"`
+	}
+	assert.NoError(t, json.Unmarshal(payload.Raw, &extra))
+	assert.Equal(t, "v1.1", extra.Version)
+
+	// Raw is not encoded back
+	data, err := json.Marshal(payload)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), `"Raw"`)
+}
@@ -0,0 +1,324 @@
+package smooch
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// defaultMaxUploadSize is used when Options.MaxUploadSize is not set.
+const defaultMaxUploadSize = 50 << 20
+
+type multipartField struct {
+	name     string
+	filename string
+	r        io.Reader
+	size     int64
+	offset   int64
+}
+
+// multipartBody streams form fields as multipart/form-data without
+// buffering them. The size is known up front when every field reports its
+// length, and the body can be replayed for retries when every field can
+// seek.
+type multipartBody struct {
+	boundary string
+	fields   []*multipartField
+	size     int64
+	maxSize  int64
+	seekable bool
+
+	last *lazyPipe
+}
+
+func newMultipartBody(values map[string]io.Reader, maxSize int64) (*multipartBody, error) {
+	b := &multipartBody{
+		boundary: multipart.NewWriter(ioutil.Discard).Boundary(),
+		maxSize:  maxSize,
+		seekable: true,
+	}
+
+	var contentSize int64
+	sizeKnown := true
+	for name, r := range values {
+		field := &multipartField{
+			name: name,
+			r:    r,
+			size: readerSize(r),
+		}
+		if filename := readerFilename(r); filename != "" {
+			field.filename = filepath.Base(filename)
+		}
+
+		if field.size < 0 {
+			sizeKnown = false
+		} else {
+			contentSize += field.size
+		}
+
+		if s, ok := r.(io.Seeker); ok {
+			offset, err := s.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			field.offset = offset
+		} else {
+			b.seekable = false
+		}
+
+		b.fields = append(b.fields, field)
+	}
+
+	// plain fields first, so the server sees them before the file content
+	sort.Slice(b.fields, func(i, j int) bool {
+		fi, fj := b.fields[i], b.fields[j]
+		if (fi.filename == "") != (fj.filename == "") {
+			return fi.filename == ""
+		}
+		return fi.name < fj.name
+	})
+
+	if maxSize > 0 && contentSize > maxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	b.size = -1
+	if sizeKnown {
+		overhead := &countingWriter{}
+		err := b.write(overhead, false)
+		if err != nil {
+			return nil, err
+		}
+		b.size = overhead.n + contentSize
+	}
+
+	return b, nil
+}
+
+func (b *multipartBody) contentType() string {
+	return "multipart/form-data; boundary=" + b.boundary
+}
+
+// reader returns a new stream of the body. The fields are read from where
+// they were when the body was created, so every call after the first one
+// seeks them back.
+func (b *multipartBody) reader() (io.ReadCloser, error) {
+	if b.last != nil {
+		// the previous stream must stop reading before the fields are rewound
+		b.last.wait()
+	}
+
+	for _, field := range b.fields {
+		if s, ok := field.r.(io.Seeker); ok {
+			if _, err := s.Seek(field.offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b.last = &lazyPipe{write: func(w io.Writer) error {
+		return b.write(w, true)
+	}}
+	return b.last, nil
+}
+
+func (b *multipartBody) write(dst io.Writer, content bool) error {
+	w := multipart.NewWriter(dst)
+	err := w.SetBoundary(b.boundary)
+	if err != nil {
+		return err
+	}
+
+	limit := &limitedReader{remaining: b.maxSize}
+	for _, field := range b.fields {
+		var fw io.Writer
+		if field.filename != "" {
+			fw, err = w.CreateFormFile(field.name, field.filename)
+		} else {
+			fw, err = w.CreateFormField(field.name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !content {
+			continue
+		}
+
+		var r io.Reader = field.r
+		if b.maxSize > 0 {
+			limit.r = field.r
+			r = limit
+		}
+		if _, err = io.Copy(fw, r); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// detectMIMEType guesses the MIME type of r from its file name extension,
+// falling back to sniffing the first 512 bytes. The returned reader must be
+// used in place of r, as sniffing may have consumed some of it.
+func detectMIMEType(r io.Reader) (string, io.Reader, error) {
+	if t := mime.TypeByExtension(filepath.Ext(readerFilename(r))); t != "" {
+		return stripMIMEParams(t), r, nil
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	head = head[:n]
+	t := stripMIMEParams(http.DetectContentType(head))
+
+	if s, ok := r.(io.Seeker); ok {
+		_, err = s.Seek(int64(-n), io.SeekCurrent)
+		return t, r, err
+	}
+	mr := io.MultiReader(bytes.NewReader(head), r)
+	if filename := readerFilename(r); filename != "" {
+		// keep the name, so the reader is still sent as a file
+		return t, &namedReader{Reader: mr, filename: filename}, nil
+	}
+	return t, mr, nil
+}
+
+// stripMIMEParams drops parameters such as charset, which the API does not
+// accept in the attachment type.
+func stripMIMEParams(t string) string {
+	mediaType, _, err := mime.ParseMediaType(t)
+	if err != nil {
+		return t
+	}
+	return mediaType
+}
+
+// namedReader gives a file name to a reader that has none, so it is sent as
+// a file rather than as a plain form field.
+type namedReader struct {
+	io.Reader
+	filename string
+}
+
+func readerFilename(r io.Reader) string {
+	switch x := r.(type) {
+	case *os.File:
+		return x.Name()
+	case *BytesFileReader:
+		return x.Filename
+	case *namedReader:
+		return x.filename
+	}
+	return ""
+}
+
+// readerSize returns the number of bytes left in r, or -1 when it can't be
+// known without reading.
+func readerSize(r io.Reader) int64 {
+	switch x := r.(type) {
+	case interface{ Len() int }:
+		return int64(x.Len())
+	case *os.File:
+		info, err := x.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := x.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+// lazyPipe starts writing into the pipe on the first Read, so a body that is
+// never sent doesn't leave a goroutine behind.
+type lazyPipe struct {
+	write func(w io.Writer) error
+
+	mu     sync.Mutex
+	pr     *io.PipeReader
+	closed bool
+	done   chan struct{}
+}
+
+func (p *lazyPipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	if p.pr == nil {
+		pr, pw := io.Pipe()
+		p.pr = pr
+		p.done = make(chan struct{})
+		go func() {
+			defer close(p.done)
+			pw.CloseWithError(p.write(pw))
+		}()
+	}
+	pr := p.pr
+	p.mu.Unlock()
+
+	return pr.Read(b)
+}
+
+func (p *lazyPipe) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	pr := p.pr
+	p.mu.Unlock()
+
+	if pr == nil {
+		return nil
+	}
+	return pr.Close()
+}
+
+// wait closes the pipe and waits until the writer is done with the fields.
+func (p *lazyPipe) wait() {
+	p.Close()
+
+	p.mu.Lock()
+	done := p.done
+	p.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// limitedReader fails with ErrUploadTooLarge once more than remaining bytes
+// have been read. It is shared by all fields so the limit applies to the
+// whole upload.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrUploadTooLarge
+	}
+	return n, err
+}
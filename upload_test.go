@@ -0,0 +1,278 @@
+package smooch
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func readUpload(t *testing.T, req *http.Request) (*multipart.Form, []byte) {
+	data, err := ioutil.ReadAll(req.Body)
+	assert.NoError(t, err)
+
+	_, params, err := mime.ParseMediaType(req.Header.Get(contentTypeHeaderKey))
+	assert.NoError(t, err)
+
+	form, err := multipart.NewReader(bytes.NewReader(data), params["boundary"]).ReadForm(1 << 20)
+	assert.NoError(t, err)
+	return form, data
+}
+
+func TestUploadAttachmentContentLength(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		form, data := readUpload(t, req)
+		assert.Equal(t, int64(len(data)), req.ContentLength)
+		assert.Equal(t, "image/png", form.Value["type"][0])
+		assert.Equal(t, "smooch.png", form.File["source"][0].Filename)
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleUploadAttachmentJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	r := NewBytesFileReader("images/smooch.png", bytes.Repeat([]byte{1}, 4096))
+	_, _, err = sc.UploadAttachment(r, NewAttachmentUpload("image/png"))
+	assert.NoError(t, err)
+}
+
+func TestUploadAttachmentStreamsUnknownSize(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, int64(0), req.ContentLength)
+		assert.Nil(t, req.GetBody)
+
+		form, _ := readUpload(t, req)
+		assert.Equal(t, "streamed content", form.Value["source"][0])
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleUploadAttachmentJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	r := io.MultiReader(strings.NewReader("streamed "), strings.NewReader("content"))
+	_, _, err = sc.UploadAttachment(r, NewAttachmentUpload("text/plain"))
+	assert.NoError(t, err)
+}
+
+func TestUploadAttachmentRetryReplaysBody(t *testing.T) {
+	var bodies [][]byte
+	fn := func(req *http.Request) *http.Response {
+		_, data := readUpload(t, req)
+		bodies = append(bodies, data)
+
+		if len(bodies) == 1 {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+			}
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleUploadAttachmentJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+		RetryPolicy: &ExponentialBackoffPolicy{
+			MaxAttempts: 2,
+			BaseDelay:   time.Millisecond,
+		},
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.UploadFileAttachment("fixtures/smooch.png", NewAttachmentUpload("image/png"))
+	assert.NoError(t, err)
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+}
+
+func TestUploadAttachmentMaxSize(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		t.Error("request should not be sent")
+		return nil
+	}
+
+	sc, err := New(Options{
+		VerifySecret:  "very-secure-test-secret",
+		HttpClient:    NewTestClient(fn),
+		MaxUploadSize: 1024,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.UploadFileAttachment("fixtures/smooch.png", NewAttachmentUpload("image/png"))
+	assert.Equal(t, ErrUploadTooLarge, err)
+
+	// without a known size the limit is enforced while streaming
+	body, err := newMultipartBody(map[string]io.Reader{
+		"source": io.MultiReader(bytes.NewReader(make([]byte, 2048))),
+	}, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), body.size)
+
+	r, err := body.reader()
+	assert.NoError(t, err)
+	_, err = ioutil.ReadAll(r)
+	assert.Equal(t, ErrUploadTooLarge, err)
+}
+
+func TestDetectMIMEType(t *testing.T) {
+	png, err := ioutil.ReadFile("fixtures/smooch.png")
+	assert.NoError(t, err)
+
+	// extension wins over content
+	mimeType, r, err := detectMIMEType(NewBytesFileReader("report.pdf", png))
+	assert.NoError(t, err)
+	assert.Equal(t, "application/pdf", mimeType)
+
+	// sniffing rewinds seekable readers
+	fbr := NewBytesFileReader("smooch", png)
+	mimeType, r, err = detectMIMEType(fbr)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, fbr, r)
+	assert.Equal(t, len(png), fbr.Len())
+
+	// and keeps the sniffed bytes of the others
+	mimeType, r, err = detectMIMEType(io.MultiReader(strings.NewReader("hello world")))
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", mimeType)
+	content, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(content))
+}
+
+func TestUploadAttachmentDetectsMIMEType(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		form, _ := readUpload(t, req)
+		assert.Equal(t, "image/png", form.Value["type"][0])
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleUploadAttachmentJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	_, _, err = sc.UploadFileAttachment("fixtures/smooch.png", NewAttachmentUpload(""))
+	assert.NoError(t, err)
+}
+
+func TestSendFileMessageNonSeekable(t *testing.T) {
+	png, err := ioutil.ReadFile("fixtures/smooch.png")
+	assert.NoError(t, err)
+
+	fn := func(req *http.Request) *http.Response {
+		body := sampleResponse
+		if strings.HasSuffix(req.URL.Path, "/attachments") {
+			form, _ := readUpload(t, req)
+			assert.Equal(t, "image/png", form.Value["type"][0])
+			if assert.Len(t, form.File["source"], 1) {
+				assert.Equal(t, "smooch", form.File["source"][0].Filename)
+				f, err := form.File["source"][0].Open()
+				assert.NoError(t, err)
+				content, err := ioutil.ReadAll(f)
+				assert.NoError(t, err)
+				assert.Equal(t, png, content)
+			}
+			assert.Empty(t, form.Value["source"])
+			body = sampleUploadAttachmentJson
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	// the name has no extension, so the type is sniffed from the content
+	r := io.MultiReader(bytes.NewReader(png))
+	_, _, err = sc.SendFileMessage("TestUser", r, "smooch", "", "")
+	assert.NoError(t, err)
+}
+
+func TestSignAttachmentURL(t *testing.T) {
+	sc, err := New(Options{
+		KeyID:        "app_5723a347f82ba0516cb4ea34",
+		Secret:       "secret",
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	mediaURL := "https://media.smooch.io/apps/app/private/test.pdf?v=1"
+	signed, err := sc.SignAttachmentURL(mediaURL, time.Minute)
+	assert.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "/apps/app/private/test.pdf", u.Path)
+	assert.Equal(t, "1", u.Query().Get("v"))
+
+	token, err := jwt.Parse(u.Query().Get(attachmentTokenQueryKey), func(token *jwt.Token) (interface{}, error) {
+		assert.Equal(t, "app_5723a347f82ba0516cb4ea34", token.Header["kid"])
+		return []byte("secret"), nil
+	})
+	assert.NoError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	assert.Equal(t, JWTScopeApp, claims["scope"])
+	assert.InDelta(t, time.Now().Add(time.Minute).Unix(), claims["exp"], 5)
+
+	_, err = sc.SignAttachmentURL("", time.Minute)
+	assert.Equal(t, ErrMediaURLEmpty, err)
+}
+
+func TestUploadPrivateAttachment(t *testing.T) {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, AttachmentAccessPrivate, req.URL.Query().Get("access"))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleUploadAttachmentJson))),
+		}
+	}
+
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+
+	upload := NewAttachmentUpload("image/png")
+	upload.Access = AttachmentAccessPrivate
+	_, _, err = sc.UploadFileAttachment("fixtures/smooch.png", upload)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,424 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	LinkConfirmationImmediate    = "immediate"
+	LinkConfirmationUserActivity = "userActivity"
+	LinkConfirmationPrompt       = "prompt"
+)
+
+// User is a user of the v2 API. Unlike v1.1 app users, users are addressed
+// by id or by external id and their channels are clients linked to them.
+type User struct {
+	ID           string          `json:"id"`
+	ExternalID   string          `json:"externalId,omitempty"`
+	SignedUpAt   time.Time       `json:"signedUpAt,omitempty"`
+	ToBeRetained bool            `json:"toBeRetained,omitempty"`
+	Profile      UserProfile     `json:"profile"`
+	Metadata     Metadata        `json:"metadata,omitempty"`
+	Identities   []*UserIdentity `json:"identities,omitempty"`
+}
+
+type UserProfile struct {
+	GivenName string `json:"givenName,omitempty"`
+	Surname   string `json:"surname,omitempty"`
+	Email     string `json:"email,omitempty"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+	Locale    string `json:"locale,omitempty"`
+}
+
+// UserIdentity is a verified identity of a user, e.g. an email address.
+type UserIdentity struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type UserCreate struct {
+	ExternalID string       `json:"externalId"`
+	Profile    *UserProfile `json:"profile,omitempty"`
+	Metadata   Metadata     `json:"metadata,omitempty"`
+}
+
+// UserUpdate holds the fields to change; the others are kept.
+type UserUpdate struct {
+	Profile  *UserProfile `json:"profile,omitempty"`
+	Metadata Metadata     `json:"metadata,omitempty"`
+}
+
+// Device is an SDK device of a user.
+type Device struct {
+	ID                    string                 `json:"id"`
+	Type                  string                 `json:"type,omitempty"`
+	GUID                  string                 `json:"guid,omitempty"`
+	ClientID              string                 `json:"clientId,omitempty"`
+	Status                string                 `json:"status,omitempty"`
+	IntegrationID         string                 `json:"integrationId,omitempty"`
+	LastSeen              time.Time              `json:"lastSeen,omitempty"`
+	PushNotificationToken string                 `json:"pushNotificationToken,omitempty"`
+	AppVersion            string                 `json:"appVersion,omitempty"`
+	Info                  map[string]interface{} `json:"info,omitempty"`
+}
+
+// UserClient is a channel a user is linked to.
+type UserClient struct {
+	ID            string                 `json:"id"`
+	Type          string                 `json:"type"`
+	Status        string                 `json:"status,omitempty"`
+	IntegrationID string                 `json:"integrationId,omitempty"`
+	ExternalID    string                 `json:"externalId,omitempty"`
+	DisplayName   string                 `json:"displayName,omitempty"`
+	AvatarURL     string                 `json:"avatarUrl,omitempty"`
+	LastSeen      time.Time              `json:"lastSeen,omitempty"`
+	LinkedAt      time.Time              `json:"linkedAt,omitempty"`
+	Info          map[string]interface{} `json:"info,omitempty"`
+	Raw           map[string]interface{} `json:"raw,omitempty"`
+}
+
+// ClientLink links a user to a channel, identified by a phone number or an
+// address depending on Type. Confirmation is one of the
+// LinkConfirmation* constants.
+type ClientLink struct {
+	Type          string
+	IntegrationID string
+	PhoneNumber   string
+	Address       string
+	GivenName     string
+	Surname       string
+	Confirmation  string
+}
+
+func (l ClientLink) MarshalJSON() ([]byte, error) {
+	type confirmation struct {
+		Type string `json:"type"`
+	}
+	confirmationType := l.Confirmation
+	if confirmationType == "" {
+		confirmationType = LinkConfirmationImmediate
+	}
+	return json.Marshal(struct {
+		Type          string       `json:"type"`
+		IntegrationID string       `json:"integrationId,omitempty"`
+		PhoneNumber   string       `json:"phoneNumber,omitempty"`
+		Address       string       `json:"address,omitempty"`
+		GivenName     string       `json:"givenName,omitempty"`
+		Surname       string       `json:"surname,omitempty"`
+		Confirmation  confirmation `json:"confirmation"`
+	}{l.Type, l.IntegrationID, l.PhoneNumber, l.Address, l.GivenName, l.Surname, confirmation{confirmationType}})
+}
+
+type UserResponse struct {
+	User *User `json:"user,omitempty"`
+}
+
+type ListDevicesResponse struct {
+	Devices []*Device `json:"devices"`
+}
+
+type ListUserClientsResponse struct {
+	Clients []*UserClient `json:"clients"`
+}
+
+type UserClientResponse struct {
+	Client *UserClient `json:"client,omitempty"`
+}
+
+func (sc *smoochClient) CreateUser(user UserCreate, opts ...RequestOption) (*User, *ResponseData, error) {
+	if user.ExternalID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users", sc.appID),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response UserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.User, respData, nil
+}
+
+// GetUser gets a user by id or by external id.
+func (sc *smoochClient) GetUser(userID string, opts ...RequestOption) (*User, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response UserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.User, respData, nil
+}
+
+func (sc *smoochClient) UpdateUser(userID string, update UserUpdate, opts ...RequestOption) (*User, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(update)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPatch, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response UserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return response.User, respData, nil
+}
+
+// DeleteUser deletes the user along with their conversations and clients.
+func (sc *smoochClient) DeleteUser(userID string, opts ...RequestOption) (*ResponseData, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	respData, err := sc.sendRequest(req, nil)
+	if err != nil {
+		return respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return respData, nil
+}
+
+// DeleteUserPersonalInformation scrubs the profile and the client info of
+// the user while keeping the user and their conversations.
+func (sc *smoochClient) DeleteUserPersonalInformation(userID string, opts ...RequestOption) (*User, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s/personalinformation", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response UserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return response.User, respData, nil
+}
+
+func (sc *smoochClient) ListUserDevices(userID string, opts ...RequestOption) ([]*Device, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s/devices", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListDevicesResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Devices, respData, nil
+}
+
+func (sc *smoochClient) ListUserClients(userID string, opts ...RequestOption) ([]*UserClient, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s/clients", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodGet, url, nil, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response ListUserClientsResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return response.Clients, respData, nil
+}
+
+// LinkUserClient links the user to a channel. Unless the confirmation is
+// immediate, the client stays pending until the user confirms.
+func (sc *smoochClient) LinkUserClient(userID string, link ClientLink, opts ...RequestOption) (*UserClient, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s/clients", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(link)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response UserClientResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return response.Client, respData, nil
+}
+
+func (sc *smoochClient) UnlinkUserClient(userID string, clientID string, opts ...RequestOption) (*ResponseData, error) {
+	if userID == "" {
+		return nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/%s/clients/%s", sc.appID, url.PathEscape(userID), url.PathEscape(clientID)),
+		ro.queryParams(nil),
+	)
+
+	req, err := sc.createRequest(http.MethodDelete, url, nil, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	respData, err := sc.sendRequest(req, nil)
+	if err != nil {
+		return respData, err
+	}
+
+	sc.invalidateAppUser(userID)
+	return respData, nil
+}
+
+// MergeUsers merges the discarded user into the surviving one, which keeps
+// its id and gets the clients, conversations and metadata of both. Use it
+// when the same person turns out to have two users, e.g. once they log in.
+func (sc *smoochClient) MergeUsers(survivingID string, discardedID string, opts ...RequestOption) (*User, *ResponseData, error) {
+	if survivingID == "" || discardedID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v2/apps/%s/users/merge", sc.appID),
+		ro.queryParams(nil),
+	)
+
+	type userRef struct {
+		ID string `json:"id"`
+	}
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(struct {
+		Surviving userRef `json:"surviving"`
+		Discarded userRef `json:"discarded"`
+	}{userRef{survivingID}, userRef{discardedID}})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response UserResponse
+	respData, err := sc.sendRequest(req, &response)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	sc.invalidateAppUser(survivingID, discardedID)
+	return response.User, respData, nil
+}
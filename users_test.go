@@ -0,0 +1,158 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	sampleUserJson = `
+	{
+		"user": {
+			"id": "7494535bff5cef41a15be74d",
+			"externalId": "your-own-id",
+			"signedUpAt": "2020-05-21T15:31:00.000Z",
+			"toBeRetained": true,
+			"profile": {
+				"givenName": "Jane",
+				"surname": "Doe",
+				"email": "jane@example.com",
+				"locale": "en-US"
+			},
+			"metadata": {"tier": "gold"},
+			"identities": [{"type": "email", "value": "jane@example.com"}]
+		}
+	}`
+
+	sampleUserClientJson = `
+	{
+		"client": {
+			"id": "5c9368b8dd56b8000fd62b8b",
+			"type": "twilio",
+			"status": "pending",
+			"integrationId": "5a3174a1c92ed3a7d3eb5b21"
+		}
+	}`
+)
+
+func newUserTestClient(t *testing.T, fn func(req *http.Request, body string) string) *smoochClient {
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient: NewTestClient(func(req *http.Request) *http.Response {
+			var body []byte
+			if req.Body != nil {
+				body, _ = ioutil.ReadAll(req.Body)
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(fn(req, string(body))))),
+			}
+		}),
+	})
+	assert.NoError(t, err)
+	return sc
+}
+
+func TestUserCRUD(t *testing.T) {
+	sc := newUserTestClient(t, func(req *http.Request, body string) string {
+		switch req.Method {
+		case http.MethodPost:
+			assert.Equal(t, "/v2/apps/app/users", req.URL.Path)
+			assert.JSONEq(t, `{"externalId": "your-own-id", "profile": {"givenName": "Jane"}}`, body)
+		case http.MethodPatch:
+			assert.Equal(t, "/v2/apps/app/users/your-own-id", req.URL.Path)
+			assert.JSONEq(t, `{"metadata": {"tier": "gold"}}`, body)
+		case http.MethodDelete:
+			assert.Equal(t, "/v2/apps/app/users/7494535bff5cef41a15be74d/personalinformation", req.URL.Path)
+		default:
+			assert.Equal(t, "/v2/apps/app/users/your-own-id", req.URL.Path)
+		}
+		return sampleUserJson
+	})
+
+	user, _, err := sc.CreateUser(UserCreate{ExternalID: "your-own-id", Profile: &UserProfile{GivenName: "Jane"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "7494535bff5cef41a15be74d", user.ID)
+	assert.Equal(t, "Jane", user.Profile.GivenName)
+	tier, _ := user.Metadata.GetString("tier")
+	assert.Equal(t, "gold", tier)
+	assert.Equal(t, "jane@example.com", user.Identities[0].Value)
+	assert.True(t, user.ToBeRetained)
+
+	_, _, err = sc.GetUser("your-own-id")
+	assert.NoError(t, err)
+
+	_, _, err = sc.UpdateUser("your-own-id", UserUpdate{Metadata: Metadata{"tier": "gold"}})
+	assert.NoError(t, err)
+
+	_, _, err = sc.DeleteUserPersonalInformation(user.ID)
+	assert.NoError(t, err)
+
+	_, _, err = sc.GetUser("")
+	assert.Equal(t, ErrUserIDEmpty, err)
+	_, _, err = sc.CreateUser(UserCreate{})
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestUserClients(t *testing.T) {
+	sc := newUserTestClient(t, func(req *http.Request, body string) string {
+		switch req.Method {
+		case http.MethodPost:
+			assert.Equal(t, "/v2/apps/app/users/user/clients", req.URL.Path)
+			assert.JSONEq(t, `{
+				"type": "twilio",
+				"phoneNumber": "+15145555555",
+				"confirmation": {"type": "prompt"}
+			}`, body)
+			return sampleUserClientJson
+		case http.MethodDelete:
+			assert.Equal(t, "/v2/apps/app/users/user/clients/5c9368b8dd56b8000fd62b8b", req.URL.Path)
+			return `{}`
+		}
+		if req.URL.Path == "/v2/apps/app/users/user/devices" {
+			return `{"devices": [{"id": "device", "type": "ios", "status": "active"}]}`
+		}
+		assert.Equal(t, "/v2/apps/app/users/user/clients", req.URL.Path)
+		return `{"clients": [{"id": "5c9368b8dd56b8000fd62b8b", "type": "twilio", "status": "active"}]}`
+	})
+
+	client, _, err := sc.LinkUserClient("user", ClientLink{
+		Type:         SourceTypeTwilio,
+		PhoneNumber:  "+15145555555",
+		Confirmation: LinkConfirmationPrompt,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", client.Status)
+
+	clients, _, err := sc.ListUserClients("user")
+	assert.NoError(t, err)
+	assert.Len(t, clients, 1)
+
+	devices, _, err := sc.ListUserDevices("user")
+	assert.NoError(t, err)
+	assert.Equal(t, "ios", devices[0].Type)
+
+	_, err = sc.UnlinkUserClient("user", client.ID)
+	assert.NoError(t, err)
+}
+
+func TestMergeUsers(t *testing.T) {
+	sc := newUserTestClient(t, func(req *http.Request, body string) string {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/v2/apps/app/users/merge", req.URL.Path)
+		assert.JSONEq(t, `{"surviving": {"id": "kept"}, "discarded": {"id": "gone"}}`, body)
+		return sampleUserJson
+	})
+
+	user, _, err := sc.MergeUsers("kept", "gone")
+	assert.NoError(t, err)
+	assert.Equal(t, "your-own-id", user.ExternalID)
+
+	_, _, err = sc.MergeUsers("kept", "")
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
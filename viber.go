@@ -0,0 +1,95 @@
+package smooch
+
+import "encoding/json"
+
+const (
+	ViberActionReply          = "reply"
+	ViberActionOpenURL        = "open-url"
+	ViberActionLocationPicker = "location-picker"
+	ViberActionSharePhone     = "share-phone"
+	ViberActionNone           = "none"
+
+	// viberRichMediaMinAPIVersion is the first Viber API version rendering
+	// rich media messages.
+	viberRichMediaMinAPIVersion = 2
+)
+
+// ViberOverride is the override payload of Viber. Keyboard is shown below
+// the message, RichMedia replaces it with a carousel of buttons.
+type ViberOverride struct {
+	Type          string          `json:"type,omitempty"`
+	MinAPIVersion int             `json:"min_api_version,omitempty"`
+	AltText       string          `json:"alt_text,omitempty"`
+	Keyboard      *ViberKeyboard  `json:"keyboard,omitempty"`
+	RichMedia     *ViberRichMedia `json:"rich_media,omitempty"`
+}
+
+func (ViberOverride) Channel() string { return SourceTypeViber }
+
+// NewViberKeyboardOverride returns an override showing buttons as a custom
+// keyboard.
+func NewViberKeyboardOverride(buttons ...*ViberButton) *ViberOverride {
+	return &ViberOverride{
+		Keyboard: &ViberKeyboard{Buttons: buttons},
+	}
+}
+
+// NewViberRichMediaOverride returns an override sending buttons as a rich
+// media carousel, with a grid of columns by rows per carousel item. altText
+// is shown by clients that can't render rich media.
+func NewViberRichMediaOverride(columns int, rows int, altText string, buttons ...*ViberButton) *ViberOverride {
+	return &ViberOverride{
+		Type:          "rich_media",
+		MinAPIVersion: viberRichMediaMinAPIVersion,
+		AltText:       altText,
+		RichMedia: &ViberRichMedia{
+			ButtonsGroupColumns: columns,
+			ButtonsGroupRows:    rows,
+			Buttons:             buttons,
+		},
+	}
+}
+
+type ViberKeyboard struct {
+	DefaultHeight bool           `json:"DefaultHeight,omitempty"`
+	BgColor       string         `json:"BgColor,omitempty"`
+	Buttons       []*ViberButton `json:"Buttons"`
+}
+
+func (k ViberKeyboard) MarshalJSON() ([]byte, error) {
+	type alias ViberKeyboard
+	return json.Marshal(struct {
+		Type string `json:"Type"`
+		alias
+	}{"keyboard", alias(k)})
+}
+
+type ViberRichMedia struct {
+	ButtonsGroupColumns int            `json:"ButtonsGroupColumns"`
+	ButtonsGroupRows    int            `json:"ButtonsGroupRows"`
+	BgColor             string         `json:"BgColor,omitempty"`
+	Buttons             []*ViberButton `json:"Buttons"`
+}
+
+func (r ViberRichMedia) MarshalJSON() ([]byte, error) {
+	type alias ViberRichMedia
+	return json.Marshal(struct {
+		Type string `json:"Type"`
+		alias
+	}{"rich_media", alias(r)})
+}
+
+// ViberButton is a keyboard or rich media button. Columns and Rows are its
+// size in the grid, from 1 to 6 and 1 to 2 for keyboards or 1 to 7 for rich
+// media. ActionBody is the text sent back for ViberActionReply or the url
+// opened for ViberActionOpenURL.
+type ViberButton struct {
+	Columns    int    `json:"Columns,omitempty"`
+	Rows       int    `json:"Rows,omitempty"`
+	ActionType string `json:"ActionType,omitempty"`
+	ActionBody string `json:"ActionBody"`
+	Text       string `json:"Text,omitempty"`
+	Image      string `json:"Image,omitempty"`
+	BgColor    string `json:"BgColor,omitempty"`
+	Silent     bool   `json:"Silent,omitempty"`
+}
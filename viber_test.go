@@ -0,0 +1,52 @@
+package smooch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViberKeyboardOverride(t *testing.T) {
+	message, err := NewMessage().
+		Text("Pick a size").
+		ChannelOverride(NewViberKeyboardOverride(
+			&ViberButton{Columns: 3, Rows: 1, ActionType: ViberActionReply, ActionBody: "S", Text: "Small"},
+			&ViberButton{Columns: 3, Rows: 1, ActionType: ViberActionReply, ActionBody: "L", Text: "Large"},
+		)).
+		Build()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"keyboard": {
+			"Type": "keyboard",
+			"Buttons": [
+				{"Columns": 3, "Rows": 1, "ActionType": "reply", "ActionBody": "S", "Text": "Small"},
+				{"Columns": 3, "Rows": 1, "ActionType": "reply", "ActionBody": "L", "Text": "Large"}
+			]
+		}
+	}`, string(message.Override[SourceTypeViber].Payload))
+}
+
+func TestViberRichMediaOverride(t *testing.T) {
+	override := NewViberRichMediaOverride(6, 2, "Our hotels",
+		&ViberButton{Columns: 6, Rows: 1, ActionType: ViberActionOpenURL, ActionBody: "https://example.org/hotel.jpg", Image: "https://example.org/hotel.jpg"},
+		&ViberButton{Columns: 6, Rows: 1, ActionType: ViberActionReply, ActionBody: "book", Text: "Book"},
+	)
+
+	o, err := NewOverride(override)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "rich_media",
+		"min_api_version": 2,
+		"alt_text": "Our hotels",
+		"rich_media": {
+			"Type": "rich_media",
+			"ButtonsGroupColumns": 6,
+			"ButtonsGroupRows": 2,
+			"Buttons": [
+				{"Columns": 6, "Rows": 1, "ActionType": "open-url", "ActionBody": "https://example.org/hotel.jpg", "Image": "https://example.org/hotel.jpg"},
+				{"Columns": 6, "Rows": 1, "ActionType": "reply", "ActionBody": "book", "Text": "Book"}
+			]
+		}
+	}`, string(o.Payload))
+	assert.Equal(t, SourceTypeViber, override.Channel())
+}
@@ -0,0 +1,416 @@
+package smooch
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const bearerPrefix = "Bearer "
+
+// WebhookMeta describes the request a webhook was delivered with.
+type WebhookMeta struct {
+	Header     http.Header
+	RemoteAddr string
+	ReceivedAt time.Time
+}
+
+func newWebhookMeta(r *http.Request, receivedAt time.Time) WebhookMeta {
+	return WebhookMeta{
+		Header:     r.Header,
+		RemoteAddr: r.RemoteAddr,
+		ReceivedAt: receivedAt,
+	}
+}
+
+// WebhookEndpointOptions configures how a webhook endpoint verifies
+// requests. At least one of VerifySecret and JWTSecret is required.
+type WebhookEndpointOptions struct {
+	// VerifySecret is compared with the X-Api-Key header.
+	VerifySecret string
+	// JWTSecret, when set, accepts requests with an Authorization bearer
+	// token signed with it in place of the X-Api-Key header.
+	JWTSecret string
+	// BasicAuth, when set, requires requests to carry these credentials.
+	BasicAuth *BasicAuth
+	// Deduplicator, when set, drops webhooks that were already dispatched.
+	Deduplicator *Deduplicator
+	// Workers, when set, dispatches webhooks on that many goroutines after
+	// answering them. The webhooks of a conversation are still handled one
+	// at a time, in the order they were received. QueueSize is the number
+	// of webhooks each worker holds before new ones wait.
+	Workers   int
+	QueueSize int
+	// EventBufferSize is the capacity of the channel returned by Events,
+	// DefaultEventBufferSize when not set. OverflowPolicy tells what
+	// happens to webhooks when it is full.
+	EventBufferSize int
+	OverflowPolicy  OverflowPolicy
+	// AuditLog, when set, records every webhook and how it was handled.
+	AuditLog *AuditLog
+	// AckAfterHandlers answers webhooks once the handlers ran, with 500
+	// Internal Server Error when any of them failed so Smooch retries the
+	// webhook. Handlers must then return before Smooch times out. It can't
+	// be used along with Workers.
+	AckAfterHandlers bool
+}
+
+// WebhookEndpoint verifies, decodes and dispatches the webhooks of one
+// Smooch webhook configuration to its own handlers. The client is the
+// endpoint of Options.WebhookURL; AddWebhookEndpoint adds others, e.g. to
+// handle the triggers of separate webhook configurations apart.
+type WebhookEndpoint struct {
+	appID  string
+	logger Logger
+	tracer trace.Tracer
+
+	verifySecret string
+	jwtSecret    string
+	basicAuth    *BasicAuth
+	deduplicator *Deduplicator
+	auditLog     *AuditLog
+	ackAfter     bool
+
+	handlers      []WebhookContextHandler
+	errorHandler  func(payload *Payload, index int, err error)
+	eventHandlers map[string][]func(e WebhookEvent)
+	sinks         []EventSink
+
+	eventBufferSize int
+	overflowPolicy  OverflowPolicy
+	stream          *eventStream
+
+	workers  *partitionedWorkers
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+}
+
+// AddWebhookEndpoint adds a webhook endpoint on path of the client's mux,
+// with its own verification and handlers.
+func (sc *smoochClient) AddWebhookEndpoint(path string, o WebhookEndpointOptions) (*WebhookEndpoint, error) {
+	if o.VerifySecret == "" && o.JWTSecret == "" {
+		return nil, ErrVerifySecretEmpty
+	}
+	if o.AckAfterHandlers && o.Workers > 0 {
+		return nil, ErrAckModeWorkers
+	}
+	if sc.webhookEndpoints[path] != nil {
+		return nil, ErrWebhookPathTaken
+	}
+
+	ep := &WebhookEndpoint{
+		appID:           sc.appID,
+		logger:          sc.logger,
+		tracer:          sc.tracer,
+		verifySecret:    o.VerifySecret,
+		jwtSecret:       o.JWTSecret,
+		basicAuth:       o.BasicAuth,
+		deduplicator:    o.Deduplicator,
+		auditLog:        o.AuditLog,
+		ackAfter:        o.AckAfterHandlers,
+		eventBufferSize: o.EventBufferSize,
+		overflowPolicy:  o.OverflowPolicy,
+	}
+	if o.Workers > 0 {
+		ep.workers = newPartitionedWorkers(o.Workers, o.QueueSize, ep.dispatchQueued)
+	}
+	sc.mux.Handle(path, ep)
+	sc.webhookEndpoints[path] = ep
+	return ep, nil
+}
+
+// Close stops dispatching webhooks, answering them with 503 Service
+// Unavailable so Smooch retries them against another instance, and waits
+// for the webhooks being handled or queued for workers until ctx is done.
+// The channels returned by Events are closed once they are dispatched.
+func (sc *smoochClient) Close(ctx context.Context) error {
+	for _, ep := range sc.webhookEndpoints {
+		ep.close()
+	}
+	for _, ep := range sc.webhookEndpoints {
+		if err := ep.wait(ctx); err != nil {
+			return err
+		}
+		if ep.workers != nil {
+			ep.workers.stop()
+		}
+		ep.closeStream()
+	}
+	return nil
+}
+
+func (ep *WebhookEndpoint) close() {
+	ep.mu.Lock()
+	ep.closed = true
+	ep.mu.Unlock()
+}
+
+func (ep *WebhookEndpoint) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		ep.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// begin registers a webhook being handled, unless the endpoint is closed.
+func (ep *WebhookEndpoint) begin() bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.closed {
+		return false
+	}
+	ep.inflight.Add(1)
+	return true
+}
+
+func (ep *WebhookEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ep.handle(w, r)
+}
+
+// WebhookHandlerFunc returns the webhook handler on its own, verifying,
+// decoding and dispatching webhooks whatever path it is mounted on. Use it
+// with routers other than the client's ServeMux.
+func (ep *WebhookEndpoint) WebhookHandlerFunc() http.HandlerFunc {
+	return ep.handle
+}
+
+func (ep *WebhookEndpoint) AddWebhookEventHandler(handler WebhookEventHandler) {
+	ep.AddWebhookEventHandlerE(func(payload *Payload) error {
+		handler(payload)
+		return nil
+	})
+}
+
+func (ep *WebhookEndpoint) AddWebhookEventHandlerE(handler WebhookEventHandlerE) {
+	ep.AddWebhookContextHandler(func(ctx context.Context, payload *Payload, meta WebhookMeta) error {
+		return handler(payload)
+	})
+}
+
+func (ep *WebhookEndpoint) AddWebhookContextHandler(handler WebhookContextHandler) {
+	ep.handlers = append(ep.handlers, handler)
+}
+
+// OnHandlerError sets the callback receiving the errors of the handlers
+// added with AddWebhookEventHandlerE, along with the payload and the index
+// of the handler in the order handlers were added. Use it to retry the
+// payload or send it to a dead letter queue. Errors are logged when it is
+// not set.
+func (ep *WebhookEndpoint) OnHandlerError(fn func(payload *Payload, index int, err error)) {
+	ep.errorHandler = fn
+}
+
+// VerifyRequest reports whether the X-Api-Key header of a webhook request
+// matches the verify secret, or it has a valid bearer token when a JWT
+// secret is set, and whether it carries the basic credentials when set.
+// The endpoint already checks it; call it when routing webhooks to your
+// own handler.
+func (ep *WebhookEndpoint) VerifyRequest(r *http.Request) bool {
+	if !ep.verifySecretHeader(r) && !ep.verifyBearerToken(r) {
+		return false
+	}
+	return ep.basicAuth == nil || ep.basicAuth.verify(r)
+}
+
+func (ep *WebhookEndpoint) handle(w http.ResponseWriter, r *http.Request) {
+	receivedAt := time.Now()
+	ctx, span := ep.startWebhookSpan(r)
+	defer span.End()
+
+	if !ep.begin() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		span.SetStatus(codes.Error, "webhook endpoint closed")
+		return
+	}
+	defer ep.inflight.Done()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		span.SetStatus(codes.Error, "request verification failed")
+		return
+	}
+	if !ep.VerifyRequest(r) {
+		ep.audit(ctx, newAuditEntry(nil, nil, newWebhookMeta(r, receivedAt), AuditOutcomeUnauthorized, nil))
+		if ep.basicAuth != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="smooch"`)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		span.SetStatus(codes.Error, "request verification failed")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		ep.logger.Errorw("request body read failed", "err", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	var payload Payload
+	err = json.Unmarshal(body, &payload)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		ep.logger.Errorw("could not decode response", "err", err)
+		ep.audit(ctx, newAuditEntry(nil, body, newWebhookMeta(r, receivedAt), AuditOutcomeInvalid, err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	setWebhookSpanAttributes(span, &payload)
+
+	if !ep.ackAfter {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	meta := newWebhookMeta(r, receivedAt)
+	if ep.deduplicator != nil {
+		isNew, err := ep.deduplicator.Filter(ctx, &payload, body)
+		if err != nil {
+			// dispatching twice beats losing the webhook
+			ep.logger.Errorw("webhook deduplication failed", "err", err)
+		} else if !isNew {
+			ep.logger.Debugw("duplicate webhook dropped", "trigger", payload.Trigger)
+			ep.audit(ctx, newAuditEntry(&payload, body, meta, AuditOutcomeDuplicate, nil))
+			if ep.ackAfter {
+				w.WriteHeader(http.StatusOK)
+			}
+			return
+		}
+	}
+
+	if ep.ackAfter {
+		ep.dispatchAcked(ctx, w, span, &payload, body, meta)
+		return
+	}
+
+	if ep.workers != nil {
+		ep.inflight.Add(1)
+		ep.workers.enqueue(ctx, &payload, meta)
+		return
+	}
+	ep.dispatchAudited(ctx, &payload, meta)
+}
+
+func (ep *WebhookEndpoint) dispatchQueued(ctx context.Context, p *Payload, meta WebhookMeta) {
+	defer ep.inflight.Done()
+	ep.dispatchAudited(ctx, p, meta)
+}
+
+// dispatchAcked dispatches the payload and answers the webhook with the
+// outcome of the handlers.
+func (ep *WebhookEndpoint) dispatchAcked(ctx context.Context, w http.ResponseWriter, span trace.Span, p *Payload, body []byte, meta WebhookMeta) {
+	err := ep.dispatchAudited(ctx, p, meta)
+	if err == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if ep.deduplicator != nil {
+		// the retry must not be dropped as a duplicate
+		if err := ep.deduplicator.Forget(ctx, p, body); err != nil {
+			ep.logger.Errorw("webhook deduplication reset failed", "err", err)
+		}
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func (ep *WebhookEndpoint) dispatchAudited(ctx context.Context, p *Payload, meta WebhookMeta) error {
+	err := ep.dispatch(ctx, p, meta)
+	outcome := AuditOutcomeHandled
+	if err != nil {
+		outcome = AuditOutcomeFailed
+	}
+	ep.audit(ctx, newAuditEntry(p, p.Raw, meta, outcome, err))
+	return err
+}
+
+// dispatch runs the handlers and returns their errors, joined.
+func (ep *WebhookEndpoint) dispatch(ctx context.Context, p *Payload, meta WebhookMeta) error {
+	var errs []error
+	for i, handler := range ep.handlers {
+		err := handler(ctx, p, meta)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		if ep.errorHandler != nil {
+			ep.errorHandler(p, i, err)
+		} else {
+			ep.logger.Errorw("webhook handler failed", "trigger", p.Trigger, "handler", i, "err", err)
+		}
+	}
+
+	handlers := ep.eventHandlers[p.Trigger]
+	if len(handlers) == 0 && len(ep.sinks) == 0 {
+		return errors.Join(errs...)
+	}
+	e := DecodeEvent(p)
+	for _, handler := range handlers {
+		handler(e)
+	}
+	ep.publish(ctx, e)
+	return errors.Join(errs...)
+}
+
+// BasicAuth holds the basic credentials webhook requests must carry.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a *BasicAuth) verify(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	// both are compared so the time taken doesn't tell which one is wrong
+	usernameOK := secureCompare(a.Username, username)
+	passwordOK := secureCompare(a.Password, password)
+	return usernameOK && passwordOK
+}
+
+func (ep *WebhookEndpoint) verifySecretHeader(r *http.Request) bool {
+	if ep.verifySecret == "" {
+		return false
+	}
+	return secureCompare(ep.verifySecret, r.Header.Get(webhookSecretHeaderKey))
+}
+
+func (ep *WebhookEndpoint) verifyBearerToken(r *http.Request) bool {
+	if ep.jwtSecret == "" {
+		return false
+	}
+	header := r.Header.Get(authorizationHeaderKey)
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return false
+	}
+	return verifyJWT(strings.TrimPrefix(header, bearerPrefix), ep.jwtSecret)
+}
+
+// secureCompare compares secrets in constant time.
+func secureCompare(expected string, given string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(given)) == 1
+}
@@ -0,0 +1,341 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerBasicAuth(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		WebhookBasicAuth: &BasicAuth{
+			Username: "smooch",
+			Password: "hunter2",
+		},
+	})
+	assert.NoError(t, err)
+
+	calls := 0
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		calls++
+	})
+
+	tests := []struct {
+		username string
+		password string
+		status   int
+	}{
+		{"smooch", "hunter2", http.StatusOK},
+		{"smooch", "hunter3", http.StatusUnauthorized},
+		{"admin", "hunter2", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(sampleWebhookData)))
+		req.Header.Set("X-Api-Key", "very-secure-test-secret")
+		if test.username != "" {
+			req.SetBasicAuth(test.username, test.password)
+		}
+		w := httptest.NewRecorder()
+
+		sc.Handler().ServeHTTP(w, req)
+		assert.Equal(t, test.status, w.Result().StatusCode, test.username+":"+test.password)
+		if test.status == http.StatusUnauthorized {
+			assert.Equal(t, `Basic realm="smooch"`, w.Result().Header.Get("WWW-Authenticate"))
+		}
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestHandlerJWTVerification(t *testing.T) {
+	sc, err := New(Options{
+		Secret:           "app-secret",
+		VerifyWebhookJWT: true,
+	})
+	assert.NoError(t, err)
+
+	valid, err := generateExpiringJWT(JWTScopeApp, "app_key", "app-secret", time.Minute)
+	assert.NoError(t, err)
+	expired, err := generateExpiringJWT(JWTScopeApp, "app_key", "app-secret", -time.Minute)
+	assert.NoError(t, err)
+	wrongKey, err := GenerateJWT(JWTScopeApp, "app_key", "other-secret")
+	assert.NoError(t, err)
+
+	tests := map[string]int{
+		"Bearer " + valid:    http.StatusOK,
+		"Bearer " + expired:  http.StatusUnauthorized,
+		"Bearer " + wrongKey: http.StatusUnauthorized,
+		valid:                http.StatusUnauthorized,
+		"":                   http.StatusUnauthorized,
+	}
+	for authorization, status := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(sampleWebhookData)))
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+
+		sc.Handler().ServeHTTP(w, req)
+		assert.Equal(t, status, w.Result().StatusCode, authorization)
+	}
+
+	// without a verify secret an empty X-Api-Key header is not enough
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(sampleWebhookData)))
+	req.Header.Set("X-Api-Key", "")
+	assert.False(t, sc.VerifyRequest(req))
+}
+
+func TestWebhookJWTSecret(t *testing.T) {
+	_, err := New(Options{VerifyWebhookJWT: true})
+	assert.Equal(t, ErrVerifySecretEmpty, err)
+
+	sc, err := New(Options{
+		Secret:           "app-secret",
+		VerifySecret:     "very-secure-test-secret",
+		VerifyWebhookJWT: true,
+		WebhookJWTSecret: "webhook-secret",
+	})
+	assert.NoError(t, err)
+
+	token, err := GenerateJWT(JWTScopeApp, "", "webhook-secret")
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	assert.True(t, sc.VerifyRequest(req))
+
+	// the shared secret still works alongside
+	req = httptest.NewRequest(http.MethodPost, "http://example.com/", nil)
+	req.Header.Set("X-Api-Key", "very-secure-test-secret")
+	assert.True(t, sc.VerifyRequest(req))
+}
+
+func TestHandlerErrors(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	failure := errors.New("database down")
+	var calls []string
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		calls = append(calls, "first")
+	})
+	sc.AddWebhookEventHandlerE(func(payload *Payload) error {
+		calls = append(calls, "second")
+		return failure
+	})
+	sc.AddWebhookEventHandlerE(func(payload *Payload) error {
+		calls = append(calls, "third")
+		return nil
+	})
+
+	type handlerError struct {
+		payload *Payload
+		index   int
+		err     error
+	}
+	var errs []handlerError
+	sc.OnHandlerError(func(payload *Payload, index int, err error) {
+		errs = append(errs, handlerError{payload, index, err})
+	})
+
+	payload := &Payload{Trigger: TriggerMessageAppUser}
+	sc.dispatch(context.Background(), payload, WebhookMeta{})
+
+	assert.Equal(t, []string{"first", "second", "third"}, calls)
+	assert.Equal(t, []handlerError{{payload, 1, failure}}, errs)
+}
+
+func TestWebhookContextHandler(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	type ctxKey struct{}
+	start := time.Now()
+	calls := 0
+	sc.AddWebhookContextHandler(func(ctx context.Context, payload *Payload, meta WebhookMeta) error {
+		calls++
+		assert.Equal(t, "request-value", ctx.Value(ctxKey{}))
+		assert.Equal(t, TriggerMessageAppUser, payload.Trigger)
+		assert.Equal(t, "203.0.113.7:4242", meta.RemoteAddr)
+		assert.Equal(t, "abc", meta.Header.Get("X-Request-Id"))
+		assert.False(t, meta.ReceivedAt.Before(start))
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(payloadExample1)))
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "request-value"))
+	req.RemoteAddr = "203.0.113.7:4242"
+	req.Header.Set("X-Api-Key", "very-secure-test-secret")
+	req.Header.Set("X-Request-Id", "abc")
+	sc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestWebhookHandlerFunc(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+		WebhookURL:   "/smooch",
+	})
+	assert.NoError(t, err)
+
+	calls := 0
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		calls++
+	})
+
+	// mounted on another router, the client's WebhookURL doesn't matter
+	router := http.NewServeMux()
+	router.Handle("/api/v2/hooks/smooch", sc.WebhookHandlerFunc())
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/api/v2/hooks/smooch", bytes.NewReader([]byte(payloadExample1)))
+	req.Header.Set("X-Api-Key", "very-secure-test-secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, 1, calls)
+
+	req = httptest.NewRequest(http.MethodPost, "http://example.com/api/v2/hooks/smooch", bytes.NewReader([]byte(payloadExample1)))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+}
+
+func TestMultipleWebhookEndpoints(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "messages-secret",
+		WebhookURL:   "/webhooks/messages",
+	})
+	assert.NoError(t, err)
+
+	delivery, err := sc.AddWebhookEndpoint("/webhooks/delivery", WebhookEndpointOptions{
+		VerifySecret: "delivery-secret",
+	})
+	assert.NoError(t, err)
+
+	var messages, deliveries int
+	sc.OnMessageAppUser(func(e *MessageAppUserEvent) {
+		messages++
+	})
+	delivery.OnDeliveryFailure(func(e *DeliveryFailureEvent) {
+		deliveries++
+	})
+
+	post := func(path string, secret string, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com"+path, bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Api-Key", secret)
+		w := httptest.NewRecorder()
+		sc.Handler().ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, post("/webhooks/messages", "messages-secret", payloadExample1))
+	assert.Equal(t, http.StatusOK, post("/webhooks/delivery", "delivery-secret", errorPayloadExample))
+	// each endpoint only accepts its own secret
+	assert.Equal(t, http.StatusUnauthorized, post("/webhooks/delivery", "messages-secret", errorPayloadExample))
+	// and only dispatches to its own handlers
+	assert.Equal(t, http.StatusOK, post("/webhooks/delivery", "delivery-secret", payloadExample1))
+
+	assert.Equal(t, 1, messages)
+	assert.Equal(t, 1, deliveries)
+
+	_, err = sc.AddWebhookEndpoint("/webhooks/delivery", WebhookEndpointOptions{VerifySecret: "other"})
+	assert.Equal(t, ErrWebhookPathTaken, err)
+	_, err = sc.AddWebhookEndpoint("/webhooks/other", WebhookEndpointOptions{})
+	assert.Equal(t, ErrVerifySecretEmpty, err)
+}
+
+func TestCloseDrainsWebhooks(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret: "very-secure-test-secret",
+	})
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		close(started)
+		<-release
+	})
+
+	post := func() int {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(payloadExample1)))
+		req.Header.Set("X-Api-Key", "very-secure-test-secret")
+		w := httptest.NewRecorder()
+		sc.Handler().ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	inflight := make(chan int)
+	go func() {
+		inflight <- post()
+	}()
+	<-started
+
+	// the handler is still running, so a short close times out
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, sc.Close(ctx))
+
+	// new webhooks are refused once closing
+	assert.Equal(t, http.StatusServiceUnavailable, post())
+
+	closed := make(chan error)
+	go func() {
+		closed <- sc.Close(context.Background())
+	}()
+	close(release)
+
+	assert.NoError(t, <-closed)
+	assert.Equal(t, http.StatusOK, <-inflight)
+}
+
+func TestAckAfterHandlers(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret:            "very-secure-test-secret",
+		WebhookAckAfterHandlers: true,
+		WebhookDeduplicator:     NewDeduplicator(NewMemoryStorage(), 0),
+	})
+	assert.NoError(t, err)
+
+	calls := 0
+	sc.AddWebhookEventHandlerE(func(payload *Payload) error {
+		calls++
+		if calls == 1 {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	post := func() int {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(payloadExample1)))
+		req.Header.Set("X-Api-Key", "very-secure-test-secret")
+		w := httptest.NewRecorder()
+		sc.Handler().ServeHTTP(w, req)
+		return w.Result().StatusCode
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, post())
+	// the failed webhook isn't taken for a duplicate when retried
+	assert.Equal(t, http.StatusOK, post())
+	assert.Equal(t, 2, calls)
+	// once handled, retries are dropped and acknowledged
+	assert.Equal(t, http.StatusOK, post())
+	assert.Equal(t, 2, calls)
+
+	_, err = sc.AddWebhookEndpoint("/workers", WebhookEndpointOptions{
+		VerifySecret:     "very-secure-test-secret",
+		AckAfterHandlers: true,
+		Workers:          2,
+	})
+	assert.Equal(t, ErrAckModeWorkers, err)
+}
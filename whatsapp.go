@@ -0,0 +1,338 @@
+package smooch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	messageSchemaWhatsApp = "whatsapp"
+
+	TemplateLanguagePolicyDeterministic = "deterministic"
+
+	TemplateComponentHeader = "header"
+	TemplateComponentBody   = "body"
+	TemplateComponentButton = "button"
+
+	TemplateButtonQuickReply = "quick_reply"
+	TemplateButtonURL        = "url"
+
+	TemplateParameterText     = "text"
+	TemplateParameterCurrency = "currency"
+	TemplateParameterDateTime = "date_time"
+	TemplateParameterImage    = "image"
+	TemplateParameterDocument = "document"
+	TemplateParameterVideo    = "video"
+	TemplateParameterPayload  = "payload"
+
+	InteractiveTypeButton = "button"
+	InteractiveTypeList   = "list"
+
+	maxInteractiveButtons     = 3
+	maxInteractiveRows        = 10
+	maxInteractiveButtonTitle = 20
+	maxInteractiveRowTitle    = 24
+)
+
+// WhatsAppTemplate is a pre-approved WhatsApp message template. It replaces
+// the deprecated hsm object.
+type WhatsAppTemplate struct {
+	Namespace  string               `json:"namespace,omitempty"`
+	Name       string               `json:"name"`
+	Language   *TemplateLanguage    `json:"language"`
+	Components []*TemplateComponent `json:"components,omitempty"`
+}
+
+// Validate checks the name and the language of the template, and the
+// types of its components and parameters.
+func (t *WhatsAppTemplate) Validate() error {
+	if t.Name == "" {
+		return ErrTemplateNameEmpty
+	}
+	if t.Language == nil || t.Language.Code == "" {
+		return ErrTemplateLanguageEmpty
+	}
+
+	for i, component := range t.Components {
+		switch component.Type {
+		case TemplateComponentHeader, TemplateComponentBody:
+		case TemplateComponentButton:
+			if component.SubType != TemplateButtonQuickReply && component.SubType != TemplateButtonURL {
+				return invalidMessage("button component %d has unknown sub type %q", i, component.SubType)
+			}
+		default:
+			return invalidMessage("component %d has unknown type %q", i, component.Type)
+		}
+
+		for n, parameter := range component.Parameters {
+			switch parameter.Type {
+			case TemplateParameterText, TemplateParameterCurrency, TemplateParameterDateTime,
+				TemplateParameterImage, TemplateParameterDocument, TemplateParameterVideo, TemplateParameterPayload:
+			default:
+				return invalidMessage("parameter %d of component %d has unknown type %q", n, i, parameter.Type)
+			}
+		}
+	}
+	return nil
+}
+
+type TemplateLanguage struct {
+	Policy string `json:"policy"`
+	Code   string `json:"code"`
+}
+
+type TemplateComponent struct {
+	Type string `json:"type"`
+	// SubType and Index are only used by button components.
+	SubType    string               `json:"sub_type,omitempty"`
+	Index      string               `json:"index,omitempty"`
+	Parameters []*TemplateParameter `json:"parameters,omitempty"`
+}
+
+type TemplateParameter struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Payload  string            `json:"payload,omitempty"`
+	Currency *TemplateCurrency `json:"currency,omitempty"`
+	DateTime *TemplateDateTime `json:"date_time,omitempty"`
+	Image    *TemplateMedia    `json:"image,omitempty"`
+	Document *TemplateMedia    `json:"document,omitempty"`
+	Video    *TemplateMedia    `json:"video,omitempty"`
+}
+
+type TemplateCurrency struct {
+	FallbackValue string `json:"fallback_value"`
+	Code          string `json:"code"`
+	Amount1000    int64  `json:"amount_1000"`
+}
+
+type TemplateDateTime struct {
+	FallbackValue string `json:"fallback_value"`
+}
+
+type TemplateMedia struct {
+	Link     string `json:"link"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// NewTemplateTextParameter is a shorthand for the most common parameter.
+func NewTemplateTextParameter(text string) *TemplateParameter {
+	return &TemplateParameter{Type: TemplateParameterText, Text: text}
+}
+
+// WhatsAppInteractive is a native WhatsApp reply button or list message.
+// Build it with NewInteractiveButtons or NewInteractiveList.
+type WhatsAppInteractive struct {
+	Type   string             `json:"type"`
+	Header *InteractiveHeader `json:"header,omitempty"`
+	Body   *InteractiveText   `json:"body"`
+	Footer *InteractiveText   `json:"footer,omitempty"`
+	Action *InteractiveAction `json:"action"`
+}
+
+type InteractiveHeader struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type InteractiveText struct {
+	Text string `json:"text"`
+}
+
+type InteractiveAction struct {
+	// Button is the label of the button opening a list.
+	Button   string                `json:"button,omitempty"`
+	Buttons  []*InteractiveButton  `json:"buttons,omitempty"`
+	Sections []*InteractiveSection `json:"sections,omitempty"`
+}
+
+type InteractiveButton struct {
+	Type  string            `json:"type"`
+	Reply *InteractiveReply `json:"reply"`
+}
+
+type InteractiveReply struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type InteractiveSection struct {
+	Title string            `json:"title,omitempty"`
+	Rows  []*InteractiveRow `json:"rows"`
+}
+
+type InteractiveRow struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// NewInteractiveButtons returns a message with up to three reply buttons.
+// The id of the tapped button comes back as the payload of the reply.
+func NewInteractiveButtons(body string, replies ...*InteractiveReply) *WhatsAppInteractive {
+	buttons := make([]*InteractiveButton, 0, len(replies))
+	for _, reply := range replies {
+		buttons = append(buttons, &InteractiveButton{Type: "reply", Reply: reply})
+	}
+	return &WhatsAppInteractive{
+		Type:   InteractiveTypeButton,
+		Body:   &InteractiveText{Text: body},
+		Action: &InteractiveAction{Buttons: buttons},
+	}
+}
+
+// NewInteractiveList returns a list message opened by a button labeled
+// button.
+func NewInteractiveList(body string, button string, sections ...*InteractiveSection) *WhatsAppInteractive {
+	return &WhatsAppInteractive{
+		Type:   InteractiveTypeList,
+		Body:   &InteractiveText{Text: body},
+		Action: &InteractiveAction{Button: button, Sections: sections},
+	}
+}
+
+// Validate checks the limits WhatsApp puts on interactive messages.
+func (i *WhatsAppInteractive) Validate() error {
+	if i.Body == nil || i.Body.Text == "" {
+		return invalidMessage("interactive messages need a body")
+	}
+	if i.Action == nil {
+		return invalidMessage("interactive messages need an action")
+	}
+
+	switch i.Type {
+	case InteractiveTypeButton:
+		buttons := i.Action.Buttons
+		if len(buttons) == 0 || len(buttons) > maxInteractiveButtons {
+			return invalidMessage("interactive button messages need 1 to %d buttons, got %d", maxInteractiveButtons, len(buttons))
+		}
+		for n, button := range buttons {
+			if button.Reply == nil || button.Reply.ID == "" || button.Reply.Title == "" {
+				return invalidMessage("button %d needs an id and a title", n)
+			}
+			if len([]rune(button.Reply.Title)) > maxInteractiveButtonTitle {
+				return invalidMessage("button %d title is longer than %d characters", n, maxInteractiveButtonTitle)
+			}
+		}
+	case InteractiveTypeList:
+		if i.Action.Button == "" {
+			return invalidMessage("interactive list messages need a button label")
+		}
+		rows := 0
+		for _, section := range i.Action.Sections {
+			if len(i.Action.Sections) > 1 && section.Title == "" {
+				return invalidMessage("sections need a title when there are several")
+			}
+			for _, row := range section.Rows {
+				if row.ID == "" || row.Title == "" {
+					return invalidMessage("row %d needs an id and a title", rows)
+				}
+				if len([]rune(row.Title)) > maxInteractiveRowTitle {
+					return invalidMessage("row %d title is longer than %d characters", rows, maxInteractiveRowTitle)
+				}
+				rows++
+			}
+		}
+		if rows == 0 || rows > maxInteractiveRows {
+			return invalidMessage("interactive list messages need 1 to %d rows, got %d", maxInteractiveRows, rows)
+		}
+	default:
+		return invalidMessage("unknown interactive type %q", i.Type)
+	}
+	return nil
+}
+
+// whatsAppMessage is a message in the WhatsApp schema, sent as is to the
+// WhatsApp API.
+type whatsAppMessage struct {
+	Type        string               `json:"type"`
+	Template    *WhatsAppTemplate    `json:"template,omitempty"`
+	Interactive *WhatsAppInteractive `json:"interactive,omitempty"`
+}
+
+type whatsAppMessagePost struct {
+	Role          Role             `json:"role"`
+	MessageSchema string           `json:"messageSchema"`
+	Message       *whatsAppMessage `json:"message"`
+}
+
+// SendTemplate sends a WhatsApp template message. The language policy
+// defaults to TemplateLanguagePolicyDeterministic.
+func (sc *smoochClient) SendTemplate(userID string, template *WhatsAppTemplate, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	if template == nil {
+		return nil, nil, ErrTemplateNil
+	}
+
+	if err := template.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if template.Language.Policy == "" {
+		language := *template.Language
+		language.Policy = TemplateLanguagePolicyDeterministic
+		t := *template
+		t.Language = &language
+		template = &t
+	}
+
+	return sc.sendWhatsAppMessage(userID, &whatsAppMessage{
+		Type:     "template",
+		Template: template,
+	}, opts)
+}
+
+// SendInteractive sends a WhatsApp reply button or list message. It is only
+// delivered to WhatsApp users, inside the 24 hour customer care window.
+func (sc *smoochClient) SendInteractive(userID string, interactive *WhatsAppInteractive, opts ...RequestOption) (*ResponsePayload, *ResponseData, error) {
+	if interactive == nil {
+		return nil, nil, ErrMessageNil
+	}
+
+	err := interactive.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sc.sendWhatsAppMessage(userID, &whatsAppMessage{
+		Type:        "interactive",
+		Interactive: interactive,
+	}, opts)
+}
+
+func (sc *smoochClient) sendWhatsAppMessage(userID string, message *whatsAppMessage, opts []RequestOption) (*ResponsePayload, *ResponseData, error) {
+	if userID == "" {
+		return nil, nil, ErrUserIDEmpty
+	}
+
+	ro := newRequestOptions(opts)
+	url := sc.getURL(
+		fmt.Sprintf("/v1.1/apps/%s/appusers/%s/messages", sc.appID, url.PathEscape(userID)),
+		ro.queryParams(nil),
+	)
+
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(&whatsAppMessagePost{
+		Role:          RoleAppMaker,
+		MessageSchema: messageSchemaWhatsApp,
+		Message:       message,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := sc.createRequest(http.MethodPost, url, buf, ro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var responsePayload ResponsePayload
+	respData, err := sc.sendRequest(req, &responsePayload)
+	if err != nil {
+		return nil, respData, err
+	}
+
+	return &responsePayload, respData, nil
+}
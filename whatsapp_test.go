@@ -0,0 +1,194 @@
+package smooch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newWhatsAppTestClient(t *testing.T, expectedBody string) *smoochClient {
+	fn := func(req *http.Request) *http.Response {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "/v1.1/apps/app/appusers/123/messages", req.URL.Path)
+		body, err := ioutil.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, expectedBody, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusCreated,
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(sampleResponse))),
+		}
+	}
+
+	sc, err := New(Options{
+		AppID:        "app",
+		VerifySecret: "very-secure-test-secret",
+		HttpClient:   NewTestClient(fn),
+	})
+	assert.NoError(t, err)
+	return sc
+}
+
+func TestSendTemplate(t *testing.T) {
+	sc := newWhatsAppTestClient(t, `{
+		"role": "appMaker",
+		"messageSchema": "whatsapp",
+		"message": {
+			"type": "template",
+			"template": {
+				"namespace": "XXXXXXXX_XXXX_XXXX_XXXX_XXXXXXXXXXXX",
+				"name": "order_shipped",
+				"language": {"policy": "deterministic", "code": "en"},
+				"components": [
+					{"type": "header", "parameters": [{"type": "image", "image": {"link": "https://example.org/box.png"}}]},
+					{"type": "body", "parameters": [{"type": "text", "text": "Bob"}]},
+					{"type": "button", "sub_type": "quick_reply", "index": "0", "parameters": [{"type": "payload", "payload": "TRACK"}]}
+				]
+			}
+		}
+	}`)
+
+	template := &WhatsAppTemplate{
+		Namespace: "XXXXXXXX_XXXX_XXXX_XXXX_XXXXXXXXXXXX",
+		Name:      "order_shipped",
+		Language:  &TemplateLanguage{Code: "en"},
+		Components: []*TemplateComponent{
+			{
+				Type: TemplateComponentHeader,
+				Parameters: []*TemplateParameter{
+					{Type: TemplateParameterImage, Image: &TemplateMedia{Link: "https://example.org/box.png"}},
+				},
+			},
+			{
+				Type:       TemplateComponentBody,
+				Parameters: []*TemplateParameter{NewTemplateTextParameter("Bob")},
+			},
+			{
+				Type:       TemplateComponentButton,
+				SubType:    TemplateButtonQuickReply,
+				Index:      "0",
+				Parameters: []*TemplateParameter{{Type: TemplateParameterPayload, Payload: "TRACK"}},
+			},
+		},
+	}
+	_, _, err := sc.SendTemplate("123", template)
+	assert.NoError(t, err)
+	assert.Equal(t, "", template.Language.Policy)
+
+	_, _, err = sc.SendTemplate("123", nil)
+	assert.Equal(t, ErrTemplateNil, err)
+
+	_, _, err = sc.SendTemplate("123", &WhatsAppTemplate{Name: "order_shipped"})
+	assert.Equal(t, ErrTemplateLanguageEmpty, err)
+
+	_, _, err = sc.SendTemplate("", template)
+	assert.Equal(t, ErrUserIDEmpty, err)
+}
+
+func TestSendInteractiveButtons(t *testing.T) {
+	sc := newWhatsAppTestClient(t, `{
+		"role": "appMaker",
+		"messageSchema": "whatsapp",
+		"message": {
+			"type": "interactive",
+			"interactive": {
+				"type": "button",
+				"body": {"text": "Confirm your booking?"},
+				"action": {
+					"buttons": [
+						{"type": "reply", "reply": {"id": "YES", "title": "Yes"}},
+						{"type": "reply", "reply": {"id": "NO", "title": "No"}}
+					]
+				}
+			}
+		}
+	}`)
+
+	_, _, err := sc.SendInteractive("123", NewInteractiveButtons("Confirm your booking?",
+		&InteractiveReply{ID: "YES", Title: "Yes"},
+		&InteractiveReply{ID: "NO", Title: "No"},
+	))
+	assert.NoError(t, err)
+}
+
+func TestSendInteractiveList(t *testing.T) {
+	sc := newWhatsAppTestClient(t, `{
+		"role": "appMaker",
+		"messageSchema": "whatsapp",
+		"message": {
+			"type": "interactive",
+			"interactive": {
+				"type": "list",
+				"body": {"text": "Where to?"},
+				"action": {
+					"button": "Destinations",
+					"sections": [{"title": "Europe", "rows": [{"id": "VNO", "title": "Vilnius", "description": "Lithuania"}]}]
+				}
+			}
+		}
+	}`)
+
+	_, _, err := sc.SendInteractive("123", NewInteractiveList("Where to?", "Destinations", &InteractiveSection{
+		Title: "Europe",
+		Rows:  []*InteractiveRow{{ID: "VNO", Title: "Vilnius", Description: "Lithuania"}},
+	}))
+	assert.NoError(t, err)
+}
+
+func TestInteractiveValidation(t *testing.T) {
+	reply := &InteractiveReply{ID: "A", Title: "A"}
+	row := &InteractiveRow{ID: "A", Title: "A"}
+
+	tests := []struct {
+		name        string
+		interactive *WhatsAppInteractive
+	}{
+		{"no body", NewInteractiveButtons("", reply)},
+		{"too many buttons", NewInteractiveButtons("Pick", reply, reply, reply, reply)},
+		{"long button title", NewInteractiveButtons("Pick", &InteractiveReply{ID: "A", Title: "a title that is too long"})},
+		{"no list button", NewInteractiveList("Pick", "", &InteractiveSection{Rows: []*InteractiveRow{row}})},
+		{"no rows", NewInteractiveList("Pick", "Open")},
+		{"untitled sections", NewInteractiveList("Pick", "Open",
+			&InteractiveSection{Rows: []*InteractiveRow{row}},
+			&InteractiveSection{Rows: []*InteractiveRow{row}},
+		)},
+		{"unknown type", &WhatsAppInteractive{Type: "product", Body: &InteractiveText{Text: "Pick"}, Action: &InteractiveAction{}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.IsType(t, &MessageValidationError{}, test.interactive.Validate())
+		})
+	}
+}
+
+func TestTemplateValidation(t *testing.T) {
+	language := &TemplateLanguage{Code: "en"}
+
+	assert.NoError(t, (&WhatsAppTemplate{
+		Name:     "ticket",
+		Language: language,
+		Components: []*TemplateComponent{
+			{Type: TemplateComponentBody, Parameters: []*TemplateParameter{{Type: TemplateParameterText, Text: "42"}}},
+			{Type: TemplateComponentButton, SubType: TemplateButtonQuickReply, Parameters: []*TemplateParameter{{Type: TemplateParameterPayload}}},
+		},
+	}).Validate())
+
+	assert.Equal(t, ErrTemplateNameEmpty, (&WhatsAppTemplate{Language: language}).Validate())
+	assert.Equal(t, ErrTemplateLanguageEmpty, (&WhatsAppTemplate{Name: "ticket"}).Validate())
+
+	tests := map[string]*TemplateComponent{
+		"unknown component":   {Type: "footer"},
+		"unknown button type": {Type: TemplateComponentButton, SubType: "call"},
+		"unknown parameter":   {Type: TemplateComponentBody, Parameters: []*TemplateParameter{{Type: "location"}}},
+	}
+	for name, component := range tests {
+		t.Run(name, func(t *testing.T) {
+			template := &WhatsAppTemplate{Name: "ticket", Language: language, Components: []*TemplateComponent{component}}
+			assert.IsType(t, &MessageValidationError{}, template.Validate())
+		})
+	}
+}
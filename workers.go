@@ -0,0 +1,68 @@
+package smooch
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultWorkerQueueSize is the number of webhooks each worker holds when
+// WebhookEndpointOptions.QueueSize is not set.
+const defaultWorkerQueueSize = 64
+
+type webhookJob struct {
+	ctx     context.Context
+	payload *Payload
+	meta    WebhookMeta
+}
+
+// partitionedWorkers dispatches webhooks on a fixed set of goroutines. The
+// webhooks of a conversation always go to the same worker, so they are
+// handled one at a time and in the order they were received, while
+// different conversations are handled in parallel.
+type partitionedWorkers struct {
+	queues   []chan webhookJob
+	stopOnce sync.Once
+}
+
+func newPartitionedWorkers(n int, queueSize int, dispatch func(ctx context.Context, p *Payload, meta WebhookMeta)) *partitionedWorkers {
+	if queueSize <= 0 {
+		queueSize = defaultWorkerQueueSize
+	}
+
+	w := &partitionedWorkers{queues: make([]chan webhookJob, n)}
+	for i := range w.queues {
+		queue := make(chan webhookJob, queueSize)
+		w.queues[i] = queue
+		go func() {
+			for job := range queue {
+				dispatch(job.ctx, job.payload, job.meta)
+			}
+		}()
+	}
+	return w
+}
+
+// enqueue blocks while the queue of the payload's conversation is full.
+func (w *partitionedWorkers) enqueue(ctx context.Context, p *Payload, meta WebhookMeta) {
+	// the request context is canceled once the webhook is answered, only
+	// the span is carried over
+	ctx = trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+	w.queues[w.partition(p)] <- webhookJob{ctx: ctx, payload: p, meta: meta}
+}
+
+func (w *partitionedWorkers) partition(p *Payload) int {
+	h := fnv.New32a()
+	h.Write([]byte(ConversationKey(p)))
+	return int(h.Sum32() % uint32(len(w.queues)))
+}
+
+func (w *partitionedWorkers) stop() {
+	w.stopOnce.Do(func() {
+		for _, queue := range w.queues {
+			close(queue)
+		}
+	})
+}
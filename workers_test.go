@@ -0,0 +1,68 @@
+package smooch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookWorkersKeepConversationOrder(t *testing.T) {
+	sc, err := New(Options{
+		VerifySecret:     "very-secure-test-secret",
+		WebhookWorkers:   4,
+		WebhookQueueSize: 1,
+	})
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	sc.AddWebhookEventHandler(func(payload *Payload) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[payload.Conversation.ID] = append(received[payload.Conversation.ID], payload.Messages[0].ID)
+	})
+
+	conversations := []string{"conv-a", "conv-b", "conv-c"}
+	for i := 0; i < 20; i++ {
+		for _, conversationID := range conversations {
+			body := fmt.Sprintf(
+				`{"trigger":"message:appUser","conversation":{"_id":%q},"messages":[{"_id":"%d","type":"text","text":"hi"}]}`,
+				conversationID, i,
+			)
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/", bytes.NewReader([]byte(body)))
+			req.Header.Set("X-Api-Key", "very-secure-test-secret")
+			w := httptest.NewRecorder()
+			sc.Handler().ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		}
+	}
+
+	// Close waits for the queued webhooks
+	assert.NoError(t, sc.Close(context.Background()))
+	assert.NoError(t, sc.Close(context.Background()))
+
+	for _, conversationID := range conversations {
+		expected := make([]string, 20)
+		for i := range expected {
+			expected[i] = fmt.Sprint(i)
+		}
+		assert.Equal(t, expected, received[conversationID])
+	}
+}
+
+func TestWebhookWorkersPartition(t *testing.T) {
+	w := &partitionedWorkers{queues: make([]chan webhookJob, 8)}
+
+	p := &Payload{Conversation: Conversation{ID: "conv"}, AppUser: AppUser{ID: "user"}}
+	assert.Equal(t, w.partition(p), w.partition(&Payload{Conversation: Conversation{ID: "conv"}}))
+
+	// app users stand in for the conversation when it is missing
+	p = &Payload{AppUser: AppUser{ID: "user"}}
+	assert.Equal(t, w.partition(p), w.partition(&Payload{Conversation: Conversation{ID: "user"}}))
+}